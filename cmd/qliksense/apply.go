@@ -8,15 +8,20 @@ import (
 func applyCmd(q *qliksense.Qliksense) *cobra.Command {
 	opts := &qliksense.InstallCommandOptions{
 		CleanPatchFiles: true,
+		WaitTimeout:     defaultWaitTimeout,
 	}
 	filePath := ""
+	progressOpts := &progressOptions{}
 	c := &cobra.Command{
 		Use:     "apply",
 		Short:   "install qliksense based on provided cr file",
 		Long:    `install qliksense based on provided cr file`,
 		Example: `qliksense apply -f file_name or cat cr_file | qliksense apply -f -`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return apply(q, cmd, opts)
+			return withProgressReporter(q, progressOpts, func(r qliksense.Reporter) error {
+				opts.Reporter = r
+				return apply(q, cmd, opts)
+			})
 		},
 	}
 
@@ -27,7 +32,14 @@ func applyCmd(q *qliksense.Qliksense) *cobra.Command {
 	f.BoolVar(&opts.CleanPatchFiles, cleanPatchFilesFlagName, opts.CleanPatchFiles, cleanPatchFilesFlagUsage)
 	f.BoolVarP(&opts.Pull, pullFlagName, pullFlagShorthand, opts.Pull, pullFlagUsage)
 	f.BoolVarP(&opts.Push, pushFlagName, pushFlagShorthand, opts.Push, pushFlagUsage)
+	f.IntVar(&opts.Images.Parallel, parallelFlagName, 1, parallelFlagUsage)
+	f.StringVar(&opts.Images.Platform, platformFlagName, "", platformFlagUsage)
+	f.BoolVar(&opts.Images.AllPlatforms, allPlatformsFlagName, false, allPlatformsFlagUsage)
 	f.StringVarP(&opts.AcceptEULA, "acceptEULA", "a", opts.AcceptEULA, "Accept EULA for qliksense")
+	f.BoolVar(&opts.Wait, waitFlagName, opts.Wait, waitFlagUsage)
+	f.DurationVar(&opts.WaitTimeout, timeoutFlagName, opts.WaitTimeout, timeoutFlagUsage)
+	addProgressFlags(c, progressOpts)
+	f.BoolVar(&opts.ForceUnlock, forceUnlockFlagName, opts.ForceUnlock, forceUnlockFlagUsage)
 
 	if err := c.MarkFlagRequired("file"); err != nil {
 		panic(err)
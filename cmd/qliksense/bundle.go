@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/qlik-oss/sense-installer/pkg/qliksense"
+	"github.com/spf13/cobra"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "air-gap bundles for disconnected sites",
+	Long:  `air-gap bundles hold everything an install needs without access to git and the image registries`,
+}
+
+func bundleCreateCmd(q *qliksense.Qliksense) *cobra.Command {
+	opts := &qliksense.BundleCommandOptions{}
+	c := &cobra.Command{
+		Use:   "create <version>",
+		Short: "create an air-gap bundle of a version",
+		Long: `create a single archive of a version holding the manifests repo at that version, the images of the profile,
+the operator and preflight images, the CRDs and a checksum of every file. Install it with qliksense install --bundle`,
+		Example: `qliksense bundle create v1.2.3 --profile docker-desktop --out qliksense-v1.2.3.tar.gz`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.CreateBundle(args[0], opts)
+		},
+	}
+
+	f := c.Flags()
+	f.StringVar(&opts.Profile, "profile", "", "Configuration profile, docker-desktop by default")
+	f.StringVar(&opts.Out, "out", "", "Archive to write, qliksense-<version>-<profile>.tar.gz by default")
+	f.IntVar(&opts.Images.Parallel, parallelFlagName, 1, parallelFlagUsage)
+	f.StringVar(&opts.Images.Platform, platformFlagName, "", platformFlagUsage)
+	f.BoolVar(&opts.Images.AllPlatforms, allPlatformsFlagName, false, allPlatformsFlagUsage)
+	return c
+}
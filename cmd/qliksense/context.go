@@ -13,20 +13,33 @@ import (
 
 func setContextConfigCmd(q *qliksense.Qliksense) *cobra.Command {
 	var (
-		cmd *cobra.Command
+		cmd  *cobra.Command
+		opts = &qliksense.ContextBindingOptions{}
 	)
 
 	cmd = &cobra.Command{
 		Use:   "set-context",
 		Short: "Sets the context in which the Kubernetes cluster and resources live in",
+		Long: `Sets the context in which the Kubernetes cluster and resources live in.
+With --kubeconfig, --kube-context or --namespace the context is bound to that kube config, kube context and namespace:
+commands of the context use them and refuse to run with another kube context unless --force-kube-context is set.`,
 		Example: `
 qliksense config set-context <context_name>
    - The above configuration will be displayed in the CR
+qliksense config set-context <context_name> --kubeconfig ~/.kube/prod --kube-context prod-admin --namespace qliksense
+   - Binds the context to the prod-admin kube context and the qliksense namespace
+qliksense config set-context <context_name> --unbind
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return q.SetContextConfig(args)
+			// --kubeconfig and --kube-context are global flags
+			opts.KubeConfig, _ = cmd.Flags().GetString(kubeConfigFlagName)
+			opts.KubeContext, _ = cmd.Flags().GetString(kubeContextFlagName)
+			return q.SetContextConfig(args, opts)
 		},
 	}
+	f := cmd.Flags()
+	f.StringVarP(&opts.Namespace, "namespace", "n", "", "Namespace to bind the context to")
+	f.BoolVar(&opts.Unbind, "unbind", false, "Remove the kube config, kube context and namespace binding of the context")
 	return cmd
 }
 
@@ -163,6 +176,8 @@ func setImageRegistryCmd(q *qliksense.Qliksense) *cobra.Command {
 		pullPassword string
 		username     string
 		password     string
+		dockerConfig bool
+		tls          qapi.RegistryTLS
 	)
 
 	cmd = &cobra.Command{
@@ -171,6 +186,9 @@ func setImageRegistryCmd(q *qliksense.Qliksense) *cobra.Command {
 		Example: `
 qliksense config set-image-registry https://your.private.registry.example.com:5000 --push-username foo1 --push-password bar1 --pull-username foo2 --pull-password bar2
 qliksense config set-image-registry https://your.private.registry.example.com:5000 --username foo --password bar
+qliksense config set-image-registry https://your.private.registry.example.com:5000 --ca-cert ca.pem --client-cert client.pem --client-key client-key.pem
+qliksense config set-image-registry https://your.private.registry.example.com:5000 --insecure
+qliksense config set-image-registry 123456789012.dkr.ecr.us-east-1.amazonaws.com --docker-config
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
@@ -192,7 +210,10 @@ qliksense config set-image-registry https://your.private.registry.example.com:50
 			if (pullUsername == "" && pullPassword != "") || (pushUsername == "" && pushPassword != "") {
 				return errors.New("if you specify passwords, you must specify usernames as well")
 			}
-			return q.SetImageRegistry(registry, pushUsername, pushPassword, pullUsername, pullPassword)
+			if dockerConfig && (pullUsername != "" || pushUsername != "") {
+				return errors.New("credentials cannot be specified with --docker-config")
+			}
+			return q.SetImageRegistry(registry, pushUsername, pushPassword, pullUsername, pullPassword, dockerConfig, &tls)
 		},
 	}
 	f := cmd.Flags()
@@ -202,6 +223,94 @@ qliksense config set-image-registry https://your.private.registry.example.com:50
 	f.StringVar(&pullPassword, "pull-password", "", "Password used for pulling images")
 	f.StringVar(&username, "username", "", "Username used for both pushing and pulling images")
 	f.StringVar(&password, "password", "", "Password used for both pushing and pulling images")
+	f.BoolVar(&dockerConfig, "docker-config", false, "Generate the image pull secret from the credentials of the docker config.json or its credential helpers, push uses them as well")
+	f.StringVar(&tls.CACert, "ca-cert", "", "PEM bundle of the CAs the certificate of the registry is verified with, in addition to the system CAs")
+	f.StringVar(&tls.ClientCert, "client-cert", "", "PEM client certificate for registries requiring mutual TLS")
+	f.StringVar(&tls.ClientKey, "client-key", "", "PEM key of the client certificate")
+	f.BoolVar(&tls.Insecure, "insecure", false, "Skip the verification of the certificate of the registry")
+	return cmd
+}
+
+func setImageMappingCmd(q *qliksense.Qliksense) *cobra.Command {
+	var (
+		prefix string
+		rules  []string
+	)
+	cmd := &cobra.Command{
+		Use:   "set-image-mapping <flatten|preserve|prefix>",
+		Short: "set how images are mapped to repositories of the image registry",
+		Long: `set how the repositories of images are mapped to repositories of the image registry, for push, the operator
+and the generated manifests. flatten keeps the last segment of a repository, preserve keeps its path and prefix puts
+the last segment under a prefix. Rules map single repositories before the strategy applies`,
+		Example: `
+qliksense config set-image-mapping preserve
+qliksense config set-image-mapping prefix --prefix qlik --rule docker.io/bitnami/mongodb=third-party/mongodb
+qliksense config set-image-mapping flatten
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.SetImageRegistryMapping(args[0], prefix, rules)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&prefix, "prefix", "", "Path the images are put under with the prefix strategy")
+	f.StringArrayVar(&rules, "rule", nil, "Repository of the image registry of a source repository, as source=target")
+	return cmd
+}
+
+func setImageMirrorsCmd(q *qliksense.Qliksense) *cobra.Command {
+	var mirrors []string
+	cmd := &cobra.Command{
+		Use:   "set-image-mirrors",
+		Short: "set the mirrors images are pulled from their source registries through",
+		Long: `set the pull-through mirrors images are pulled from their source registries through, for pull, the
+preflight checks and the operator. A mirror applies to the images of a registry or a repository, the mirror of
+the longest matching source is used. Without mirrors the mirrors are removed`,
+		Example: `
+qliksense config set-image-mirrors --mirror docker.io=mirror.example.com/dockerhub --mirror qlik-docker-oss.bintray.io=mirror.example.com/bintray
+qliksense config set-image-mirrors
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.SetImageMirrors(mirrors)
+		},
+	}
+	f := cmd.Flags()
+	f.StringArrayVar(&mirrors, "mirror", nil, "Mirror of a source registry or repository, as source=mirror")
+	return cmd
+}
+
+func setImagePolicyCmd(q *qliksense.Qliksense) *cobra.Command {
+	var (
+		global bool
+		remove bool
+	)
+	cmd := &cobra.Command{
+		Use:   "set-image-policy <policy.json>",
+		Short: "set the signature policy images are pulled with",
+		Long: `set the containers-policy.json images are pulled with, for the current context or globally for every context
+without a policy of its own. A pull fails for an image not satisfying the policy`,
+		Example: `
+qliksense config set-image-policy policy.json
+qliksense config set-image-policy policy.json --global
+qliksense config set-image-policy --remove
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remove {
+				if len(args) != 0 {
+					return errors.New("a policy cannot be set and removed at the same time")
+				}
+				return q.SetImagePolicy("", global)
+			}
+			if len(args) != 1 {
+				return errors.New("a policy file is required")
+			}
+			return q.SetImagePolicy(args[0], global)
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVar(&global, "global", false, "Set the policy of every context without a policy of its own")
+	f.BoolVar(&remove, "remove", false, "Remove the policy")
 	return cmd
 }
 
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/qlik-oss/sense-installer/pkg/qliksense"
+	"github.com/spf13/cobra"
+)
+
+func diffCmd(q *qliksense.Qliksense) *cobra.Command {
+	opts := &qliksense.DiffCommandOptions{}
+	c := &cobra.Command{
+		Use:   "diff [version]",
+		Short: "show what an install or upgrade would change in the cluster",
+		Long: `show what an install or upgrade would change in the cluster. The manifests of the current context,
+or of the version if provided, are rendered and compared with the live objects. Secret data is masked`,
+		Example: `qliksense diff
+qliksense diff v1.2.3 --output json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version := ""
+			if len(args) != 0 {
+				version = args[0]
+			}
+			return q.DiffQK8s(version, opts)
+		},
+	}
+
+	f := c.Flags()
+	f.StringVarP(&opts.Output, "output", "o", "text", "Output format, text or json")
+
+	return c
+}
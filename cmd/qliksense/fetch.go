@@ -7,6 +7,7 @@ import (
 
 func fetchCmd(q *qliksense.Qliksense) *cobra.Command {
 	opts := &qliksense.FetchCommandOptions{}
+	progressOpts := &progressOptions{}
 	c := &cobra.Command{
 		Use:     "fetch",
 		Short:   "fetch a release from qliksense-k8s repo, if version not supplied, will use from context",
@@ -16,7 +17,10 @@ func fetchCmd(q *qliksense.Qliksense) *cobra.Command {
 			if len(args) == 1 {
 				opts.Version = args[0]
 			}
-			return q.FetchK8sWithOpts(opts)
+			return withProgressReporter(q, progressOpts, func(r qliksense.Reporter) error {
+				opts.Reporter = r
+				return q.FetchK8sWithOpts(opts)
+			})
 		},
 	}
 
@@ -25,6 +29,7 @@ func fetchCmd(q *qliksense.Qliksense) *cobra.Command {
 	f.StringVarP(&opts.AccessToken, "accessToken", "", "", "access token for git url")
 	f.StringVarP(&opts.SecretName, "secretName", "", "", "kubernetes secret name where a key name accessToken exist")
 	f.BoolVarP(&opts.Overwrite, "overwrite", "", false, "Ovewrite previously fetched veersion as well as local chagnes")
+	addProgressFlags(c, progressOpts)
 
 	return c
 }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/qlik-oss/sense-installer/pkg/qliksense"
+	"github.com/spf13/cobra"
+)
+
+func historyCmd(q *qliksense.Qliksense) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "history",
+		Short: "list the revisions recorded for the current context",
+		Long: `list the revisions recorded for the current context. A revision is recorded on every install, apply,
+upgrade and rollback and can be reapplied with qliksense rollback --to <revision>`,
+		Example: `qliksense history`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.PrintHistory()
+		},
+	}
+	return c
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"github.com/qlik-oss/sense-installer/pkg/qliksense"
+	"github.com/spf13/cobra"
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "images of qliksense installs",
+	Long:  `inspect, save and load the images an install of qliksense needs`,
+}
+
+func imagesListCmd(q *qliksense.Qliksense) *cobra.Command {
+	opts := &qliksense.ImageListCommandOptions{}
+	c := &cobra.Command{
+		Use:   "list [version]",
+		Short: "list the images of an install",
+		Long: `list every image an install of the current context, or of the version if provided, needs: the images
+of the profile and the operator, ops-runner and preflight images. Every image is listed with the reference push
+puts it at in the image registry of the context, the digest of its manifest and its compressed size`,
+		Example: `qliksense images list
+qliksense images list v1.2.3 --profile docker-desktop --output csv`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version := ""
+			if len(args) != 0 {
+				version = args[0]
+			}
+			return q.ListImages(version, opts)
+		},
+	}
+
+	f := c.Flags()
+	f.StringVar(&opts.Profile, "profile", "", "Configuration profile, the one of the current context by default")
+	f.StringVarP(&opts.Output, "output", "o", "text", "Output format, text, json or csv")
+	return c
+}
+
+func imagesSaveCmd(q *qliksense.Qliksense) *cobra.Command {
+	opts := &qliksense.ImageSaveCommandOptions{}
+	c := &cobra.Command{
+		Use:   "save",
+		Short: "save the images of the current context into an archive",
+		Long: `save the images of the current context from the image store into a single oci-archive or docker-archive,
+for skopeo, docker load or qliksense images load. Images not pulled yet are pulled first`,
+		Example: `qliksense images save -o images.tar
+qliksense images save -o images.tar --format docker-archive`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.SaveImages(opts)
+		},
+	}
+
+	f := c.Flags()
+	f.StringVarP(&opts.Out, "output", "o", "", "Archive to write")
+	f.StringVar(&opts.Format, "format", qliksense.ImageArchiveOci, "Format of the archive, oci-archive or docker-archive")
+	c.MarkFlagRequired("output")
+	return c
+}
+
+func imagesLoadCmd(q *qliksense.Qliksense) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "load <archive>",
+		Short: "load the images of an archive into the image store",
+		Long: `load the images of an oci-archive or docker-archive, as written by qliksense images save, skopeo or docker save,
+into the image store qliksense push takes them from. Every image of the archive needs a name and a tag`,
+		Example: `qliksense images load images.tar`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.LoadImages(args[0])
+		},
+	}
+	return c
+}
+
+func imagesVerifyCmd(q *qliksense.Qliksense) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "verify",
+		Short: "verify the images of the current context against the signature policy",
+		Long: `verify every image of the current context in the image store against the signature policy set with
+qliksense config set-image-policy. The signatures of the manifest an image was pulled from are read again from
+its source registry and the image in the image store has to match that manifest`,
+		Example: `qliksense images verify`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.VerifyImages()
+		},
+	}
+	return c
+}
+
+func imagesPruneCmd(q *qliksense.Qliksense) *cobra.Command {
+	opts := &qliksense.ImagePruneCommandOptions{}
+	c := &cobra.Command{
+		Use:   "prune",
+		Short: "remove the images no fetched version needs from the image store",
+		Long: `remove the images no version fetched in any context needs from the image store, and the blobs no image
+left references. The operator, ops-runner and preflight images of every context and the images of --keep are kept`,
+		Example: `qliksense images prune --dry-run
+qliksense images prune --keep qlik/engine:12.585.0`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.PruneImages(opts)
+		},
+	}
+
+	f := c.Flags()
+	f.StringArrayVar(&opts.Keep, "keep", nil, "Image to keep even if no fetched version needs it")
+	f.BoolVar(&opts.DryRun, "dry-run", false, "Only show what would be removed")
+	return c
+}
@@ -8,14 +8,17 @@ import (
 func installCmd(q *qliksense.Qliksense) *cobra.Command {
 	opts := &qliksense.InstallCommandOptions{
 		CleanPatchFiles: true,
+		WaitTimeout:     defaultWaitTimeout,
 	}
 	filePath := ""
+	progressOpts := &progressOptions{}
 	c := &cobra.Command{
 		Use:   "install",
 		Short: "install a qliksense release",
 		Long:  `install a qliksense release`,
 		Example: `qliksense install <version> #if no version provides, expect manifestsRoot is set somewhere in the file system
 		# qliksense install -f file_name or cat cr_file | qliksense install -f -
+		# qliksense install --bundle qliksense-v1.2.3-docker-desktop.tar.gz --push
 		`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			version := ""
@@ -23,18 +26,19 @@ func installCmd(q *qliksense.Qliksense) *cobra.Command {
 				version = args[0]
 			}
 
-			if filePath != "" {
-				if err := apply(q, cmd, opts); err != nil {
-					return err
+			return withProgressReporter(q, progressOpts, func(r qliksense.Reporter) error {
+				opts.Reporter = r
+				if filePath != "" {
+					if err := apply(q, cmd, opts); err != nil {
+						return err
+					}
+				} else {
+					if err := q.InstallQK8s(version, opts); err != nil {
+						return err
+					}
 				}
-			} else {
-				if err := q.InstallQK8s(version, opts); err != nil {
-					return err
-				}
-			}
-			postflightChecksCmd := AllPostflightChecks(q)
-			postflightChecksCmd.DisableFlagParsing = true
-			return postflightChecksCmd.Execute()
+				return runPostflightChecks(q, r)
+			})
 		},
 	}
 
@@ -45,8 +49,26 @@ func installCmd(q *qliksense.Qliksense) *cobra.Command {
 	f.BoolVar(&opts.CleanPatchFiles, cleanPatchFilesFlagName, opts.CleanPatchFiles, cleanPatchFilesFlagUsage)
 	f.BoolVarP(&opts.Pull, pullFlagName, pullFlagShorthand, opts.Pull, pullFlagUsage)
 	f.BoolVarP(&opts.Push, pushFlagName, pushFlagShorthand, opts.Push, pushFlagUsage)
+	f.IntVar(&opts.Images.Parallel, parallelFlagName, 1, parallelFlagUsage)
+	f.StringVar(&opts.Images.Platform, platformFlagName, "", platformFlagUsage)
+	f.BoolVar(&opts.Images.AllPlatforms, allPlatformsFlagName, false, allPlatformsFlagUsage)
 	f.StringVarP(&opts.AcceptEULA, "acceptEULA", "a", opts.AcceptEULA, "Accept EULA for qliksense")
+	f.BoolVar(&opts.Wait, waitFlagName, opts.Wait, waitFlagUsage)
+	f.DurationVar(&opts.WaitTimeout, timeoutFlagName, opts.WaitTimeout, timeoutFlagUsage)
 	f.BoolVarP(&opts.DryRun, "dry-run", "", false, "Dry run will generate the patches without rotating keys")
+	f.BoolVar(&opts.PinDigests, pinDigestsFlagName, false, pinDigestsFlagUsage)
+	f.StringVar(&opts.Bundle, "bundle", "", "Install from an air-gap bundle of qliksense bundle create instead of git and the image registries")
+	addProgressFlags(c, progressOpts)
+	f.BoolVar(&opts.ForceUnlock, forceUnlockFlagName, opts.ForceUnlock, forceUnlockFlagUsage)
 
 	return c
 }
+
+// runPostflightChecks runs all postflight checks after an install or upgrade
+func runPostflightChecks(q *qliksense.Qliksense, r qliksense.Reporter) error {
+	return qliksense.RunPhase(r, qliksense.PhasePostflight, func() error {
+		postflightChecksCmd := AllPostflightChecks(q)
+		postflightChecksCmd.DisableFlagParsing = true
+		return postflightChecksCmd.Execute()
+	})
+}
@@ -11,6 +11,7 @@ var keysCmd = &cobra.Command{
 }
 
 func keysRotateCmd(q *qliksense.Qliksense) *cobra.Command {
+	forceUnlock := false
 	c := &cobra.Command{
 		Use:   "rotate",
 		Short: "Rotate qliksense application keys",
@@ -18,6 +19,7 @@ func keysRotateCmd(q *qliksense.Qliksense) *cobra.Command {
 			if err := q.InstallQK8s("", &qliksense.InstallCommandOptions{
 				CleanPatchFiles: true,
 				RotateKeys:      true,
+				ForceUnlock:     forceUnlock,
 			}); err != nil {
 				return err
 			} else {
@@ -27,5 +29,6 @@ func keysRotateCmd(q *qliksense.Qliksense) *cobra.Command {
 			}
 		},
 	}
+	c.Flags().BoolVar(&forceUnlock, forceUnlockFlagName, forceUnlock, forceUnlockFlagUsage)
 	return c
 }
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/qlik-oss/sense-installer/pkg/qliksense"
+	"github.com/spf13/cobra"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "install lock of the namespace",
+	Long: `install, upgrade, uninstall and keys rotate hold a lease in the target namespace while they run,
+so that they do not run against the same namespace at the same time`,
+}
+
+func lockStatusCmd(q *qliksense.Qliksense) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "status",
+		Short:   "show who holds the install lock of the namespace",
+		Example: `qliksense lock status`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.PrintLockStatus()
+		},
+	}
+	return c
+}
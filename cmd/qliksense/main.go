@@ -1,5 +1,9 @@
 package main
 
+import "os"
+
 func main() {
-	initAndExecute()
+	if err := initAndExecute(); err != nil {
+		os.Exit(1)
+	}
 }
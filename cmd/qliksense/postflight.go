@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	. "github.com/logrusorgru/aurora"
-	ansi "github.com/mattn/go-colorable"
 	"github.com/qlik-oss/sense-installer/pkg/api"
 	postflight "github.com/qlik-oss/sense-installer/pkg/postflight"
 	"github.com/qlik-oss/sense-installer/pkg/qliksense"
@@ -25,7 +24,6 @@ func postflightCmd(q *qliksense.Qliksense) *cobra.Command {
 }
 
 func postflightMigrationCheck(q *qliksense.Qliksense) *cobra.Command {
-	out := ansi.NewColorableStdout()
 	postflightOpts := &postflight.PostflightOptions{}
 	var postflightMigrationCmd = &cobra.Command{
 		Use:     "db-migration-check",
@@ -34,12 +32,13 @@ func postflightMigrationCheck(q *qliksense.Qliksense) *cobra.Command {
 		Example: `qliksense postflight db-migration-check`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			pf := &postflight.QliksensePostflight{Q: q, P: postflightOpts, CG: &api.ClientGoUtils{Verbose: postflightOpts.Verbose}}
+			out := pf.Out()
 
 			// Postflight db_migration_check
 			namespace, kubeConfigContents, err := pf.CG.LoadKubeConfigAndNamespace()
 			if err != nil {
 				fmt.Fprintf(out, "%s\n", Red("Postflight db_migration_check FAILED"))
-				fmt.Printf("Error: %v\n", err)
+				fmt.Fprintf(out, "Error: %v\n", err)
 				return nil
 			}
 			if namespace == "" {
@@ -47,7 +46,7 @@ func postflightMigrationCheck(q *qliksense.Qliksense) *cobra.Command {
 			}
 			if err = pf.DbMigrationCheck(namespace, kubeConfigContents); err != nil {
 				fmt.Fprintf(out, "%s\n", Red("Postflight db_migration_check FAILED"))
-				fmt.Printf("Error: %v\n", err)
+				fmt.Fprintf(out, "Error: %v\n", err)
 				return nil
 			}
 			fmt.Fprintf(out, "%s\n", Green("Postflight db_migration_check completed"))
@@ -60,7 +59,6 @@ func postflightMigrationCheck(q *qliksense.Qliksense) *cobra.Command {
 }
 
 func AllPostflightChecks(q *qliksense.Qliksense) *cobra.Command {
-	out := ansi.NewColorableStdout()
 	postflightOpts := &postflight.PostflightOptions{}
 	var postflightAllChecksCmd = &cobra.Command{
 		Use:     "all",
@@ -69,13 +67,14 @@ func AllPostflightChecks(q *qliksense.Qliksense) *cobra.Command {
 		Example: `qliksense postflight all`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			pf := &postflight.QliksensePostflight{Q: q, P: postflightOpts, CG: &api.ClientGoUtils{Verbose: postflightOpts.Verbose}}
+			out := pf.Out()
 
 			// run all postflight checks
-			fmt.Printf("Running all postflight checks...\n\n")
+			fmt.Fprintf(out, "Running all postflight checks...\n\n")
 			namespace, kubeConfigContents, err := pf.CG.LoadKubeConfigAndNamespace()
 			if err != nil {
 				fmt.Fprintf(out, "%s\n", Red("Unable to run all postflight checks"))
-				fmt.Printf("Error: %v\n", err)
+				fmt.Fprintf(out, "Error: %v\n", err)
 				return nil
 			}
 			if namespace == "" {
@@ -83,7 +82,7 @@ func AllPostflightChecks(q *qliksense.Qliksense) *cobra.Command {
 			}
 			if err = pf.RunAllPostflightChecks(namespace, kubeConfigContents, postflightOpts); err != nil {
 				fmt.Fprintf(out, "%s\n", Red("1 or more preflight checks have FAILED"))
-				fmt.Printf("Completed running all postflight checks")
+				fmt.Fprintf(out, "Completed running all postflight checks")
 				return nil
 			}
 			fmt.Fprintf(out, "%s\n", Green("All postflight checks have PASSED"))
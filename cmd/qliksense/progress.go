@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/qlik-oss/sense-installer/pkg/qliksense"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputText = "text"
+	outputJson = "json"
+)
+
+type progressOptions struct {
+	output     string
+	eventsFile string
+}
+
+func addProgressFlags(c *cobra.Command, opts *progressOptions) {
+	f := c.Flags()
+	f.StringVarP(&opts.output, outputFlagName, outputFlagShorthand, outputText, outputFlagUsage)
+	f.StringVar(&opts.eventsFile, eventsFileFlagName, "", eventsFileFlagUsage)
+}
+
+// withProgressReporter runs f with the reporter selected by --output and --events-file, nil if none is.
+// The reporter ends with a summary of the command. With --output json the events are the only output
+// on stdout, the progress messages of q go to stderr.
+func withProgressReporter(q *qliksense.Qliksense, opts *progressOptions, f func(r qliksense.Reporter) error) error {
+	if opts.output != outputText && opts.output != outputJson {
+		return fmt.Errorf("unsupported output %s, use %s or %s", opts.output, outputText, outputJson)
+	}
+	var reporters qliksense.MultiReporter
+	if opts.eventsFile != "" {
+		eventsFile, err := os.OpenFile(opts.eventsFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		defer eventsFile.Close()
+		reporters = append(reporters, qliksense.NewJSONReporter(eventsFile))
+	}
+	if opts.output == outputJson {
+		reporters = append(reporters, qliksense.NewJSONReporter(os.Stdout))
+		previous := q.Out
+		q.Out = os.Stderr
+		defer func() {
+			q.Out = previous
+		}()
+	}
+	if len(reporters) == 0 {
+		return f(nil)
+	}
+	return qliksense.RunCommand(reporters, f)
+}
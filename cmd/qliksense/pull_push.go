@@ -7,6 +7,8 @@ import (
 
 func pullQliksenseImages(q *qliksense.Qliksense) *cobra.Command {
 	opts := &aboutCommandOptions{}
+	imageOpts := &qliksense.ImageCommandOptions{}
+	progressOpts := &progressOptions{}
 
 	cmd := &cobra.Command{
 		Use:     "pull",
@@ -17,22 +19,41 @@ func pullQliksenseImages(q *qliksense.Qliksense) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return q.PullImages(version, opts.Profile)
+			return withProgressReporter(q, progressOpts, func(r qliksense.Reporter) error {
+				return qliksense.RunPhase(r, qliksense.PhasePull, func() error {
+					return q.PullImages(version, opts.Profile, imageOpts)
+				})
+			})
 		},
 	}
 	f := cmd.Flags()
 	f.StringVar(&opts.Profile, "profile", "", "Configuration profile")
+	f.IntVar(&imageOpts.Parallel, parallelFlagName, 1, parallelFlagUsage)
+	f.StringVar(&imageOpts.Platform, platformFlagName, "", platformFlagUsage)
+	f.BoolVar(&imageOpts.AllPlatforms, allPlatformsFlagName, false, allPlatformsFlagUsage)
+	addProgressFlags(cmd, progressOpts)
 	return cmd
 }
 
 func pushQliksenseImages(q *qliksense.Qliksense) *cobra.Command {
+	imageOpts := &qliksense.ImageCommandOptions{}
+	progressOpts := &progressOptions{}
 	cmd := &cobra.Command{
 		Use:     "push",
 		Short:   "Push docker images for offline install",
 		Example: `qliksense push`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return q.PushImagesForCurrentCR()
+			return withProgressReporter(q, progressOpts, func(r qliksense.Reporter) error {
+				return qliksense.RunPhase(r, qliksense.PhasePush, func() error {
+					return q.PushImagesForCurrentCR(imageOpts)
+				})
+			})
 		},
 	}
+	f := cmd.Flags()
+	f.IntVar(&imageOpts.Parallel, parallelFlagName, 1, parallelFlagUsage)
+	f.StringVar(&imageOpts.Platform, platformFlagName, "", platformFlagUsage)
+	f.BoolVar(&imageOpts.AllPlatforms, allPlatformsFlagName, false, allPlatformsFlagUsage)
+	addProgressFlags(cmd, progressOpts)
 	return cmd
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/qlik-oss/sense-installer/pkg/api"
+	"github.com/qlik-oss/sense-installer/pkg/qliksense"
+	"github.com/spf13/cobra"
+)
+
+func renderCmd(q *qliksense.Qliksense) *cobra.Command {
+	opts := &qliksense.RenderCommandOptions{
+		Secrets: api.SecretsPlain,
+	}
+	c := &cobra.Command{
+		Use:   "render",
+		Short: "render the manifests of the current context into a directory",
+		Long: `render the CRDs, the operator controller, the secrets, the generated manifests and the CR of the
+current context into a directory, one file per resource, without using the cluster. The directory can be
+applied by a GitOps tool such as Argo CD or Flux`,
+		Example: `qliksense render --out ./qliksense
+qliksense render --out ./qliksense --single-file --secrets redacted
+qliksense render --out ./qliksense --secrets encrypted --encrypt-command "kubeseal -o yaml"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.RenderQK8s(opts)
+		},
+	}
+
+	f := c.Flags()
+	f.StringVar(&opts.Out, "out", "", "Directory to write the manifests into")
+	f.BoolVar(&opts.SingleFile, "single-file", false, "Write all resources into a single multi-document file")
+	f.StringVar(&opts.Secrets, "secrets", opts.Secrets, "How to write secrets: plain, redacted or encrypted")
+	f.StringVar(&opts.EncryptCommand, "encrypt-command", "", "Command encrypting a secret from stdin to stdout, for --secrets encrypted")
+	f.BoolVar(&opts.PinDigests, pinDigestsFlagName, false, pinDigestsFlagUsage)
+	if err := c.MarkFlagRequired("out"); err != nil {
+		panic(err)
+	}
+	return c
+}
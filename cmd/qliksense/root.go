@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	. "github.com/logrusorgru/aurora"
 	ansi "github.com/mattn/go-colorable"
@@ -22,17 +23,43 @@ import (
 // qliksense <command>
 
 const (
-	qlikSenseHomeVar         = "QLIKSENSE_HOME"
-	qlikSenseDirVar          = ".qliksense"
-	cleanPatchFilesFlagName  = "clean"
-	cleanPatchFilesFlagUsage = "Set --clean=false to keep any prior config repo file changes on install (for debugging)"
-	pullFlagName             = "pull"
-	pullFlagShorthand        = "d"
-	pullFlagUsage            = "If using private docker registry, pull (download) all required qliksense images before install"
-	pushFlagName             = "push"
-	pushFlagShorthand        = "u"
-	pushFlagUsage            = "If using private docker registry, push (upload) all downloaded qliksense images to that registry before install"
-	rootCommandName          = "qliksense"
+	qlikSenseHomeVar          = "QLIKSENSE_HOME"
+	qlikSenseDirVar           = ".qliksense"
+	cleanPatchFilesFlagName   = "clean"
+	cleanPatchFilesFlagUsage  = "Set --clean=false to keep any prior config repo file changes on install (for debugging)"
+	pullFlagName              = "pull"
+	pullFlagShorthand         = "d"
+	pullFlagUsage             = "If using private docker registry, pull (download) all required qliksense images before install"
+	pushFlagName              = "push"
+	pushFlagShorthand         = "u"
+	pushFlagUsage             = "If using private docker registry, push (upload) all downloaded qliksense images to that registry before install"
+	rootCommandName           = "qliksense"
+	waitFlagName              = "wait"
+	waitFlagUsage             = "Wait until every Deployment, StatefulSet, Job and the Qliksense CR is ready"
+	timeoutFlagName           = "timeout"
+	timeoutFlagUsage          = "How long to wait for readiness with --wait"
+	defaultWaitTimeout        = 15 * time.Minute
+	kubeConfigFlagName        = "kubeconfig"
+	kubeConfigFlagUsage       = "Path to the kube config file, KUBECONFIG or ~/.kube/config is used by default"
+	kubeContextFlagName       = "kube-context"
+	kubeContextFlagUsage      = "Kube context to use, the current context of the kube config is used by default"
+	forceKubeContextFlagName  = "force-kube-context"
+	forceKubeContextFlagUsage = "Run even if the selected kube context differs from the one the context is bound to"
+	outputFlagName            = "output"
+	outputFlagShorthand       = "o"
+	outputFlagUsage           = "Output format, text or json for progress events as JSON lines on stdout"
+	eventsFileFlagName        = "events-file"
+	eventsFileFlagUsage       = "Also write the progress events as JSON lines into this file"
+	forceUnlockFlagName       = "force-unlock"
+	forceUnlockFlagUsage      = "Take over the install lock of the namespace even if another command holds it"
+	parallelFlagName          = "parallel"
+	parallelFlagUsage         = "Number of images to pull or push at the same time"
+	platformFlagName          = "platform"
+	platformFlagUsage         = "Platform as os/arch, i.e. linux/arm64, to pull from the manifest lists of multi-arch images"
+	allPlatformsFlagName      = "all-platforms"
+	allPlatformsFlagUsage     = "Pull every platform of the manifest lists of multi-arch images"
+	pinDigestsFlagName        = "pin-digests"
+	pinDigestsFlagUsage       = "Pin the images of the manifests to the digests of their manifests, recorded in the image digests lock file of the context"
 )
 
 func initAndExecute() error {
@@ -90,6 +117,25 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+// commandSelectsKubeContext returns false for the commands that manage the context bindings
+func commandSelectsKubeContext(commandName string) bool {
+	switch commandName {
+	case fmt.Sprintf("%v config set-context", rootCommandName),
+		fmt.Sprintf("%v config list-contexts", rootCommandName),
+		fmt.Sprintf("%v config delete-context", rootCommandName):
+		return false
+	}
+	return true
+}
+
+// commandTargetContext returns the context the command runs for if given as argument, empty for the current context
+func commandTargetContext(commandName string, args []string) string {
+	if commandName == fmt.Sprintf("%v uninstall", rootCommandName) && len(args) > 0 {
+		return args[0]
+	}
+	return ""
+}
+
 func commandUsesContext(commandName string) bool {
 	return commandName != "" &&
 		commandName != rootCommandName &&
@@ -98,12 +144,15 @@ func commandUsesContext(commandName string) bool {
 }
 
 func getRootCmd(p *qliksense.Qliksense) *cobra.Command {
+	var kubeConfig, kubeContext string
+	var forceKubeContext bool
 	cmd := &cobra.Command{
 		Use:   rootCommandName,
 		Short: "qliksense cli tool",
 		Long:  `qliksense cli tool provides functionality to perform operations on qliksense-k8s, qliksense operator, and kubernetes cluster`,
 		Args:  cobra.ArbitraryArgs,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			api.SetKubeConfig(kubeConfig, kubeContext, "")
 			if commandUsesContext(cmd.CommandPath()) {
 				if err := p.SetUpQliksenseDefaultContext(); err != nil {
 					panic(err)
@@ -112,11 +161,18 @@ func getRootCmd(p *qliksense.Qliksense) *cobra.Command {
 				if err := pf.Initialize(); err != nil {
 					panic(err)
 				}
+				if commandSelectsKubeContext(cmd.CommandPath()) {
+					return p.SelectKubeContext(commandTargetContext(cmd.CommandPath(), args), kubeConfig, kubeContext, forceKubeContext)
+				}
 			}
+			return nil
 		},
 		SilenceUsage: true,
 	}
 	cmd.Flags().SetInterspersed(false)
+	cmd.PersistentFlags().StringVar(&kubeConfig, kubeConfigFlagName, "", kubeConfigFlagUsage)
+	cmd.PersistentFlags().StringVar(&kubeContext, kubeContextFlagName, "", kubeContextFlagUsage)
+	cmd.PersistentFlags().BoolVar(&forceKubeContext, forceKubeContextFlagName, false, forceKubeContextFlagUsage)
 	return cmd
 }
 
@@ -148,6 +204,33 @@ func rootCmd(p *qliksense.Qliksense) *cobra.Command {
 	// add install command
 	cmd.AddCommand(installCmd(p))
 
+	// add upgrade, rollback and history commands
+	cmd.AddCommand(upgradeCmd(p))
+	cmd.AddCommand(rollbackCmd(p))
+	cmd.AddCommand(historyCmd(p))
+
+	// add diff command
+	cmd.AddCommand(diffCmd(p))
+
+	// add render command
+	cmd.AddCommand(renderCmd(p))
+
+	// add images command
+	cmd.AddCommand(imagesCmd)
+	imagesCmd.AddCommand(imagesListCmd(p))
+	imagesCmd.AddCommand(imagesSaveCmd(p))
+	imagesCmd.AddCommand(imagesLoadCmd(p))
+	imagesCmd.AddCommand(imagesVerifyCmd(p))
+	imagesCmd.AddCommand(imagesPruneCmd(p))
+
+	// add bundle command
+	cmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleCreateCmd(p))
+
+	// add lock command
+	cmd.AddCommand(lockCmd)
+	lockCmd.AddCommand(lockStatusCmd(p))
+
 	// add config command
 	configCmd := configCmd(p)
 	cmd.AddCommand(configCmd)
@@ -179,6 +262,13 @@ func rootCmd(p *qliksense.Qliksense) *cobra.Command {
 	// add set-image-registry command as a sub-command to the app config sub-command
 	configCmd.AddCommand(setImageRegistryCmd(p))
 
+	// add set-image-mapping command as a sub-command to the app config sub-command
+	configCmd.AddCommand(setImageMappingCmd(p))
+	configCmd.AddCommand(setImageMirrorsCmd(p))
+
+	// add set-image-policy command as a sub-command to the app config sub-command
+	configCmd.AddCommand(setImagePolicyCmd(p))
+
 	// add clean-config-repo-patches command as a sub-command to the app config sub-command
 	configCmd.AddCommand(cleanConfigRepoPatchesCmd(p))
 
@@ -6,23 +6,31 @@ import (
 )
 
 func uninstallCmd(q *qliksense.Qliksense) *cobra.Command {
-	skipConfirmation := false
+	opts := &qliksense.UninstallCommandOptions{}
 	c := &cobra.Command{
-		Use:     "uninstall",
-		Short:   "Uninstall the deployed qliksense.",
-		Long:    `Uninstall the deployed qliksense. By default uninstall the current context`,
-		Example: `qliksense uninstall <context-name>`,
+		Use:   "uninstall",
+		Short: "Uninstall the deployed qliksense.",
+		Long: `Uninstall the deployed qliksense. By default uninstall the current context.
+Everything the install created is deleted: the CR, the generated manifests, the resources used by the patches,
+the image pull secret and the operator controller. Persistent volume claims and CRDs are kept unless purged.`,
+		Example: `qliksense uninstall <context-name>
+qliksense uninstall --dry-run
+qliksense uninstall --purge-pvcs --purge-crds`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
-				return q.UninstallQK8s(args[0], skipConfirmation)
+				return q.UninstallQK8s(args[0], opts)
 			}
-			return q.UninstallQK8s("", skipConfirmation)
+			return q.UninstallQK8s("", opts)
 		},
 	}
 
 	f := c.Flags()
 
-	f.BoolVar(&skipConfirmation, "yes", skipConfirmation, "skips confirmation")
+	f.BoolVar(&opts.SkipConfirmation, "yes", opts.SkipConfirmation, "skips confirmation, purges are confirmed nonetheless")
+	f.BoolVar(&opts.DryRun, "dry-run", opts.DryRun, "only list the resources that would be deleted")
+	f.BoolVar(&opts.PurgePVCs, "purge-pvcs", opts.PurgePVCs, "also delete the persistent volume claims, all data is lost")
+	f.BoolVar(&opts.PurgeCRDs, "purge-crds", opts.PurgeCRDs, "also delete the CRDs, resources of those kinds of other contexts are deleted as well")
+	f.BoolVar(&opts.ForceUnlock, forceUnlockFlagName, opts.ForceUnlock, forceUnlockFlagUsage)
 
 	return c
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/qlik-oss/sense-installer/pkg/qliksense"
+	"github.com/spf13/cobra"
+)
+
+func upgradeCmd(q *qliksense.Qliksense) *cobra.Command {
+	opts := &qliksense.UpgradeCommandOptions{
+		CleanPatchFiles: true,
+	}
+	progressOpts := &progressOptions{}
+	c := &cobra.Command{
+		Use:   "upgrade <version>",
+		Short: "upgrade qliksense to a new release",
+		Long: `upgrade qliksense to a new release. It fetches the version, shows the images and resources that change
+and applies the new version into the cluster. Every upgrade is recorded as a revision for qliksense rollback`,
+		Example: `qliksense upgrade v1.2.3
+qliksense upgrade v1.2.3 --dry-run`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withProgressReporter(q, progressOpts, func(r qliksense.Reporter) error {
+				opts.Reporter = r
+				if err := q.UpgradeQK8s(args[0], opts); err != nil {
+					return err
+				}
+				if opts.DryRun {
+					return nil
+				}
+				return runPostflightChecks(q, r)
+			})
+		},
+	}
+
+	f := c.Flags()
+	f.BoolVar(&opts.CleanPatchFiles, cleanPatchFilesFlagName, opts.CleanPatchFiles, cleanPatchFilesFlagUsage)
+	f.BoolVarP(&opts.Pull, pullFlagName, pullFlagShorthand, opts.Pull, pullFlagUsage)
+	f.BoolVarP(&opts.Push, pushFlagName, pushFlagShorthand, opts.Push, pushFlagUsage)
+	f.IntVar(&opts.Images.Parallel, parallelFlagName, 1, parallelFlagUsage)
+	f.StringVar(&opts.Images.Platform, platformFlagName, "", platformFlagUsage)
+	f.BoolVar(&opts.Images.AllPlatforms, allPlatformsFlagName, false, allPlatformsFlagUsage)
+	f.BoolVarP(&opts.DryRun, "dry-run", "", false, "Only show the images and resources that would change")
+	addProgressFlags(c, progressOpts)
+	f.BoolVar(&opts.ForceUnlock, forceUnlockFlagName, opts.ForceUnlock, forceUnlockFlagUsage)
+
+	return c
+}
+
+func rollbackCmd(q *qliksense.Qliksense) *cobra.Command {
+	opts := &qliksense.RollbackCommandOptions{}
+	c := &cobra.Command{
+		Use:   "rollback",
+		Short: "rollback qliksense to a recorded revision",
+		Long: `rollback qliksense to a recorded revision of the current context. Without --to the revision before
+the latest one is reapplied. Use qliksense history to list the revisions`,
+		Example: `qliksense rollback
+qliksense rollback --to 3`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return q.RollbackQK8s(opts)
+		},
+	}
+
+	f := c.Flags()
+	f.IntVarP(&opts.ToRevision, "to", "", 0, "Revision to reapply, defaults to the revision before the latest one")
+	f.BoolVarP(&opts.DryRun, "dry-run", "", false, "Only show the revision that would be reapplied")
+	f.BoolVar(&opts.ForceUnlock, forceUnlockFlagName, opts.ForceUnlock, forceUnlockFlagUsage)
+
+	return c
+}
@@ -305,6 +305,28 @@ func (qc *QliksenseConfig) IsContextExist(ctxName string) bool {
 	return false
 }
 
+// GetContext returns the context with the name, nil if it does not exist
+func (qc *QliksenseConfig) GetContext(ctxName string) *Context {
+	for i := range qc.Spec.Contexts {
+		if qc.Spec.Contexts[i].Name == ctxName {
+			return &qc.Spec.Contexts[i]
+		}
+	}
+	return nil
+}
+
+// SetContextBinding binds the context to the kube config, kube context and namespace, empty values unbind
+func (qc *QliksenseConfig) SetContextBinding(ctxName, kubeConfig, kubeContext, namespace string) error {
+	ctx := qc.GetContext(ctxName)
+	if ctx == nil {
+		return fmt.Errorf("context name [ %s ] not found", ctxName)
+	}
+	ctx.KubeConfig = kubeConfig
+	ctx.KubeContext = kubeContext
+	ctx.Namespace = namespace
+	return qc.Write()
+}
+
 func (qc *QliksenseConfig) GetCurrentContextDir() (string, error) {
 	if qcr, err := qc.GetCurrentCR(); err != nil {
 		return "", err
@@ -531,6 +553,44 @@ func (qc *QliksenseConfig) GetDecryptedCr(cr *QliksenseCR) (*QliksenseCR, error)
 	return newCr, nil
 }
 
+// GetEncryptedCr it encrypts all the secret values of a decrypted CR and return a new CR
+func (qc *QliksenseConfig) GetEncryptedCr(cr *QliksenseCR) (*QliksenseCR, error) {
+	newCr := &QliksenseCR{}
+	copier.Copy(newCr, cr)
+	// the spec is shared after copying, do not modify the secrets of the given CR
+	newSpec := *cr.Spec
+	newCr.Spec = &newSpec
+	encryptionKey, err := qc.GetEncryptionKeyFor(cr.GetName())
+	if err != nil {
+		return nil, err
+	}
+	finalSecrets := map[string]config.NameValues{}
+	for k, nvs := range newCr.Spec.Secrets {
+		newNvs := config.NameValues{}
+		for _, nv := range nvs {
+			if nv.Value != "" {
+				eb, err := EncryptData([]byte(nv.Value), encryptionKey)
+				if err != nil {
+					return nil, err
+				}
+				nv.Value = b64.StdEncoding.EncodeToString(eb)
+			}
+			newNvs = append(newNvs, nv)
+		}
+		finalSecrets[k] = newNvs
+	}
+	newCr.Spec.Secrets = finalSecrets
+
+	if newCr.Spec.Git != nil && newCr.Spec.Git.AccessToken != "" {
+		gitRepo := *newCr.Spec.Git
+		newCr.Spec.Git = &gitRepo
+		if err := newCr.SetFetchAccessToken(cr.Spec.Git.AccessToken, encryptionKey); err != nil {
+			return nil, err
+		}
+	}
+	return newCr, nil
+}
+
 //Validate validate CR
 func (cr *QliksenseCR) Validate() bool {
 	return true
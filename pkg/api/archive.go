@@ -0,0 +1,335 @@
+package api
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChecksumsFileName lists the sha256 of every file of a directory, in the format of sha256sum
+const ChecksumsFileName = "SHA256SUMS"
+
+// WriteChecksums writes the sha256 of every regular file below dir into dir/SHA256SUMS
+func WriteChecksums(dir string) error {
+	checksums, err := computeChecksums(dir)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for p := range checksums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", checksums[p], p)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, ChecksumsFileName), []byte(b.String()), 0644)
+}
+
+// VerifyChecksums checks that the files below dir are exactly the ones listed in dir/SHA256SUMS, with the same sha256
+func VerifyChecksums(dir string) error {
+	checksums, err := computeChecksums(dir)
+	if err != nil {
+		return err
+	}
+	return compareChecksums(dir, checksums)
+}
+
+// VerifyExtractedChecksums checks the files ExtractArchive wrote into dir against dir/SHA256SUMS: the checksums
+// it returned, of the content as it was written, and the files below dir as they are now
+func VerifyExtractedChecksums(dir string, written map[string]string) error {
+	delete(written, ChecksumsFileName)
+	if err := compareChecksums(dir, written); err != nil {
+		return err
+	}
+	return VerifyChecksums(dir)
+}
+
+// compareChecksums checks that checksums has exactly the files listed in dir/SHA256SUMS, with the same sha256
+func compareChecksums(dir string, checksums map[string]string) error {
+	f, err := os.Open(filepath.Join(dir, ChecksumsFileName))
+	if err != nil {
+		return fmt.Errorf("unable to read the checksums: %w", err)
+	}
+	defer f.Close()
+	expected := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "  ", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid checksum line %q", scanner.Text())
+		}
+		expected[parts[1]] = parts[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for p, sum := range expected {
+		if actual, ok := checksums[p]; !ok {
+			return fmt.Errorf("%s is missing", p)
+		} else if actual != sum {
+			return fmt.Errorf("checksum of %s does not match", p)
+		}
+	}
+	for p := range checksums {
+		if _, ok := expected[p]; !ok {
+			return fmt.Errorf("%s is not listed in %s", p, ChecksumsFileName)
+		}
+	}
+	return nil
+}
+
+// computeChecksums returns the sha256 of every regular file below dir by slash separated relative path
+func computeChecksums(dir string) (map[string]string, error) {
+	checksums := map[string]string{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ChecksumsFileName {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		checksums[rel] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	return checksums, err
+}
+
+// ArchiveDirectory writes the content of dir into a gzipped tar archive
+func ArchiveDirectory(dir, archiveFile string) error {
+	out, err := os.Create(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gzWriter := gzip.NewWriter(out)
+	if err := writeTar(gzWriter, dir); err != nil {
+		return err
+	} else if err := gzWriter.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// TarDirectory writes the content of dir into an uncompressed tar archive
+func TarDirectory(dir, tarFile string) error {
+	out, err := os.Create(tarFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := writeTar(out, dir); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func writeTar(w io.Writer, dir string) error {
+	tarWriter := tar.NewWriter(w)
+	if err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tarWriter, f)
+		return err
+	}); err != nil {
+		return err
+	}
+	return tarWriter.Close()
+}
+
+// ExtractArchive extracts a gzipped tar archive into dir, entries pointing outside of dir are refused.
+// It returns the sha256 of the regular files it wrote by slash separated relative path.
+func ExtractArchive(archiveFile, dir string) (map[string]string, error) {
+	in, err := os.Open(archiveFile)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	gzReader, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", archiveFile, err)
+	}
+	defer gzReader.Close()
+	checksums, err := readTar(gzReader, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", archiveFile, err)
+	}
+	return checksums, nil
+}
+
+// ExtractTar extracts an uncompressed tar archive into dir, entries pointing outside of dir are refused
+func ExtractTar(tarFile, dir string) error {
+	in, err := os.Open(tarFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if _, err := readTar(in, dir); err != nil {
+		return fmt.Errorf("unable to read %s: %w", tarFile, err)
+	}
+	return nil
+}
+
+// readTar extracts the entries of the archive into dir and returns the sha256 of the regular files it wrote.
+// Symlinks are extracted, but directories are only created or followed through links resolving within dir,
+// files are never written through a link and no link may resolve outside of dir once extracted.
+func readTar(r io.Reader, dir string) (map[string]string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	checksums := map[string]string{}
+	var links []string
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !isWithinDir(dir, target) {
+			return nil, fmt.Errorf("archive entry %s is outside of the archive", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirWithinDir(dir, realDir, target); err != nil {
+				return nil, fmt.Errorf("archive entry %s: %w", header.Name, err)
+			}
+		case tar.TypeReg:
+			if err := mkdirWithinDir(dir, realDir, filepath.Dir(target)); err != nil {
+				return nil, fmt.Errorf("archive entry %s: %w", header.Name, err)
+			} else if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return nil, fmt.Errorf("archive entry %s overwrites a symlink", header.Name)
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return nil, err
+			}
+			h := sha256.New()
+			if _, err := io.Copy(io.MultiWriter(f, h), tarReader); err != nil {
+				f.Close()
+				return nil, err
+			} else if err := f.Close(); err != nil {
+				return nil, err
+			}
+			checksums[filepath.ToSlash(filepath.Clean(filepath.FromSlash(header.Name)))] = hex.EncodeToString(h.Sum(nil))
+		case tar.TypeSymlink:
+			if filepath.IsAbs(header.Linkname) || !isWithinDir(dir, filepath.Join(filepath.Dir(target), header.Linkname)) {
+				return nil, fmt.Errorf("archive entry %s links outside of the archive", header.Name)
+			}
+			if err := mkdirWithinDir(dir, realDir, filepath.Dir(target)); err != nil {
+				return nil, fmt.Errorf("archive entry %s: %w", header.Name, err)
+			} else if err := os.Symlink(header.Linkname, target); err != nil {
+				return nil, err
+			}
+			links = append(links, target)
+		}
+	}
+	// a link is only resolved once what it points to is extracted, through the links extracted after it
+	for _, link := range links {
+		if resolved, err := filepath.EvalSymlinks(link); err == nil && !isWithinDir(realDir, resolved) {
+			return nil, fmt.Errorf("archive entry %s links outside of the archive", link)
+		}
+	}
+	return checksums, nil
+}
+
+// mkdirWithinDir creates the directory p below dir. The existing symlinks on the way have to resolve to directories
+// within realDir, the resolved dir, the path is never created through a dangling link.
+func mkdirWithinDir(dir, realDir, p string) error {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return err
+	}
+	current := dir
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if name == "." {
+			continue
+		}
+		current = filepath.Join(current, name)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			if err := os.Mkdir(current, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			resolved, err := filepath.EvalSymlinks(current)
+			if err != nil {
+				return fmt.Errorf("%s is a dangling symlink", name)
+			} else if !isWithinDir(realDir, resolved) {
+				return fmt.Errorf("%s links outside of the archive", name)
+			} else if info, err = os.Stat(resolved); err != nil {
+				return err
+			}
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", name)
+		}
+	}
+	return nil
+}
+
+func isWithinDir(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveDirectory(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	src := filepath.Join(tmp, "src")
+	files := map[string]string{
+		"bundle.yaml":                 "version: v1.0.0\n",
+		"repo/manifests/base/a.yaml":  "kind: ConfigMap\n",
+		"images/index/engine/1.0/idx": "{}",
+	}
+	for name, content := range files {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		} else if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := WriteChecksums(src); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(tmp, "bundle.tar.gz")
+	if err := ArchiveDirectory(src, archive); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(tmp, "dest")
+	written, err := ExtractArchive(archive, dest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := VerifyExtractedChecksums(dest, written); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// what was written is checked, not only what is on disk afterwards
+	written["repo/manifests/base/a.yaml"] = "0"
+	if err := VerifyExtractedChecksums(dest, written); err == nil || !strings.Contains(err.Error(), "a.yaml") {
+		t.Errorf("expected the file written with another content to be reported, got %v", err)
+	}
+	for name, content := range files {
+		if b, err := ioutil.ReadFile(filepath.Join(dest, filepath.FromSlash(name))); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		} else if string(b) != content {
+			t.Errorf("expected %q in %s, got %q", content, name, string(b))
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dest, "repo/manifests/base/a.yaml"), []byte("kind: Secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksums(dest); err == nil || !strings.Contains(err.Error(), "a.yaml") {
+		t.Errorf("expected the changed file to be reported, got %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dest, "extra"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dest, "repo/manifests/base/a.yaml"), []byte("kind: ConfigMap\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksums(dest); err == nil || !strings.Contains(err.Error(), "extra") {
+		t.Errorf("expected the unlisted file to be reported, got %v", err)
+	}
+}
+
+func TestExtractArchive_outside(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+	}{
+		{name: "file", headers: []*tar.Header{{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644}}},
+		{name: "symlink", headers: []*tar.Header{{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"}}},
+		{name: "absolute symlink", headers: []*tar.Header{{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}}},
+		{name: "file through chained symlinks", headers: []*tar.Header{
+			{Name: "l", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "l/x", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "l/x/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		}},
+		{name: "symlink through symlinks", headers: []*tar.Header{
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "x", Typeflag: tar.TypeSymlink, Linkname: "a/../outside"},
+			{Name: "x/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		}},
+		{name: "file over a symlink", headers: []*tar.Header{
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "missing"},
+			{Name: "link", Typeflag: tar.TypeReg, Mode: 0644},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(tmp, tt.name, "dest")
+			archive := filepath.Join(tmp, tt.name, "evil.tar.gz")
+			if err := os.MkdirAll(filepath.Dir(archive), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Create(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gzWriter := gzip.NewWriter(f)
+			tarWriter := tar.NewWriter(gzWriter)
+			for _, header := range tt.headers {
+				if err := tarWriter.WriteHeader(header); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tarWriter.Close()
+			gzWriter.Close()
+			f.Close()
+			if _, err := ExtractArchive(archive, dest); err == nil {
+				t.Error("expected the entry to be refused")
+			}
+			for _, outside := range []string{"evil", "outside"} {
+				if _, err := os.Lstat(filepath.Join(tmp, tt.name, outside)); !os.IsNotExist(err) {
+					t.Errorf("expected nothing written outside of the archive, found %s", outside)
+				}
+			}
+		})
+	}
+}
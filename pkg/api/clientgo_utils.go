@@ -4,12 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"time"
 
-	"github.com/mitchellh/go-homedir"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -39,24 +37,16 @@ func (p *ClientGoUtils) LogVerboseMessage(strMessage string, args ...interface{}
 func int32Ptr(i int32) *int32 { return &i }
 
 func (p *ClientGoUtils) LoadKubeConfigAndNamespace() (string, []byte, error) {
-	LogDebugMessage("Reading .kube/config file...")
+	LogDebugMessage("Reading kube config...")
 
-	homeDir, err := homedir.Dir()
+	kubeConfigContents, err := LoadKubeConfigContents()
 	if err != nil {
-		err = fmt.Errorf("Unable to deduce home dir")
+		err = fmt.Errorf("Unable to load kube config: %w", err)
 		return "", nil, err
 	}
-	LogDebugMessage("Kube config location: %s\n\n", filepath.Join(homeDir, ".kube", "config"))
 
-	kubeConfig := filepath.Join(homeDir, ".kube", "config")
-	kubeConfigContents, err := ioutil.ReadFile(kubeConfig)
-	if err != nil {
-		err = fmt.Errorf("Unable to deduce home dir")
-		return "", nil, err
-	}
-
-	// retrieve namespace
-	namespace := GetKubectlNamespace()
+	// retrieve namespace, the kube config was loaded above
+	namespace, _ := GetKubeNamespace()
 	// if namespace comes back empty, we will run checks in the default namespace
 	if namespace == "" {
 		namespace = "default"
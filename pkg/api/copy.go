@@ -1,8 +1,30 @@
 package api
 
-import "github.com/otiai10/copy"
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/otiai10/copy"
+)
 
 //copy source directory to destination
 func CopyDirectory(source string, dest string) error {
 	return copy.Copy(source, dest)
 }
+
+// MoveDirectory moves the source directory to dest, replacing dest if it exists. Within a file system the
+// directory is renamed, across file systems it is copied and removed.
+func MoveDirectory(source string, dest string) error {
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(source, dest); err == nil {
+		return nil
+	}
+	if err := CopyDirectory(source, dest); err != nil {
+		return err
+	}
+	return os.RemoveAll(source)
+}
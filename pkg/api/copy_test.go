@@ -28,6 +28,39 @@ func TestCopyDirectory(t *testing.T) {
 	}
 }
 
+func TestMoveDirectory(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	src := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ioutil.WriteFile(filepath.Join(src, "sub", "file"), []byte("moved"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dest := filepath.Join(tmpDir, "parent", "dest")
+	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ioutil.WriteFile(filepath.Join(dest, "stale"), []byte("stale"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := MoveDirectory(src, dest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(dest, "sub", "file")); err != nil || string(content) != "moved" {
+		t.Errorf("expected the moved file, got %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "stale")); !os.IsNotExist(err) {
+		t.Errorf("expected the previous content of dest to be replaced, got %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("expected the source to be gone, got %v", err)
+	}
+}
+
 func TestCopyDirectory_withGit_withKuz(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping in short test mode")
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ChangeAdded     = "added"
+	ChangeChanged   = "changed"
+	ChangeRemoved   = "removed"
+	ChangeUnchanged = "unchanged"
+
+	diffOperation = "diff"
+	maskedValue   = "***"
+)
+
+// ObjectDiff is the difference between the live and the rendered version of one object
+type ObjectDiff struct {
+	Kind         string `json:"kind"`
+	Namespace    string `json:"namespace,omitempty"`
+	Name         string `json:"name"`
+	Change       string `json:"change"`
+	Diff         string `json:"diff,omitempty"`
+	LinesAdded   int    `json:"linesAdded"`
+	LinesRemoved int    `json:"linesRemoved"`
+}
+
+// DiffSummary counts the objects per change and the changed lines
+type DiffSummary struct {
+	Added        int `json:"added"`
+	Changed      int `json:"changed"`
+	Removed      int `json:"removed"`
+	Unchanged    int `json:"unchanged"`
+	LinesAdded   int `json:"linesAdded"`
+	LinesRemoved int `json:"linesRemoved"`
+}
+
+// ManifestsDiff is the difference between the live cluster and rendered manifests, unchanged objects are only counted
+type ManifestsDiff struct {
+	Summary DiffSummary   `json:"summary"`
+	Objects []*ObjectDiff `json:"objects"`
+}
+
+// Diff compares the manifests with the live objects. The live version of every object is compared with the
+// result of a server-side dry-run apply. Objects of previousManifests that are not in manifests
+// but still exist in the cluster are reported as removed. Secret data is masked.
+func (e *ApplyEngine) Diff(manifests, previousManifests, namespace string) (*ManifestsDiff, error) {
+	objs, err := decodeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+	previousObjs, err := decodeManifests(previousManifests)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ManifestsDiff{}
+	var errs ObjectErrors
+	rendered := map[string]bool{}
+	for _, obj := range objs {
+		objDiff, err := e.diffObject(obj, namespace)
+		rendered[objectKey(obj)] = true
+		if err != nil {
+			errs = append(errs, e.objectError(diffOperation, obj, err))
+			continue
+		}
+		result.add(objDiff)
+	}
+	for _, obj := range previousObjs {
+		ri, _, err := e.resourceFor(obj, namespace, false)
+		if meta.IsNoMatchError(err) || rendered[objectKey(obj)] {
+			continue
+		} else if err != nil {
+			errs = append(errs, e.objectError(diffOperation, obj, err))
+			continue
+		}
+		live, err := ri.Get(obj.GetName(), metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			errs = append(errs, e.objectError(diffOperation, obj, err))
+			continue
+		}
+		objDiff, err := newObjectDiff(obj, live, nil)
+		if err != nil {
+			return nil, err
+		}
+		result.add(objDiff)
+	}
+	if len(errs) > 0 {
+		return result, errs
+	}
+	return result, nil
+}
+
+func (e *ApplyEngine) diffObject(obj *unstructured.Unstructured, namespace string) (*ObjectDiff, error) {
+	ri, _, err := e.resourceFor(obj, namespace, false)
+	if meta.IsNoMatchError(err) {
+		// the type comes with the manifests, i.e. a new CRD
+		return newObjectDiff(obj, nil, obj)
+	} else if err != nil {
+		return nil, err
+	}
+	live, err := ri.Get(obj.GetName(), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return newObjectDiff(obj, nil, obj)
+	} else if err != nil {
+		return nil, err
+	}
+	e.stamp(obj)
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	if err != nil {
+		return nil, err
+	}
+	force := true
+	merged, err := ri.Patch(obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: applyFieldManager,
+		Force:        &force,
+		DryRun:       []string{metav1.DryRunAll},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newObjectDiff(obj, live, merged)
+}
+
+// newObjectDiff builds the unified diff from the live object (nil if it does not exist)
+// to the object after apply (nil if it gets removed)
+func newObjectDiff(obj, live, merged *unstructured.Unstructured) (*ObjectDiff, error) {
+	objDiff := &ObjectDiff{
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	switch {
+	case live == nil:
+		objDiff.Change = ChangeAdded
+	case merged == nil:
+		objDiff.Change = ChangeRemoved
+	default:
+		objDiff.Change = ChangeChanged
+	}
+	before, after := normalizeForDiff(live), normalizeForDiff(merged)
+	if obj.GetKind() == "Secret" {
+		maskSecretData(before, after)
+	}
+	beforeYaml, err := toDiffYaml(before)
+	if err != nil {
+		return nil, err
+	}
+	afterYaml, err := toDiffYaml(after)
+	if err != nil {
+		return nil, err
+	}
+	if beforeYaml == afterYaml {
+		objDiff.Change = ChangeUnchanged
+		return objDiff, nil
+	}
+	name := objDiff.Kind + "/" + objDiff.Name
+	if objDiff.Namespace != "" {
+		name = objDiff.Kind + "/" + objDiff.Namespace + "/" + objDiff.Name
+	}
+	objDiff.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(beforeYaml),
+		B:        difflib.SplitLines(afterYaml),
+		FromFile: "live/" + name,
+		ToFile:   "rendered/" + name,
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	objDiff.LinesAdded, objDiff.LinesRemoved = countDiffLines(objDiff.Diff)
+	return objDiff, nil
+}
+
+func (d *ManifestsDiff) add(objDiff *ObjectDiff) {
+	switch objDiff.Change {
+	case ChangeAdded:
+		d.Summary.Added++
+	case ChangeChanged:
+		d.Summary.Changed++
+	case ChangeRemoved:
+		d.Summary.Removed++
+	default:
+		d.Summary.Unchanged++
+		return
+	}
+	d.Summary.LinesAdded += objDiff.LinesAdded
+	d.Summary.LinesRemoved += objDiff.LinesRemoved
+	d.Objects = append(d.Objects, objDiff)
+}
+
+// ObjectsWithChange returns the objects with the given change, in the order they were compared
+func (d *ManifestsDiff) ObjectsWithChange(change string) []*ObjectDiff {
+	var objs []*ObjectDiff
+	for _, objDiff := range d.Objects {
+		if objDiff.Change == change {
+			objs = append(objs, objDiff)
+		}
+	}
+	return objs
+}
+
+// normalizeForDiff drops the status and the metadata maintained by the server
+func normalizeForDiff(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil {
+		return nil
+	}
+	obj = obj.DeepCopy()
+	delete(obj.Object, "status")
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "creationTimestamp", "generation", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "annotations", "deployment.kubernetes.io/revision")
+	// every apply stamps a new revision
+	unstructured.RemoveNestedField(obj.Object, "metadata", "annotations", RevisionAnnotation)
+	if annotations, found, _ := unstructured.NestedMap(obj.Object, "metadata", "annotations"); found && len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+	}
+	return obj
+}
+
+// maskSecretData replaces the values of secrets, changed values are marked as before and after
+func maskSecretData(before, after *unstructured.Unstructured) {
+	for _, field := range []string{"data", "stringData"} {
+		var beforeData, afterData map[string]interface{}
+		if before != nil {
+			beforeData, _, _ = unstructured.NestedMap(before.Object, field)
+		}
+		if after != nil {
+			afterData, _, _ = unstructured.NestedMap(after.Object, field)
+		}
+		maskedBefore := map[string]interface{}{}
+		maskedAfter := map[string]interface{}{}
+		for k, v := range beforeData {
+			if afterValue, ok := afterData[k]; ok && afterValue == v {
+				maskedBefore[k] = maskedValue
+				maskedAfter[k] = maskedValue
+			} else {
+				maskedBefore[k] = maskedValue + " (before)"
+			}
+		}
+		for k := range afterData {
+			if _, ok := maskedAfter[k]; !ok {
+				maskedAfter[k] = maskedValue + " (after)"
+			}
+		}
+		if beforeData != nil {
+			unstructured.SetNestedMap(before.Object, maskedBefore, field)
+		}
+		if afterData != nil {
+			unstructured.SetNestedMap(after.Object, maskedAfter, field)
+		}
+	}
+}
+
+func toDiffYaml(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	b, err := yaml.Marshal(obj.Object)
+	return string(b), err
+}
+
+func countDiffLines(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+func objectKey(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s/%s/%s/%s", obj.GroupVersionKind().Group, obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
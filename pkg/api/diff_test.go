@@ -0,0 +1,127 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestObject(kind, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for k, v := range fields {
+		obj.Object[k] = v
+	}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace("qliksense")
+	return obj
+}
+
+func Test_newObjectDiff(t *testing.T) {
+	live := newTestObject("ConfigMap", "engine-configs", map[string]interface{}{"data": map[string]interface{}{"a": "1"}})
+	live.SetResourceVersion("42")
+	same := newTestObject("ConfigMap", "engine-configs", map[string]interface{}{"data": map[string]interface{}{"a": "1"}})
+	changed := newTestObject("ConfigMap", "engine-configs", map[string]interface{}{"data": map[string]interface{}{"a": "2"}})
+
+	if objDiff, err := newObjectDiff(same, live, same); err != nil {
+		t.Fatal(err)
+	} else if objDiff.Change != ChangeUnchanged {
+		t.Fatalf("expected %s, got %s: %s", ChangeUnchanged, objDiff.Change, objDiff.Diff)
+	}
+
+	objDiff, err := newObjectDiff(changed, live, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objDiff.Change != ChangeChanged || objDiff.LinesAdded != 1 || objDiff.LinesRemoved != 1 {
+		t.Fatalf("expected one changed line, got %s +%d -%d: %s", objDiff.Change, objDiff.LinesAdded, objDiff.LinesRemoved, objDiff.Diff)
+	}
+	if !strings.Contains(objDiff.Diff, "-  a: \"1\"") || !strings.Contains(objDiff.Diff, "+  a: \"2\"") {
+		t.Fatalf("unexpected diff: %s", objDiff.Diff)
+	}
+
+	if objDiff, err := newObjectDiff(live, live, nil); err != nil {
+		t.Fatal(err)
+	} else if objDiff.Change != ChangeRemoved || objDiff.LinesAdded != 0 {
+		t.Fatalf("expected %s, got %s: %s", ChangeRemoved, objDiff.Change, objDiff.Diff)
+	}
+}
+
+func Test_newObjectDiff_masksSecrets(t *testing.T) {
+	live := newTestObject("Secret", "engine-secrets", map[string]interface{}{"data": map[string]interface{}{
+		"same": "c2FtZQ==", "old": "b2xk",
+	}})
+	rendered := newTestObject("Secret", "engine-secrets", map[string]interface{}{"data": map[string]interface{}{
+		"same": "c2FtZQ==", "old": "bmV3",
+	}})
+	objDiff, err := newObjectDiff(rendered, live, rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"c2FtZQ==", "b2xk", "bmV3"} {
+		if strings.Contains(objDiff.Diff, value) {
+			t.Fatalf("secret value %s is not masked: %s", value, objDiff.Diff)
+		}
+	}
+	if !strings.Contains(objDiff.Diff, "-  old: '*** (before)'") || !strings.Contains(objDiff.Diff, "+  old: '*** (after)'") {
+		t.Fatalf("expected the changed value to be marked: %s", objDiff.Diff)
+	}
+
+	added, err := newObjectDiff(rendered, nil, rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Change != ChangeAdded || strings.Contains(added.Diff, "bmV3") {
+		t.Fatalf("expected an added secret with masked values: %s", added.Diff)
+	}
+}
+
+func TestApplyEngine_Diff(t *testing.T) {
+	engine, client := newTestApplyEngine()
+	live := newTestObject("ConfigMap", "engine-configs", map[string]interface{}{"data": map[string]interface{}{"key": "old"}})
+	removed := newTestObject("ConfigMap", "old-configs", nil)
+	configMaps := client.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).Namespace("qliksense")
+	for _, obj := range []*unstructured.Unstructured{live, removed} {
+		if _, err := configMaps.Create(obj, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		// the dry-run apply of engine-configs returns the rendered object
+		return true, newTestObject("ConfigMap", "engine-configs", map[string]interface{}{"data": map[string]interface{}{"key": "value"}}), nil
+	})
+
+	previous := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: engine-configs
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old-configs
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: already-gone
+`
+	manifestsDiff, err := engine.Diff(testApplyManifests, previous, "qliksense")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := manifestsDiff.Summary
+	if s.Added != 2 || s.Changed != 1 || s.Removed != 1 || s.Unchanged != 0 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	if removed := manifestsDiff.ObjectsWithChange(ChangeRemoved); removed[0].Name != "old-configs" {
+		t.Fatalf("expected old-configs to be removed, got %v", removed[0].Name)
+	}
+}
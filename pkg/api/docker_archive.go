@@ -0,0 +1,194 @@
+package api
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// files of a docker-archive, as written by docker save
+const (
+	dockerArchiveManifestFileName     = "manifest.json"
+	dockerArchiveRepositoriesFileName = "repositories"
+)
+
+// DockerArchiveManifestItem is an image in the manifest.json of a docker-archive
+type DockerArchiveManifestItem struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+	Parent   string `json:",omitempty"`
+}
+
+// MergeDockerArchives writes the images of several docker-archive files into a single docker-archive.
+// The files of the images are content addressed, a file in several archives is written once.
+func MergeDockerArchives(archiveFiles []string, archiveFile string) error {
+	out, err := os.Create(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	tarWriter := tar.NewWriter(out)
+	var items []*DockerArchiveManifestItem
+	repositories := map[string]map[string]string{}
+	written := map[string]bool{}
+	for _, f := range archiveFiles {
+		if err := mergeDockerArchive(tarWriter, f, &items, repositories, written); err != nil {
+			return fmt.Errorf("unable to read %s: %w", f, err)
+		}
+	}
+	if b, err := json.Marshal(items); err != nil {
+		return err
+	} else if err := writeTarFile(tarWriter, dockerArchiveManifestFileName, b); err != nil {
+		return err
+	}
+	if len(repositories) > 0 {
+		if b, err := json.Marshal(repositories); err != nil {
+			return err
+		} else if err := writeTarFile(tarWriter, dockerArchiveRepositoriesFileName, b); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func mergeDockerArchive(tarWriter *tar.Writer, archiveFile string, items *[]*DockerArchiveManifestItem, repositories map[string]map[string]string, written map[string]bool) error {
+	in, err := os.Open(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tarReader := tar.NewReader(in)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch header.Name {
+		case dockerArchiveManifestFileName:
+			var archiveItems []*DockerArchiveManifestItem
+			if err := json.NewDecoder(tarReader).Decode(&archiveItems); err != nil {
+				return err
+			}
+			*items = append(*items, archiveItems...)
+		case dockerArchiveRepositoriesFileName:
+			archiveRepositories := map[string]map[string]string{}
+			if err := json.NewDecoder(tarReader).Decode(&archiveRepositories); err != nil {
+				return err
+			}
+			for repo, tags := range archiveRepositories {
+				if repositories[repo] == nil {
+					repositories[repo] = map[string]string{}
+				}
+				for tag, id := range tags {
+					repositories[repo][tag] = id
+				}
+			}
+		default:
+			if written[header.Name] {
+				continue
+			}
+			written[header.Name] = true
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			} else if _, err := io.Copy(tarWriter, tarReader); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ReadDockerArchiveManifest returns the images of the docker-archive extracted into dir
+func ReadDockerArchiveManifest(dir string) ([]*DockerArchiveManifestItem, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, dockerArchiveManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	var items []*DockerArchiveManifestItem
+	if err := json.Unmarshal(b, &items); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", dockerArchiveManifestFileName, err)
+	}
+	return items, nil
+}
+
+// WriteDockerArchiveImage writes a docker-archive holding only the image item of the docker-archive extracted into dir
+func WriteDockerArchiveImage(dir string, item *DockerArchiveManifestItem, archiveFile string) error {
+	out, err := os.Create(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	tarWriter := tar.NewWriter(out)
+	single := &DockerArchiveManifestItem{Config: item.Config, RepoTags: item.RepoTags, Layers: item.Layers}
+	if b, err := json.Marshal([]*DockerArchiveManifestItem{single}); err != nil {
+		return err
+	} else if err := writeTarFile(tarWriter, dockerArchiveManifestFileName, b); err != nil {
+		return err
+	}
+	written := map[string]bool{}
+	for _, name := range append([]string{item.Config}, item.Layers...) {
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if !isWithinDir(dir, p) {
+			return fmt.Errorf("%s is outside of the archive", name)
+		}
+		// layers may be symlinks to the legacy layer directories, the content is written instead
+		if err := copyFileIntoTar(tarWriter, p, name); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func copyFileIntoTar(tarWriter *tar.Writer, p, name string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, f)
+	return err
+}
+
+func writeTarFile(tarWriter *tar.Writer, name string, content []byte) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err := tarWriter.Write(content)
+	return err
+}
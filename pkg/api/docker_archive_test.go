@@ -0,0 +1,102 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestDockerArchive(t *testing.T, dir, archiveFile string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := TarDirectory(dir, archiveFile); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeDockerArchives(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	engine := filepath.Join(tmp, "engine.tar")
+	writeTestDockerArchive(t, filepath.Join(tmp, "engine"), engine, map[string]string{
+		"manifest.json": `[{"Config":"c1.json","RepoTags":["qlik/engine:1.0"],"Layers":["base.tar","l1.tar"]}]`,
+		"repositories":  `{"qlik/engine":{"1.0":"id1"}}`,
+		"c1.json":       "config 1",
+		"base.tar":      "base layer",
+		"l1.tar":        "layer 1",
+	})
+	edgeAuth := filepath.Join(tmp, "edge-auth.tar")
+	writeTestDockerArchive(t, filepath.Join(tmp, "edge-auth"), edgeAuth, map[string]string{
+		"manifest.json": `[{"Config":"c2.json","RepoTags":["qlik/edge-auth:2.0"],"Layers":["base.tar","l2.tar"]}]`,
+		"repositories":  `{"qlik/edge-auth":{"2.0":"id2"}}`,
+		"c2.json":       "config 2",
+		"base.tar":      "base layer",
+		"l2.tar":        "layer 2",
+	})
+
+	merged := filepath.Join(tmp, "images.tar")
+	if err := MergeDockerArchives([]string{engine, edgeAuth}, merged); err != nil {
+		t.Fatal(err)
+	}
+	mergedDir := filepath.Join(tmp, "merged")
+	if err := ExtractTar(merged, mergedDir); err != nil {
+		t.Fatal(err)
+	}
+	items, err := ReadDockerArchiveManifest(mergedDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*DockerArchiveManifestItem{
+		{Config: "c1.json", RepoTags: []string{"qlik/engine:1.0"}, Layers: []string{"base.tar", "l1.tar"}},
+		{Config: "c2.json", RepoTags: []string{"qlik/edge-auth:2.0"}, Layers: []string{"base.tar", "l2.tar"}},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("expected items %v, got %v", want, items)
+	}
+	for _, name := range []string{"c1.json", "c2.json", "base.tar", "l1.tar", "l2.tar", "repositories"} {
+		if _, err := os.Stat(filepath.Join(mergedDir, name)); err != nil {
+			t.Errorf("expected %s in the merged archive: %v", name, err)
+		}
+	}
+
+	single := filepath.Join(tmp, "single.tar")
+	if err := WriteDockerArchiveImage(mergedDir, items[1], single); err != nil {
+		t.Fatal(err)
+	}
+	singleDir := filepath.Join(tmp, "single")
+	if err := ExtractTar(single, singleDir); err != nil {
+		t.Fatal(err)
+	}
+	if singleItems, err := ReadDockerArchiveManifest(singleDir); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(singleItems, want[1:]) {
+		t.Fatalf("expected items %v, got %v", want[1:], singleItems)
+	}
+	if _, err := os.Stat(filepath.Join(singleDir, "l1.tar")); !os.IsNotExist(err) {
+		t.Error("expected only the files of the image in its archive")
+	}
+}
+
+func TestWriteDockerArchiveImage_outside(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	item := &DockerArchiveManifestItem{Config: "../c.json", RepoTags: []string{"qlik/engine:1.0"}}
+	if err := WriteDockerArchiveImage(tmp, item, filepath.Join(tmp, "single.tar")); err == nil {
+		t.Fatal("expected an error for a file outside of the archive")
+	}
+}
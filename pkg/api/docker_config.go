@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	dockerConfigDirVar   = "DOCKER_CONFIG"
+	dockerConfigFileName = "config.json"
+	// dockerHubServer is the server docker login records the credentials of docker hub for
+	dockerHubServer = "https://index.docker.io/v1/"
+	// credentialHelperNotFound is what docker credential helpers print for a server they have no credentials of
+	credentialHelperNotFound = "credentials not found"
+	// pullSecretFromDockerConfigFileName marks a context whose image pull secret is generated from the docker credentials
+	pullSecretFromDockerConfigFileName = "pull-secret-from-docker-config"
+)
+
+// dockerConfigFile is the part of the config.json of docker with credentials
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// GetDockerConfigFile returns the config.json of docker in $DOCKER_CONFIG, in ~/.docker otherwise
+func GetDockerConfigFile() (string, error) {
+	if dir := os.Getenv(dockerConfigDirVar); dir != "" {
+		return filepath.Join(dir, dockerConfigFileName), nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker", dockerConfigFileName), nil
+}
+
+// IsPullSecretFromDockerConfig returns whether the image pull secret of the current context is generated from
+// the credentials docker has for the image registry each time it is applied
+func (qc *QliksenseConfig) IsPullSecretFromDockerConfig() (bool, error) {
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(filepath.Join(contextDir, pullSecretFromDockerConfigFileName)); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetPullSecretFromDockerConfig sets whether the image pull secret of the current context is generated from
+// the credentials docker has for the image registry, nothing of them is stored
+func (qc *QliksenseConfig) SetPullSecretFromDockerConfig(fromDockerConfig bool) error {
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return err
+	}
+	markerFile := filepath.Join(contextDir, pullSecretFromDockerConfigFileName)
+	if !fromDockerConfig {
+		if err := os.Remove(markerFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	} else if err := os.MkdirAll(contextDir, os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(markerFile, nil, 0644)
+}
+
+// GetDockerConfigJsonSecretFromDockerConfig returns the credentials docker has for the registry, as docker login
+// recorded them: from the credential helper of the registry, the auths of the config.json or its credentials store.
+// It returns nil if docker has none.
+func GetDockerConfigJsonSecretFromDockerConfig(registry string) (*DockerConfigJsonSecret, error) {
+	configFile, err := GetDockerConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	config := &dockerConfigFile{}
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", configFile, err)
+	}
+
+	host := dockerConfigHost(registry)
+	for server, helper := range config.CredHelpers {
+		if dockerConfigHost(server) == host {
+			return getCredentialHelperSecret(helper, server, registry)
+		}
+	}
+	for server, auth := range config.Auths {
+		if dockerConfigHost(server) != host {
+			continue
+		}
+		username, password := auth.Username, auth.Password
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth of %s in %s: %w", server, configFile, err)
+			}
+			usernamePassword := strings.SplitN(string(decoded), ":", 2)
+			if len(usernamePassword) != 2 {
+				return nil, fmt.Errorf("invalid auth of %s in %s", server, configFile)
+			}
+			username, password = usernamePassword[0], usernamePassword[1]
+		}
+		if username != "" {
+			return &DockerConfigJsonSecret{Uri: registry, Username: username, Password: password}, nil
+		}
+		// the credentials of the server are in the credentials store
+		return getCredentialHelperSecret(config.CredsStore, server, registry)
+	}
+	if config.CredsStore != "" {
+		server := host
+		if host == "docker.io" {
+			server = dockerHubServer
+		}
+		return getCredentialHelperSecret(config.CredsStore, server, registry)
+	}
+	return nil, nil
+}
+
+// getCredentialHelperSecret runs docker-credential-<helper> for the credentials of server, nil if it has none
+func getCredentialHelperSecret(helper, server, registry string) (*DockerConfigJsonSecret, error) {
+	if helper == "" {
+		return nil, nil
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		output := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(output, credentialHelperNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("docker-credential-%s: %v: %s", helper, err, output)
+	}
+	credentials := &struct {
+		Username string
+		Secret   string
+	}{}
+	if err := json.Unmarshal(stdout.Bytes(), credentials); err != nil {
+		return nil, fmt.Errorf("invalid output of docker-credential-%s: %w", helper, err)
+	} else if credentials.Username == "" && credentials.Secret == "" {
+		return nil, nil
+	}
+	return &DockerConfigJsonSecret{Uri: registry, Username: credentials.Username, Password: credentials.Secret}, nil
+}
+
+// dockerConfigHost returns the host of a server of the docker config, docker.io for docker hub
+func dockerConfigHost(server string) string {
+	switch host := RegistryHost(server); host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	default:
+		return host
+	}
+}
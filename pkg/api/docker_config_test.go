@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGetDockerConfigJsonSecretFromDockerConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test credential helper is a shell script")
+	}
+	tmp, err := ioutil.TempDir("", "docker-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	// the helper has credentials of helper.example.com only, as a real helper would answer
+	helper := `#!/bin/sh
+read server
+if [ "$server" = "helper.example.com" ] || [ "$server" = "store.example.com" ]; then
+  echo "{\"ServerURL\":\"$server\",\"Username\":\"AWS\",\"Secret\":\"token-$server\"}"
+else
+  echo "credentials not found in native keychain"
+  exit 1
+fi
+`
+	if err := ioutil.WriteFile(filepath.Join(tmp, "docker-credential-test"), []byte(helper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "%s"},
+    "registry.example.com:5000": {"username": "foo", "password": "bar"},
+    "store.example.com": {}
+  },
+  "credsStore": "test",
+  "credHelpers": {"helper.example.com": "test"}
+}`, base64.StdEncoding.EncodeToString([]byte("hub:secret")))
+	if err := ioutil.WriteFile(filepath.Join(tmp, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+	defer os.Unsetenv(dockerConfigDirVar)
+	os.Setenv(dockerConfigDirVar, tmp)
+
+	tests := []struct {
+		registry string
+		username string
+		password string
+	}{
+		{registry: "docker.io", username: "hub", password: "secret"},
+		{registry: "https://registry.example.com:5000/qlik", username: "foo", password: "bar"},
+		{registry: "helper.example.com", username: "AWS", password: "token-helper.example.com"},
+		{registry: "store.example.com", username: "AWS", password: "token-store.example.com"},
+		{registry: "unknown.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.registry, func(t *testing.T) {
+			secret, err := GetDockerConfigJsonSecretFromDockerConfig(tt.registry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if tt.username == "" {
+				if secret != nil {
+					t.Fatalf("expected no credentials, got %v", secret)
+				}
+				return
+			} else if secret == nil {
+				t.Fatal("expected credentials")
+			}
+			if secret.Uri != tt.registry || secret.Username != tt.username || secret.Password != tt.password {
+				t.Errorf("expected %s:%s for %s, got %v", tt.username, tt.password, tt.registry, secret)
+			}
+		})
+	}
+
+	os.Setenv(dockerConfigDirVar, filepath.Join(tmp, "missing"))
+	if secret, err := GetDockerConfigJsonSecretFromDockerConfig("docker.io"); err != nil || secret != nil {
+		t.Errorf("expected no credentials without a docker config, got %v, %v", secret, err)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+const imageDigestsFileName = "image-digests.lock.yaml"
+
+// ImageDigests is the lock file of the images the manifests of a context are pinned to: the digest of the
+// manifest of every image, by the image as the manifests reference it
+type ImageDigests map[string]string
+
+// GetImageDigests returns the image digests the manifests of the current context were last pinned to,
+// empty if they never were
+func (qc *QliksenseConfig) GetImageDigests() (ImageDigests, error) {
+	digests := ImageDigests{}
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(filepath.Join(contextDir, imageDigestsFileName))
+	if os.IsNotExist(err) {
+		return digests, nil
+	} else if err != nil {
+		return nil, err
+	} else if err := yaml.Unmarshal(content, &digests); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", imageDigestsFileName, err)
+	}
+	return digests, nil
+}
+
+// WriteImageDigests writes the lock file of the image digests of the current context
+func (qc *QliksenseConfig) WriteImageDigests(digests ImageDigests) error {
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(digests)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(contextDir, os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(contextDir, imageDigestsFileName), content, 0644)
+}
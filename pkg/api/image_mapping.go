@@ -0,0 +1,181 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/docker/reference"
+	"gopkg.in/yaml.v2"
+)
+
+const imageRegistryMappingFileName = "image-registry-mapping.yaml"
+
+// strategies of mapping the repository of an image to a repository of the image registry
+const (
+	// ImageMappingFlatten keeps the last segment of the repository: qlik/engine becomes <registry>/engine
+	ImageMappingFlatten = "flatten"
+	// ImageMappingPreserve keeps the path of the repository: qlik/engine becomes <registry>/qlik/engine
+	ImageMappingPreserve = "preserve"
+	// ImageMappingPrefix puts the last segment under a prefix: qlik/engine becomes <registry>/<prefix>/engine
+	ImageMappingPrefix = "prefix"
+)
+
+// ImageRegistryMapping is how the repositories of images are mapped to repositories of the image registry,
+// for push, the operator and the generated manifests alike
+type ImageRegistryMapping struct {
+	Strategy string `yaml:"strategy,omitempty"`
+	Prefix   string `yaml:"prefix,omitempty"`
+	// Rules map repositories before the strategy applies
+	Rules []ImageMappingRule `yaml:"rules,omitempty"`
+}
+
+// ImageMappingRule maps the repository Source, i.e. docker.io/bitnami/mongodb, to the path Target of the image registry
+type ImageMappingRule struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+}
+
+// IsFlatten returns true if every repository is flattened, as it is without a mapping
+func (m *ImageRegistryMapping) IsFlatten() bool {
+	return m == nil || ((m.Strategy == "" || m.Strategy == ImageMappingFlatten) && len(m.Rules) == 0)
+}
+
+// Validate checks the strategy and the rules
+func (m *ImageRegistryMapping) Validate() error {
+	switch m.Strategy {
+	case "", ImageMappingFlatten, ImageMappingPreserve:
+		if m.Prefix != "" {
+			return fmt.Errorf("a prefix is only supported by the %s strategy", ImageMappingPrefix)
+		}
+	case ImageMappingPrefix:
+		if strings.Trim(m.Prefix, "/") == "" {
+			return fmt.Errorf("the %s strategy requires a prefix", ImageMappingPrefix)
+		}
+	default:
+		return fmt.Errorf("unsupported strategy %s, use %s, %s or %s", m.Strategy, ImageMappingFlatten, ImageMappingPreserve, ImageMappingPrefix)
+	}
+	for _, rule := range m.Rules {
+		if rule.Source == "" || strings.Trim(rule.Target, "/") == "" {
+			return fmt.Errorf("invalid rule %s=%s, a rule needs a source and a target", rule.Source, rule.Target)
+		}
+	}
+	return nil
+}
+
+// TargetImage returns the reference of the image in the registry, with the tag or digest of image
+func (m *ImageRegistryMapping) TargetImage(image, registry string) string {
+	if registry == "" {
+		return image
+	}
+	repository, suffix := SplitImageReference(image)
+	return m.TargetRepository(repository, registry) + suffix
+}
+
+// TargetRepository returns the repository of the registry the images of repository are mapped to
+func (m *ImageRegistryMapping) TargetRepository(repository, registry string) string {
+	if registry == "" {
+		return repository
+	}
+	// the scheme of a registry given as a URL is not part of a reference
+	if i := strings.Index(registry, "://"); i >= 0 {
+		registry = registry[i+3:]
+	}
+	registry = strings.TrimSuffix(registry, "/")
+	if m != nil {
+		for _, rule := range m.Rules {
+			if normalizeRepository(rule.Source) == normalizeRepository(repository) {
+				return path.Join(registry, strings.Trim(rule.Target, "/"))
+			}
+		}
+	}
+	name := path.Base(repository)
+	if m == nil {
+		return path.Join(registry, name)
+	}
+	switch m.Strategy {
+	case ImageMappingPreserve:
+		return path.Join(registry, repositoryPath(repository))
+	case ImageMappingPrefix:
+		return path.Join(registry, strings.Trim(m.Prefix, "/"), name)
+	}
+	return path.Join(registry, name)
+}
+
+// SplitImageReference splits an image into its repository and its ":tag" or "@digest", empty if it has neither
+func SplitImageReference(image string) (repository, suffix string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], image[i:]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i:]
+	}
+	return image, ""
+}
+
+// repositoryPath returns the path of the repository without its registry, library/ for the official docker images
+func repositoryPath(repository string) string {
+	named, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return repository
+	}
+	return reference.Path(named)
+}
+
+// normalizeRepository returns the repository with its registry, docker.io if it has none
+func normalizeRepository(repository string) string {
+	named, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return repository
+	}
+	return named.Name()
+}
+
+// GetImageRegistryMapping returns the mapping of the current context, nil if it has none
+func (qc *QliksenseConfig) GetImageRegistryMapping() (*ImageRegistryMapping, error) {
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(filepath.Join(contextDir, imageRegistryMappingFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	mapping := &ImageRegistryMapping{}
+	if err := yaml.Unmarshal(content, mapping); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", imageRegistryMappingFileName, err)
+	} else if err := mapping.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", imageRegistryMappingFileName, err)
+	}
+	return mapping, nil
+}
+
+// SetImageRegistryMapping sets the mapping of the current context, a nil mapping removes it
+func (qc *QliksenseConfig) SetImageRegistryMapping(mapping *ImageRegistryMapping) error {
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return err
+	}
+	mappingFile := filepath.Join(contextDir, imageRegistryMappingFileName)
+	if mapping == nil {
+		if err := os.Remove(mappingFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := mapping.Validate(); err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(mapping)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(contextDir, os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(mappingFile, content, 0644)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestImageRegistryMapping_TargetImage(t *testing.T) {
+	rules := []ImageMappingRule{{Source: "bitnami/mongodb", Target: "/third-party/mongodb/"}}
+	tests := []struct {
+		name     string
+		mapping  *ImageRegistryMapping
+		image    string
+		registry string
+		want     string
+	}{
+		{name: "no registry", mapping: &ImageRegistryMapping{Strategy: ImageMappingPreserve}, image: "qlik/engine:1.0", want: "qlik/engine:1.0"},
+		{name: "no mapping", image: "qlik/engine:1.0", registry: "registry.example.com", want: "registry.example.com/engine:1.0"},
+		{name: "flatten", mapping: &ImageRegistryMapping{Strategy: ImageMappingFlatten}, image: "docker.io/qlik/engine:1.0",
+			registry: "registry.example.com/qlik", want: "registry.example.com/qlik/engine:1.0"},
+		{name: "preserve", mapping: &ImageRegistryMapping{Strategy: ImageMappingPreserve}, image: "qlik-docker-oss.bintray.io/qlik/engine@sha256:abc",
+			registry: "registry.example.com:5000", want: "registry.example.com:5000/qlik/engine@sha256:abc"},
+		{name: "preserve official image", mapping: &ImageRegistryMapping{Strategy: ImageMappingPreserve}, image: "docker.io/nginx",
+			registry: "https://registry.example.com/", want: "registry.example.com/library/nginx"},
+		{name: "prefix", mapping: &ImageRegistryMapping{Strategy: ImageMappingPrefix, Prefix: "/qlik/"}, image: "localhost:5000/qlik/engine:1.0",
+			registry: "registry.example.com", want: "registry.example.com/qlik/engine:1.0"},
+		{name: "rule", mapping: &ImageRegistryMapping{Strategy: ImageMappingPreserve, Rules: rules}, image: "docker.io/bitnami/mongodb:4.0",
+			registry: "registry.example.com", want: "registry.example.com/third-party/mongodb:4.0"},
+		{name: "rule of another registry", mapping: &ImageRegistryMapping{Rules: rules}, image: "quay.io/bitnami/mongodb:4.0",
+			registry: "registry.example.com", want: "registry.example.com/mongodb:4.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.TargetImage(tt.image, tt.registry); got != tt.want {
+				t.Errorf("TargetImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageRegistryMapping_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping *ImageRegistryMapping
+		wantErr bool
+	}{
+		{name: "default", mapping: &ImageRegistryMapping{}},
+		{name: "prefix", mapping: &ImageRegistryMapping{Strategy: ImageMappingPrefix, Prefix: "qlik"}},
+		{name: "prefix without prefix", mapping: &ImageRegistryMapping{Strategy: ImageMappingPrefix}, wantErr: true},
+		{name: "prefix of preserve", mapping: &ImageRegistryMapping{Strategy: ImageMappingPreserve, Prefix: "qlik"}, wantErr: true},
+		{name: "unknown strategy", mapping: &ImageRegistryMapping{Strategy: "nested"}, wantErr: true},
+		{name: "rule without target", mapping: &ImageRegistryMapping{Rules: []ImageMappingRule{{Source: "qlik/engine"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mapping.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitImageReference(t *testing.T) {
+	tests := []struct {
+		image      string
+		repository string
+		suffix     string
+	}{
+		{image: "qlik/engine:1.0", repository: "qlik/engine", suffix: ":1.0"},
+		{image: "registry.example.com:5000/qlik/engine", repository: "registry.example.com:5000/qlik/engine"},
+		{image: "qlik/engine@sha256:abc", repository: "qlik/engine", suffix: "@sha256:abc"},
+	}
+	for _, tt := range tests {
+		if repository, suffix := SplitImageReference(tt.image); repository != tt.repository || suffix != tt.suffix {
+			t.Errorf("SplitImageReference(%q) = %q, %q, want %q, %q", tt.image, repository, suffix, tt.repository, tt.suffix)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const imageMirrorsFileName = "image-registry-mirrors.yaml"
+
+// ImageMirrorRule pulls the images of the registry or repository Source, i.e. docker.io or docker.io/qlik,
+// through the pull-through mirror Mirror, i.e. mirror.example.com/dockerhub
+type ImageMirrorRule struct {
+	Source string `yaml:"source"`
+	Mirror string `yaml:"mirror"`
+}
+
+// ImageMirrors are the mirror rules images are pulled from their source registries with
+type ImageMirrors []ImageMirrorRule
+
+// Validate checks that every rule has a source and a mirror
+func (m ImageMirrors) Validate() error {
+	for _, rule := range m {
+		if trimRegistryScheme(rule.Source) == "" || trimRegistryScheme(rule.Mirror) == "" {
+			return fmt.Errorf("invalid mirror %s=%s, a mirror needs a source and a mirror", rule.Source, rule.Mirror)
+		}
+	}
+	return nil
+}
+
+// MirrorImage returns the reference the image is pulled from: the image under the mirror of the longest source
+// its repository is in, the image itself if no source matches. Official docker images are in docker.io/library.
+func (m ImageMirrors) MirrorImage(image string) string {
+	repository, suffix := SplitImageReference(image)
+	repository = normalizeRepository(repository)
+	source, mirror := "", ""
+	for _, rule := range m {
+		ruleSource := trimRegistryScheme(rule.Source)
+		if (repository == ruleSource || strings.HasPrefix(repository, ruleSource+"/")) && len(ruleSource) > len(source) {
+			source, mirror = ruleSource, trimRegistryScheme(rule.Mirror)
+		}
+	}
+	if source == "" {
+		return image
+	}
+	return path.Join(mirror, strings.TrimPrefix(repository, source)) + suffix
+}
+
+// trimRegistryScheme returns the registry or repository without scheme and slashes around it
+func trimRegistryScheme(registry string) string {
+	if i := strings.Index(registry, "://"); i >= 0 {
+		registry = registry[i+3:]
+	}
+	return strings.Trim(registry, "/")
+}
+
+// GetImageMirrors returns the mirror rules of the current context, nil if it has none
+func (qc *QliksenseConfig) GetImageMirrors() (ImageMirrors, error) {
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(filepath.Join(contextDir, imageMirrorsFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var mirrors ImageMirrors
+	if err := yaml.Unmarshal(content, &mirrors); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", imageMirrorsFileName, err)
+	} else if err := mirrors.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", imageMirrorsFileName, err)
+	}
+	return mirrors, nil
+}
+
+// SetImageMirrors sets the mirror rules of the current context, no rules remove them
+func (qc *QliksenseConfig) SetImageMirrors(mirrors ImageMirrors) error {
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return err
+	}
+	mirrorsFile := filepath.Join(contextDir, imageMirrorsFileName)
+	if len(mirrors) == 0 {
+		if err := os.Remove(mirrorsFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := mirrors.Validate(); err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(mirrors)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(contextDir, os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(mirrorsFile, content, 0644)
+}
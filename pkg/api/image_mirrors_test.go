@@ -0,0 +1,43 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestImageMirrors_MirrorImage(t *testing.T) {
+	mirrors := ImageMirrors{
+		{Source: "docker.io", Mirror: "mirror.example.com/dockerhub"},
+		{Source: "docker.io/qlik", Mirror: "https://mirror.example.com/qlik/"},
+		{Source: "qlik-docker-oss.bintray.io", Mirror: "mirror.example.com:5000/bintray"},
+	}
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "nginx:1.17", want: "mirror.example.com/dockerhub/library/nginx:1.17"},
+		{image: "docker.io/bitnami/mongodb:4.0", want: "mirror.example.com/dockerhub/bitnami/mongodb:4.0"},
+		{image: "qlik/engine@sha256:abc", want: "mirror.example.com/qlik/engine@sha256:abc"},
+		{image: "qlik-docker-oss.bintray.io/qlik/preflight", want: "mirror.example.com:5000/bintray/qlik/preflight"},
+		{image: "quay.io/qlik/engine:1.0", want: "quay.io/qlik/engine:1.0"},
+		{image: "docker.io/qlikx/engine:1.0", want: "mirror.example.com/dockerhub/qlikx/engine:1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := mirrors.MirrorImage(tt.image); got != tt.want {
+				t.Errorf("MirrorImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := ImageMirrors(nil).MirrorImage("nginx"); got != "nginx" {
+		t.Errorf("expected no rules to keep the image, got %v", got)
+	}
+}
+
+func TestImageMirrors_Validate(t *testing.T) {
+	if err := (ImageMirrors{{Source: "docker.io", Mirror: "mirror.example.com"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (ImageMirrors{{Source: "docker.io", Mirror: "https://"}}).Validate(); err == nil {
+		t.Error("expected an error for a rule without mirror")
+	}
+}
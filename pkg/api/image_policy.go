@@ -0,0 +1,50 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// imagePolicyFileName is the containers-policy.json of a context, or of every context in the qliksense home
+const imagePolicyFileName = "image-policy.json"
+
+// GetImagePolicyFile returns the signature policy images are pulled with: the one of the current context,
+// the global one otherwise. It returns an empty path if neither is set.
+func (qc *QliksenseConfig) GetImagePolicyFile() (string, error) {
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return "", err
+	}
+	for _, policyFile := range []string{filepath.Join(contextDir, imagePolicyFileName), filepath.Join(qc.QliksenseHomePath, imagePolicyFileName)} {
+		if _, err := os.Stat(policyFile); err == nil {
+			return policyFile, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// SetImagePolicy writes the signature policy of the current context, or the global one, an empty policy removes it
+func (qc *QliksenseConfig) SetImagePolicy(policy []byte, global bool) error {
+	dir := qc.QliksenseHomePath
+	if !global {
+		contextDir, err := qc.GetCurrentContextDir()
+		if err != nil {
+			return err
+		}
+		dir = contextDir
+	}
+	policyFile := filepath.Join(dir, imagePolicyFileName)
+	if len(policy) == 0 {
+		if err := os.Remove(policyFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(policyFile, policy, 0644)
+}
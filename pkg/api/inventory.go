@@ -0,0 +1,247 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/yaml"
+)
+
+// components of a context the inventory records objects for
+const (
+	InventoryCRDs           = "crds"
+	InventoryOperator       = "operator"
+	InventoryPullSecret     = "pull-secret"
+	InventoryPatchResources = "patch-resources"
+	InventoryManifests      = "manifests"
+	InventoryCR             = "cr"
+
+	inventoryFileName = "inventory.yaml"
+)
+
+const PersistentVolumeClaimKind = "PersistentVolumeClaim"
+
+var persistentVolumeClaimsResource = schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}
+
+// InventoryObject identifies an object that was applied into the cluster for a context
+type InventoryObject struct {
+	Component  string `json:"component" yaml:"component"`
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string `json:"kind" yaml:"kind"`
+	Namespace  string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name       string `json:"name" yaml:"name"`
+}
+
+func (o *InventoryObject) String() string {
+	if o.Namespace == "" {
+		return fmt.Sprintf("%s/%s", o.Kind, o.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", o.Kind, o.Namespace, o.Name)
+}
+
+func (o *InventoryObject) toUnstructured() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(o.APIVersion)
+	obj.SetKind(o.Kind)
+	obj.SetNamespace(o.Namespace)
+	obj.SetName(o.Name)
+	return obj
+}
+
+// Inventory holds every object applied into the cluster for a context, so that uninstall can remove them
+type Inventory struct {
+	Objects []*InventoryObject `json:"objects" yaml:"objects"`
+}
+
+// Add adds the objects that are not in the inventory yet
+func (inv *Inventory) Add(objects ...*InventoryObject) {
+	known := map[string]bool{}
+	for _, o := range inv.Objects {
+		known[o.Component+"/"+o.APIVersion+"/"+o.String()] = true
+	}
+	for _, o := range objects {
+		if key := o.Component + "/" + o.APIVersion + "/" + o.String(); !known[key] {
+			known[key] = true
+			inv.Objects = append(inv.Objects, o)
+		}
+	}
+}
+
+// ObjectsOf returns the objects of the component
+func (inv *Inventory) ObjectsOf(component string) []*InventoryObject {
+	var objects []*InventoryObject
+	for _, o := range inv.Objects {
+		if o.Component == component {
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
+
+// GetContextInventoryFile returns ~/.qliksense/contexts/<contx-name>/inventory.yaml
+func (qc *QliksenseConfig) GetContextInventoryFile(contextName string) string {
+	return filepath.Join(qc.GetContextPath(contextName), inventoryFileName)
+}
+
+// GetInventory reads the inventory of the context, it is empty if nothing was recorded
+func (qc *QliksenseConfig) GetInventory(contextName string) (*Inventory, error) {
+	inv := &Inventory{}
+	data, err := ioutil.ReadFile(qc.GetContextInventoryFile(contextName))
+	if os.IsNotExist(err) {
+		return inv, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// WriteInventory stores the inventory of the context
+func (qc *QliksenseConfig) WriteInventory(contextName string, inv *Inventory) error {
+	data, err := yaml.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(qc.GetContextInventoryFile(contextName), data, revisionFilePermission)
+}
+
+// AddToInventory adds the objects to the inventory of the context
+func (qc *QliksenseConfig) AddToInventory(contextName string, objects []*InventoryObject) error {
+	inv, err := qc.GetInventory(contextName)
+	if err != nil {
+		return err
+	}
+	inv.Add(objects...)
+	return qc.WriteInventory(contextName, inv)
+}
+
+// DeleteInventory removes the inventory of the context
+func (qc *QliksenseConfig) DeleteInventory(contextName string) error {
+	if err := os.Remove(qc.GetContextInventoryFile(contextName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// InventoryObjects returns the objects of the manifests for the inventory. Objects of namespaced kinds
+// without a namespace get the namespace they are applied into.
+func (e *ApplyEngine) InventoryObjects(component, manifests, namespace string) ([]*InventoryObject, error) {
+	objs, err := decodeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*InventoryObject
+	for _, obj := range objs {
+		if _, _, err := e.resourceFor(obj, namespace, false); err != nil && !meta.IsNoMatchError(err) {
+			return nil, err
+		}
+		objects = append(objects, &InventoryObject{
+			Component:  component,
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+		})
+	}
+	return objects, nil
+}
+
+// DeleteObjects deletes the objects in reverse order, objects that do not exist are skipped
+func (e *ApplyEngine) DeleteObjects(objects []*InventoryObject) error {
+	var errs ObjectErrors
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i].toUnstructured()
+		if err := e.deleteObject(obj, obj.GetNamespace()); err != nil {
+			errs = append(errs, e.objectError(deleteOperation, obj, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// WaitForDeletion waits until none of the objects exists anymore
+func (e *ApplyEngine) WaitForDeletion(objects []*InventoryObject, timeout time.Duration) error {
+	return wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		for _, o := range objects {
+			obj := o.toUnstructured()
+			ri, _, err := e.resourceFor(obj, obj.GetNamespace(), false)
+			if meta.IsNoMatchError(err) {
+				continue
+			} else if err != nil {
+				return false, err
+			}
+			if _, err := ri.Get(obj.GetName(), metav1.GetOptions{}); err == nil {
+				return false, nil
+			} else if !k8serrors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// PersistentVolumeClaims returns the claims of the objects: claims recorded in the inventory and
+// the claims created from the volume claim templates of the stateful sets that still exist
+func (e *ApplyEngine) PersistentVolumeClaims(objects []*InventoryObject) ([]*InventoryObject, error) {
+	var claims []*InventoryObject
+	for _, o := range objects {
+		if o.Kind == PersistentVolumeClaimKind {
+			claims = append(claims, o)
+		}
+	}
+	claimsResource := e.client.Resource(persistentVolumeClaimsResource)
+	for _, o := range objects {
+		if o.Kind != StatefulSetKind {
+			continue
+		}
+		obj := o.toUnstructured()
+		ri, _, err := e.resourceFor(obj, obj.GetNamespace(), false)
+		if err != nil {
+			return nil, err
+		}
+		statefulSet, err := ri.Get(obj.GetName(), metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		templates, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "volumeClaimTemplates")
+		if len(templates) == 0 {
+			continue
+		}
+		list, err := claimsResource.Namespace(statefulSet.GetNamespace()).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, template := range templates {
+			templateName, _, _ := unstructured.NestedString(template.(map[string]interface{}), "metadata", "name")
+			// claims of stateful sets are named <template>-<stateful set>-<ordinal>
+			pattern := regexp.MustCompile("^" + regexp.QuoteMeta(templateName+"-"+statefulSet.GetName()) + "-[0-9]+$")
+			for _, claim := range list.Items {
+				if pattern.MatchString(claim.GetName()) {
+					claims = append(claims, &InventoryObject{
+						APIVersion: "v1",
+						Kind:       PersistentVolumeClaimKind,
+						Namespace:  claim.GetNamespace(),
+						Name:       claim.GetName(),
+					})
+				}
+			}
+		}
+	}
+	return claims, nil
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAddToInventory(t *testing.T) {
+	td, dir := setup()
+	defer td()
+	createCRFile(dir)
+	qc := NewQConfig(dir)
+	qct, err := qc.SetCrLocation("contx1", filepath.Join("contexts", "contx1", "contx1.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inv, err := qct.GetInventory("contx1"); err != nil || len(inv.Objects) != 0 {
+		t.Fatalf("expected an empty inventory, got %v, %v", inv, err)
+	}
+	engine, _ := newTestApplyEngine()
+	objects, err := engine.InventoryObjects(InventoryManifests, testApplyManifests, "qliksense")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// adding the same objects twice records them once
+	for i := 0; i < 2; i++ {
+		if err := qct.AddToInventory("contx1", objects); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inv, err := qct.GetInventory("contx1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifests := inv.ObjectsOf(InventoryManifests)
+	if len(inv.Objects) != 3 || len(manifests) != 3 {
+		t.Fatalf("expected 3 objects, got %v", inv.Objects)
+	}
+	for i, expected := range []string{"ConfigMap/qliksense/engine-configs", "ClusterRole/engine-role", "ConfigMap/other/listed-configs"} {
+		if manifests[i].String() != expected {
+			t.Fatalf("expected %s, got %s", expected, manifests[i])
+		}
+	}
+
+	if err := qct.DeleteInventory("contx1"); err != nil {
+		t.Fatal(err)
+	}
+	if inv, err := qct.GetInventory("contx1"); err != nil || len(inv.Objects) != 0 {
+		t.Fatalf("expected an empty inventory after delete, got %v, %v", inv, err)
+	}
+}
+
+func TestApplyEngine_PersistentVolumeClaims(t *testing.T) {
+	engine, client := newTestApplyEngine()
+	mapper := engine.mapper.(*meta.DefaultRESTMapper)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: StatefulSetKind}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: PersistentVolumeClaimKind}, meta.RESTScopeNamespace)
+
+	statefulSet := newTestObject(StatefulSetKind, "mongodb", map[string]interface{}{"spec": map[string]interface{}{
+		"volumeClaimTemplates": []interface{}{
+			map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}},
+		},
+	}})
+	statefulSet.SetAPIVersion("apps/v1")
+	if _, err := client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}).
+		Namespace("qliksense").Create(statefulSet, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"data-mongodb-0", "data-mongodb-1", "data-mongodb-other-0", "logs"} {
+		if _, err := client.Resource(persistentVolumeClaimsResource).Namespace("qliksense").
+			Create(newTestObject(PersistentVolumeClaimKind, name, nil), metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	claims, err := engine.PersistentVolumeClaims([]*InventoryObject{
+		{APIVersion: "apps/v1", Kind: StatefulSetKind, Namespace: "qliksense", Name: "mongodb"},
+		{APIVersion: "apps/v1", Kind: StatefulSetKind, Namespace: "qliksense", Name: "already-gone"},
+		{APIVersion: "v1", Kind: PersistentVolumeClaimKind, Namespace: "qliksense", Name: "logs"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, claim := range claims {
+		names = append(names, claim.Name)
+	}
+	if len(names) != 3 || names[0] != "logs" || names[1] != "data-mongodb-0" || names[2] != "data-mongodb-1" {
+		t.Fatalf("unexpected claims: %v", names)
+	}
+
+	if err := engine.DeleteObjects(claims); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list, err := client.Resource(persistentVolumeClaimsResource).Namespace("qliksense").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].GetName() != "data-mongodb-other-0" {
+		t.Fatalf("unexpected claims after delete: %v", unstructuredNames(list.Items))
+	}
+}
+
+func unstructuredNames(items []unstructured.Unstructured) []string {
+	var names []string
+	for _, item := range items {
+		names = append(names, item.GetName())
+	}
+	return names
+}
@@ -0,0 +1,357 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	machine_yaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	applyFieldManager = "qliksense"
+	applyOperation    = "apply"
+	deleteOperation   = "delete"
+
+	// OwnerLabel is set on every object applied for a context, its value is the context name
+	OwnerLabel = "qliksense.qlik.com/context"
+	// RevisionAnnotation is set on every object applied for a context, its value is the revision number
+	RevisionAnnotation = "qliksense.qlik.com/revision"
+)
+
+// ObjectError is the failure of an operation on one object of the manifests
+type ObjectError struct {
+	Operation string
+	Kind      string
+	Namespace string
+	Name      string
+	Err       error
+}
+
+func (e *ObjectError) Error() string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + e.Name
+	}
+	return fmt.Sprintf("%s %s %s failed: %v", e.Operation, e.Kind, name, e.Err)
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// ObjectErrors holds the failures of all objects of the manifests an operation failed on
+type ObjectErrors []*ObjectError
+
+func (e ObjectErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, objErr := range e {
+		msgs = append(msgs, objErr.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ApplyEngine applies and deletes manifests through the dynamic client using server-side apply
+type ApplyEngine struct {
+	client           dynamic.Interface
+	mapper           meta.RESTMapper
+	defaultNamespace string
+	Verbose          bool
+	Out              io.Writer
+	// Labels and Annotations are added to every object that gets applied
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+type resettableRESTMapper interface {
+	meta.RESTMapper
+	Reset()
+}
+
+// NewApplyEngine creates an ApplyEngine for the client and mapper, namespaced objects without
+// a namespace go into defaultNamespace
+func NewApplyEngine(client dynamic.Interface, mapper meta.RESTMapper, defaultNamespace string) *ApplyEngine {
+	if defaultNamespace == "" {
+		defaultNamespace = metav1.NamespaceDefault
+	}
+	return &ApplyEngine{
+		client:           client,
+		mapper:           mapper,
+		defaultNamespace: defaultNamespace,
+		Verbose:          true,
+		Out:              os.Stdout,
+	}
+}
+
+// NewApplyEngineFromKubeConfig creates an ApplyEngine for the current kube context
+func NewApplyEngineFromKubeConfig() (*ApplyEngine, error) {
+	clientConfig := getKubeClientConfig()
+	restConfig, err := GetKubeRestConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, err
+	}
+	return NewApplyEngine(client, mapper, namespace), nil
+}
+
+// Apply server-side applies all objects of the manifests, objects without a namespace go into namespace
+// or into the default namespace of the engine if namespace is empty
+func (e *ApplyEngine) Apply(manifests, namespace string) error {
+	objs, err := decodeManifests(manifests)
+	if err != nil {
+		return err
+	}
+	var errs ObjectErrors
+	for _, obj := range objs {
+		if err := e.applyObject(obj, namespace); err != nil {
+			errs = append(errs, e.objectError(applyOperation, obj, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Delete deletes all objects of the manifests in reverse order, objects that do not exist are skipped
+func (e *ApplyEngine) Delete(manifests, namespace string) error {
+	objs, err := decodeManifests(manifests)
+	if err != nil {
+		return err
+	}
+	var errs ObjectErrors
+	for i := len(objs) - 1; i >= 0; i-- {
+		if err := e.deleteObject(objs[i], namespace); err != nil {
+			errs = append(errs, e.objectError(deleteOperation, objs[i], err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (e *ApplyEngine) applyObject(obj *unstructured.Unstructured, namespace string) error {
+	ri, mapping, err := e.resourceFor(obj, namespace, true)
+	if err != nil {
+		return err
+	}
+	e.stamp(obj)
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	if err != nil {
+		return err
+	}
+	force := true
+	if _, err := ri.Patch(obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: applyFieldManager,
+		Force:        &force,
+	}); err != nil {
+		return err
+	}
+	e.logObject(mapping, obj, "applied")
+	return nil
+}
+
+func (e *ApplyEngine) deleteObject(obj *unstructured.Unstructured, namespace string) error {
+	ri, mapping, err := e.resourceFor(obj, namespace, false)
+	if meta.IsNoMatchError(err) {
+		// the type is not known to the cluster, so there is nothing to delete
+		return nil
+	} else if err != nil {
+		return err
+	}
+	propagation := metav1.DeletePropagationBackground
+	if err := ri.Delete(obj.GetName(), &metav1.DeleteOptions{PropagationPolicy: &propagation}); k8serrors.IsNotFound(err) {
+		e.logObject(mapping, obj, "not found")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	e.logObject(mapping, obj, "deleted")
+	return nil
+}
+
+// stamp adds the labels and annotations of the engine to the object
+func (e *ApplyEngine) stamp(obj *unstructured.Unstructured) {
+	if len(e.Labels) > 0 {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for k, v := range e.Labels {
+			labels[k] = v
+		}
+		obj.SetLabels(labels)
+	}
+	if len(e.Annotations) > 0 {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for k, v := range e.Annotations {
+			annotations[k] = v
+		}
+		obj.SetAnnotations(annotations)
+	}
+}
+
+// resourceFor maps the object to its resource, for namespaced resources the namespace of the object is set
+func (e *ApplyEngine) resourceFor(obj *unstructured.Unstructured, namespace string, waitForType bool) (dynamic.ResourceInterface, *meta.RESTMapping, error) {
+	if obj.GetName() == "" {
+		return nil, nil, fmt.Errorf("object of kind %s has no name", obj.GetKind())
+	}
+	var mapping *meta.RESTMapping
+	var err error
+	gvk := obj.GroupVersionKind()
+	if waitForType {
+		mapping, err = e.waitForRESTMapping(gvk)
+	} else {
+		mapping, err = e.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return e.client.Resource(mapping.Resource), mapping, nil
+	}
+	if obj.GetNamespace() == "" {
+		if namespace == "" {
+			namespace = e.defaultNamespace
+		}
+		obj.SetNamespace(namespace)
+	}
+	return e.client.Resource(mapping.Resource).Namespace(obj.GetNamespace()), mapping, nil
+}
+
+// waitForRESTMapping resolves the kind, types created earlier in the same manifests (i.e. CRDs) may need
+// a few attempts until they are served by discovery
+func (e *ApplyEngine) waitForRESTMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	var mapping *meta.RESTMapping
+	err := retry.OnError(wait.Backoff{
+		Duration: 1 * time.Second,
+		Factor:   1.5,
+		Jitter:   0.1,
+		Steps:    5,
+	}, func(err error) bool {
+		if !meta.IsNoMatchError(err) {
+			return false
+		}
+		resettable, ok := e.mapper.(resettableRESTMapper)
+		if ok {
+			resettable.Reset()
+		}
+		return ok
+	}, func() error {
+		var err error
+		mapping, err = e.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		return err
+	})
+	return mapping, err
+}
+
+func (e *ApplyEngine) objectError(operation string, obj *unstructured.Unstructured, err error) *ObjectError {
+	return &ObjectError{
+		Operation: operation,
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Err:       err,
+	}
+}
+
+func (e *ApplyEngine) logObject(mapping *meta.RESTMapping, obj *unstructured.Unstructured, result string) {
+	if !e.Verbose {
+		return
+	}
+	resource := mapping.Resource.Resource
+	if mapping.Resource.Group != "" {
+		resource = resource + "." + mapping.Resource.Group
+	}
+	fmt.Fprintf(e.Out, "%s/%s %s\n", resource, obj.GetName(), result)
+}
+
+// decodeManifests decodes a multi-doc yaml into objects, empty documents are skipped and lists are expanded
+func decodeManifests(manifests string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := machine_yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for {
+		content := map[string]interface{}{}
+		if err := decoder.Decode(&content); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to decode manifests: %w", err)
+		}
+		if len(content) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: content}
+		if obj.IsList() {
+			if err := obj.EachListItem(func(item runtime.Object) error {
+				objs = append(objs, item.(*unstructured.Unstructured))
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// KubeApply server-side applies the manifests in the provided namespace,
+// if namespace="" then the namespace of the current kube context is used
+func KubeApply(manifests, namespace string) error {
+	return KubeApplyVerbose(manifests, namespace, true)
+}
+
+func KubeApplyVerbose(manifests, namespace string, verbose bool) error {
+	engine, err := NewApplyEngineFromKubeConfig()
+	if err != nil {
+		return err
+	}
+	engine.Verbose = verbose
+	return engine.Apply(manifests, namespace)
+}
+
+// KubeDelete delete resources in the provided namespace,
+// if namespace="" then the namespace of the current kube context is used
+func KubeDelete(manifests, namespace string) error {
+	return KubeDeleteVerbose(manifests, namespace, true)
+}
+
+func KubeDeleteVerbose(manifests, namespace string, verbose bool) error {
+	engine, err := NewApplyEngineFromKubeConfig()
+	if err != nil {
+		return err
+	}
+	engine.Verbose = verbose
+	return engine.Delete(manifests, namespace)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testApplyManifests = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: engine-configs
+data:
+  key: value
+---
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: engine-role
+rules: []
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: listed-configs
+    namespace: other
+`
+
+func newTestApplyEngine() (*ApplyEngine, *dynamicfake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	engine := NewApplyEngine(client, mapper, "")
+	engine.Out = ioutil.Discard
+	return engine, client
+}
+
+func Test_decodeManifests(t *testing.T) {
+	objs, err := decodeManifests(testApplyManifests)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"ConfigMap/engine-configs", "ClusterRole/engine-role", "ConfigMap/listed-configs"}
+	if len(objs) != len(expected) {
+		t.Fatalf("expected %d objects, got %d", len(expected), len(objs))
+	}
+	for i, obj := range objs {
+		if got := obj.GetKind() + "/" + obj.GetName(); got != expected[i] {
+			t.Fatalf("expected: %s, got: %s", expected[i], got)
+		}
+	}
+}
+
+func TestApplyEngine_Apply(t *testing.T) {
+	engine, client := newTestApplyEngine()
+	var patches []k8stesting.PatchActionImpl
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patches = append(patches, action.(k8stesting.PatchActionImpl))
+		return true, nil, nil
+	})
+
+	if err := engine.Apply(testApplyManifests, "qliksense"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []struct {
+		resource  string
+		namespace string
+		name      string
+	}{
+		{"configmaps", "qliksense", "engine-configs"},
+		{"clusterroles", "", "engine-role"},
+		{"configmaps", "other", "listed-configs"},
+	}
+	if len(patches) != len(expected) {
+		t.Fatalf("expected %d patches, got %d", len(expected), len(patches))
+	}
+	for i, patch := range patches {
+		if patch.GetPatchType() != types.ApplyPatchType {
+			t.Errorf("expected patch type %v, got %v", types.ApplyPatchType, patch.GetPatchType())
+		}
+		if patch.GetResource().Resource != expected[i].resource || patch.GetNamespace() != expected[i].namespace || patch.GetName() != expected[i].name {
+			t.Errorf("expected %v, got %s %s/%s", expected[i], patch.GetResource().Resource, patch.GetNamespace(), patch.GetName())
+		}
+	}
+}
+
+func TestApplyEngine_Apply_objectErrors(t *testing.T) {
+	engine, client := newTestApplyEngine()
+	client.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	client.PrependReactor("patch", "clusterroles", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+
+	manifests := testApplyManifests + `
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+`
+	err := engine.Apply(manifests, "")
+	var objErrs ObjectErrors
+	if !errors.As(err, &objErrs) {
+		t.Fatalf("expected ObjectErrors, got: %v", err)
+	}
+	if len(objErrs) != 3 {
+		t.Fatalf("expected 3 object errors, got: %v", objErrs)
+	}
+	if objErrs[0].Name != "engine-configs" || objErrs[0].Namespace != "default" || objErrs[0].Operation != applyOperation {
+		t.Errorf("unexpected first object error: %v", objErrs[0])
+	}
+	if objErrs[2].Kind != "Unknown" || !meta.IsNoMatchError(objErrs[2].Err) {
+		t.Errorf("expected no match error for the unknown kind, got: %v", objErrs[2])
+	}
+}
+
+func TestApplyEngine_Delete(t *testing.T) {
+	engine, client := newTestApplyEngine()
+	var deleted []string
+	client.PrependReactor("delete", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleted = append(deleted, action.(k8stesting.DeleteActionImpl).GetName())
+		return true, nil, nil
+	})
+
+	manifests := testApplyManifests + `
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+`
+	if err := engine.Delete(manifests, "qliksense"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"listed-configs", "engine-role", "engine-configs"}
+	if len(deleted) != len(expected) {
+		t.Fatalf("expected %v to be deleted, got %v", expected, deleted)
+	}
+	for i := range expected {
+		if deleted[i] != expected[i] {
+			t.Fatalf("expected %v to be deleted, got %v", expected, deleted)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// kube config file, kube context and namespace selected with --kubeconfig and --kube-context or by the context binding
+var (
+	kubeConfigPath string
+	kubeContext    string
+	kubeNamespace  string
+)
+
+// SetKubeConfig sets the kube config file, the kube context and the namespace every kubernetes client uses.
+// Empty values keep the defaults: the files of KUBECONFIG, or ~/.kube/config, their current context and its namespace.
+func SetKubeConfig(path, context, namespace string) {
+	kubeConfigPath = path
+	kubeContext = context
+	kubeNamespace = namespace
+}
+
+// getKubeClientConfig loads the kube config the way kubectl does, honoring KUBECONFIG, --kubeconfig and --kube-context
+func getKubeClientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeConfigPath
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{
+		CurrentContext: kubeContext,
+		Context:        clientcmdapi.Context{Namespace: kubeNamespace},
+	})
+}
+
+// GetKubeRestConfig returns the rest config of the selected kube context
+func GetKubeRestConfig() (*rest.Config, error) {
+	restConfig, err := getKubeClientConfig().ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig: %w", err)
+	}
+	return restConfig, nil
+}
+
+// GetKubeNamespace returns the selected namespace or the namespace of the selected kube context, empty if it has none
+func GetKubeNamespace() (string, error) {
+	if kubeNamespace != "" {
+		return kubeNamespace, nil
+	}
+	rawConfig, err := getKubeClientConfig().RawConfig()
+	if err != nil {
+		return "", fmt.Errorf("unable to load kubeconfig: %w", err)
+	}
+	if kubeContext, ok := rawConfig.Contexts[currentKubeContext(rawConfig)]; ok {
+		return kubeContext.Namespace, nil
+	}
+	return "", nil
+}
+
+// GetKubeContextName returns the name of the selected kube context, an error if it does not exist
+func GetKubeContextName() (string, error) {
+	rawConfig, err := getKubeClientConfig().RawConfig()
+	if err != nil {
+		return "", err
+	}
+	name := currentKubeContext(rawConfig)
+	if _, ok := rawConfig.Contexts[name]; !ok {
+		return "", fmt.Errorf("kube context %q not found", name)
+	}
+	return name, nil
+}
+
+func currentKubeContext(rawConfig clientcmdapi.Config) string {
+	if kubeContext != "" {
+		return kubeContext
+	}
+	return rawConfig.CurrentContext
+}
+
+// LoadKubeConfigContents returns the merged kube config as a single file, with the selected
+// kube context as current context and the referenced certificate and key files embedded
+func LoadKubeConfigContents() ([]byte, error) {
+	rawConfig, err := getKubeClientConfig().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	rawConfig.CurrentContext = currentKubeContext(rawConfig)
+	if ctx, ok := rawConfig.Contexts[rawConfig.CurrentContext]; !ok {
+		return nil, fmt.Errorf("kube context %q not found", rawConfig.CurrentContext)
+	} else if kubeNamespace != "" {
+		ctx.Namespace = kubeNamespace
+	}
+	if err := clientcmdapi.FlattenConfig(&rawConfig); err != nil {
+		return nil, err
+	}
+	config := &clientcmdapiv1.Config{}
+	if err := clientcmdlatest.Scheme.Convert(&rawConfig, config, nil); err != nil {
+		return nil, err
+	}
+	config.APIVersion = clientcmdlatest.Version
+	config.Kind = "Config"
+	return yaml.Marshal(config)
+}
+
+// WriteKubeConfigToTempFile writes the kube config of LoadKubeConfigContents into a temporary file,
+// for libraries that take the path of a kube config file. The returned function removes the file.
+func WriteKubeConfigToTempFile() (string, func(), error) {
+	contents, err := LoadKubeConfigContents()
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := ioutil.TempFile("", "kubeconfig-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		os.Remove(f.Name())
+	}
+	// TempFile creates the file with 0600
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, err
+	} else if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return f.Name(), cleanup, nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const testKubeConfigTemplate = `apiVersion: v1
+kind: Config
+clusters:
+- name: CONTEXT
+  cluster:
+    server: https://CONTEXT.example.com
+users:
+- name: CONTEXT
+  user:
+    client-certificate: CONTEXT.crt
+    token: secret
+contexts:
+- name: CONTEXT
+  context:
+    cluster: CONTEXT
+    user: CONTEXT
+    namespace: CONTEXT-ns
+current-context: CONTEXT
+`
+
+func writeTestKubeConfig(t *testing.T, dir, contextName string) string {
+	kubeConfigFile := filepath.Join(dir, contextName+".yaml")
+	if err := ioutil.WriteFile(kubeConfigFile, []byte(strings.ReplaceAll(testKubeConfigTemplate, "CONTEXT", contextName)), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(filepath.Join(dir, contextName+".crt"), []byte(contextName+"-cert"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return kubeConfigFile
+}
+
+func TestKubeConfigSelection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	first := writeTestKubeConfig(t, dir, "first")
+	second := writeTestKubeConfig(t, dir, "second")
+	defer os.Setenv(clientcmd.RecommendedConfigPathEnvVar, os.Getenv(clientcmd.RecommendedConfigPathEnvVar))
+	os.Setenv(clientcmd.RecommendedConfigPathEnvVar, first+string(os.PathListSeparator)+second)
+	defer SetKubeConfig("", "", "")
+
+	tests := []struct {
+		name          string
+		path          string
+		context       string
+		wantNamespace string
+		wantServer    string
+		wantErr       bool
+	}{
+		{name: "KUBECONFIG", wantNamespace: "first-ns", wantServer: "https://first.example.com"},
+		{name: "context of KUBECONFIG", context: "second", wantNamespace: "second-ns", wantServer: "https://second.example.com"},
+		{name: "kubeconfig", path: second, wantNamespace: "second-ns", wantServer: "https://second.example.com"},
+		{name: "unknown context", path: second, context: "first", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetKubeConfig(tt.path, tt.context, "")
+			contents, err := LoadKubeConfigContents()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if namespace, err := GetKubeNamespace(); err != nil || namespace != tt.wantNamespace {
+				t.Errorf("expected namespace %s, got %s, %v", tt.wantNamespace, namespace, err)
+			}
+			config, err := clientcmd.Load(contents)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cluster := config.Clusters[config.Contexts[config.CurrentContext].Cluster]
+			if cluster.Server != tt.wantServer {
+				t.Errorf("expected server %s, got %s", tt.wantServer, cluster.Server)
+			}
+			// certificate files are embedded, the file may be used from anywhere
+			if user := config.AuthInfos[config.Contexts[config.CurrentContext].AuthInfo]; len(user.ClientCertificateData) == 0 {
+				t.Errorf("expected the client certificate to be embedded")
+			}
+		})
+	}
+}
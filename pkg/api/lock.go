@@ -0,0 +1,232 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	InstallLockName       = "qliksense-install-lock"
+	LockCommandAnnotation = "qliksense.qlik.com/command"
+	defaultLockDuration   = 60 * time.Second
+)
+
+// LockStatus is the state of the install lock of a namespace
+type LockStatus struct {
+	Namespace   string
+	Holder      string
+	Command     string
+	AcquireTime time.Time
+	RenewTime   time.Time
+	Duration    time.Duration
+}
+
+// Expired returns true if the holder did not renew the lock in time, i.e. because it was killed
+func (s *LockStatus) Expired(now time.Time) bool {
+	return now.After(s.RenewTime.Add(s.Duration))
+}
+
+func (s *LockStatus) String() string {
+	return fmt.Sprintf("namespace %s is locked by %s running %s since %s", s.Namespace, s.Holder, s.Command, s.AcquireTime.Format(time.RFC3339))
+}
+
+// InstallLock is a coordination.k8s.io Lease in the target namespace, held while a command changes the install.
+// The holder renews the lease while it holds it, a lease not renewed within its duration may be taken over.
+type InstallLock struct {
+	Clientset kubernetes.Interface
+	Namespace string
+	Holder    string
+	Command   string
+	Duration  time.Duration
+	Out       io.Writer
+	stop      chan struct{}
+	done      chan struct{}
+	lost      chan struct{}
+}
+
+// NewInstallLockFromKubeConfig creates the install lock of the namespace for the current kube context
+func NewInstallLockFromKubeConfig(namespace, command string) (*InstallLock, error) {
+	restConfig, err := GetKubeRestConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &InstallLock{
+		Clientset: clientset,
+		Namespace: namespace,
+		Holder:    LockHolderIdentity(),
+		Command:   command,
+		Duration:  defaultLockDuration,
+		Out:       os.Stdout,
+	}, nil
+}
+
+// LockHolderIdentity identifies this process as user@host (pid)
+func LockHolderIdentity() string {
+	userName := "unknown"
+	if u, err := user.Current(); err == nil {
+		userName = u.Username
+	}
+	hostName, err := os.Hostname()
+	if err != nil {
+		hostName = "unknown"
+	}
+	return fmt.Sprintf("%s@%s (pid %d)", userName, hostName, os.Getpid())
+}
+
+// Status returns the status of the lock, nil if the namespace is not locked
+func (l *InstallLock) Status() (*LockStatus, error) {
+	lease, err := l.Clientset.CoordinationV1().Leases(l.Namespace).Get(InstallLockName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return leaseStatus(lease), nil
+}
+
+// Acquire takes the lock and keeps renewing it until Release. A lock held by someone else is an error,
+// unless it expired or force is set.
+func (l *InstallLock) Acquire(force bool) error {
+	leases := l.Clientset.CoordinationV1().Leases(l.Namespace)
+	now := metav1.NowMicro()
+	lease, err := leases.Get(InstallLockName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: InstallLockName, Namespace: l.Namespace}}
+		l.setHolder(lease, now)
+		if _, err := leases.Create(lease); k8serrors.IsAlreadyExists(err) {
+			return fmt.Errorf("namespace %s was locked concurrently, please retry", l.Namespace)
+		} else if err != nil {
+			return fmt.Errorf("unable to lock namespace %s: %w", l.Namespace, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("unable to lock namespace %s: %w", l.Namespace, err)
+	} else {
+		status := leaseStatus(lease)
+		if status.Holder != "" && status.Holder != l.Holder && !status.Expired(now.Time) {
+			if !force {
+				return fmt.Errorf("%v, use --force-unlock to take over the lock", status)
+			}
+			fmt.Fprintf(l.Out, "Warning: taking over the lock, %v\n", status)
+		}
+		l.setHolder(lease, now)
+		transitions := int32(1)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions + 1
+		}
+		lease.Spec.LeaseTransitions = &transitions
+		// the resource version of the lease read makes a concurrent take over fail with a conflict
+		if _, err := leases.Update(lease); k8serrors.IsConflict(err) {
+			return fmt.Errorf("namespace %s was locked concurrently, please retry", l.Namespace)
+		} else if err != nil {
+			return fmt.Errorf("unable to lock namespace %s: %w", l.Namespace, err)
+		}
+	}
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	l.lost = make(chan struct{})
+	go l.renew()
+	return nil
+}
+
+func (l *InstallLock) setHolder(lease *coordinationv1.Lease, now metav1.MicroTime) {
+	holder := l.Holder
+	duration := int32(l.Duration.Seconds())
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	if lease.Annotations == nil {
+		lease.Annotations = map[string]string{}
+	}
+	lease.Annotations[LockCommandAnnotation] = l.Command
+}
+
+// renew updates the renew time of the lease every third of its duration until stopped
+func (l *InstallLock) renew() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.Duration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			leases := l.Clientset.CoordinationV1().Leases(l.Namespace)
+			lease, err := leases.Get(InstallLockName, metav1.GetOptions{})
+			if err != nil {
+				fmt.Fprintf(l.Out, "Warning: unable to renew the lock of namespace %s: %v\n", l.Namespace, err)
+				continue
+			} else if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.Holder {
+				fmt.Fprintf(l.Out, "Warning: the lock of namespace %s was taken over by %s\n", l.Namespace, leaseStatus(lease).Holder)
+				close(l.lost)
+				return
+			}
+			now := metav1.NowMicro()
+			lease.Spec.RenewTime = &now
+			if _, err := leases.Update(lease); err != nil {
+				fmt.Fprintf(l.Out, "Warning: unable to renew the lock of namespace %s: %v\n", l.Namespace, err)
+			}
+		}
+	}
+}
+
+// Lost is closed once another process took over the lock while it was held, the command holding it has to stop.
+// It is nil, i.e. never closed, before Acquire.
+func (l *InstallLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops renewing the lock and deletes the lease, unless it was taken over in the meantime
+func (l *InstallLock) Release() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop = nil
+	}
+	leases := l.Clientset.CoordinationV1().Leases(l.Namespace)
+	lease, err := leases.Get(InstallLockName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.Holder {
+		return nil
+	}
+	uid := lease.UID
+	if err := leases.Delete(InstallLockName, &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}}); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func leaseStatus(lease *coordinationv1.Lease) *LockStatus {
+	status := &LockStatus{
+		Namespace: lease.Namespace,
+		Command:   lease.Annotations[LockCommandAnnotation],
+	}
+	if lease.Spec.HolderIdentity != nil {
+		status.Holder = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.AcquireTime != nil {
+		status.AcquireTime = lease.Spec.AcquireTime.Time
+	}
+	if lease.Spec.RenewTime != nil {
+		status.RenewTime = lease.Spec.RenewTime.Time
+	}
+	if lease.Spec.LeaseDurationSeconds != nil {
+		status.Duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return status
+}
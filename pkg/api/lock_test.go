@@ -0,0 +1,115 @@
+package api
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestInstallLock(clientset *fake.Clientset, holder, command string) *InstallLock {
+	return &InstallLock{
+		Clientset: clientset,
+		Namespace: "qliksense",
+		Holder:    holder,
+		Command:   command,
+		Duration:  time.Minute,
+		Out:       ioutil.Discard,
+	}
+}
+
+func TestInstallLock(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	first := newTestInstallLock(clientset, "alice@host (pid 1)", "install")
+	second := newTestInstallLock(clientset, "bob@host (pid 2)", "upgrade")
+
+	if status, err := first.Status(); err != nil || status != nil {
+		t.Fatalf("expected no lock, got %v, %v", status, err)
+	}
+	if err := first.Acquire(false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status, err := second.Status()
+	if err != nil {
+		t.Fatal(err)
+	} else if status == nil || status.Holder != first.Holder || status.Command != "install" || status.Expired(time.Now()) {
+		t.Fatalf("expected the lock held by %s running install, got %+v", first.Holder, status)
+	}
+
+	if err := second.Acquire(false); err == nil || !strings.Contains(err.Error(), "--force-unlock") {
+		t.Fatalf("expected the lock to be refused, got %v", err)
+	}
+	if err := second.Acquire(true); err != nil {
+		t.Fatalf("expected the lock to be taken over, got %v", err)
+	}
+	// the lock was taken over, releasing it leaves it to the new holder
+	if err := first.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := second.Status(); err != nil || status == nil || status.Holder != second.Holder {
+		t.Fatalf("expected the lock held by %s, got %+v, %v", second.Holder, status, err)
+	}
+	if err := second.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := second.Status(); err != nil || status != nil {
+		t.Fatalf("expected the lock to be released, got %+v, %v", status, err)
+	}
+}
+
+func TestInstallLock_expired(t *testing.T) {
+	holder := "alice@host (pid 1)"
+	duration := int32(60)
+	renewTime := metav1.NewMicroTime(time.Now().Add(-2 * time.Minute))
+	clientset := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: InstallLockName, Namespace: "qliksense"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &renewTime,
+			RenewTime:            &renewTime,
+		},
+	})
+	lock := newTestInstallLock(clientset, "bob@host (pid 2)", "install")
+	if err := lock.Acquire(false); err != nil {
+		t.Fatalf("expected an expired lock to be taken over, got %v", err)
+	}
+	defer lock.Release()
+	if status, err := lock.Status(); err != nil || status.Holder != lock.Holder {
+		t.Fatalf("expected the lock held by %s, got %+v, %v", lock.Holder, status, err)
+	}
+}
+
+func TestInstallLock_lost(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	first := newTestInstallLock(clientset, "alice@host (pid 1)", "install")
+	first.Duration = 30 * time.Millisecond
+	second := newTestInstallLock(clientset, "bob@host (pid 2)", "upgrade")
+
+	if first.Lost() != nil {
+		t.Fatal("expected no lost channel before the lock is acquired")
+	}
+	if err := first.Acquire(false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer first.Release()
+	select {
+	case <-first.Lost():
+		t.Fatal("expected the lock to be held")
+	case <-time.After(3 * first.Duration):
+	}
+
+	if err := second.Acquire(true); err != nil {
+		t.Fatalf("expected the lock to be taken over, got %v", err)
+	}
+	defer second.Release()
+	select {
+	case <-first.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("expected the holder to be notified that the lock was taken over")
+	}
+}
@@ -2,9 +2,7 @@ package api
 
 import (
 	"os"
-	"path"
 	"path/filepath"
-	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -99,10 +97,18 @@ func (p *PreflightConfig) GetImageName(imageFor string, accountForImageRegistry
 		if currentCR, err := qConfig.GetCurrentCR(); err != nil {
 			return "", err
 		} else if imageRegistry := currentCR.Spec.GetImageRegistry(); imageRegistry != "" {
-			imageSegments := strings.Split(image, "/")
-			imageNameAndTag := imageSegments[len(imageSegments)-1]
-			return path.Join(imageRegistry, imageNameAndTag), nil
+			mapping, err := qConfig.GetImageRegistryMapping()
+			if err != nil {
+				return "", err
+			}
+			return mapping.TargetImage(image, imageRegistry), nil
 		}
+		// without an image registry the images are pulled from their source registries, through the mirrors
+		mirrors, err := qConfig.GetImageMirrors()
+		if err != nil {
+			return "", err
+		}
+		return mirrors.MirrorImage(image), nil
 	}
 	return image, nil
 }
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const pruneOperation = "prune"
+
+// kinds that are never pruned, deleting them would also delete data or other resources
+var pruneProtectedKinds = map[schema.GroupKind]bool{
+	{Group: "", Kind: "Namespace"}:                                    true,
+	{Group: "", Kind: PersistentVolumeClaimKind}:                      true,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: true,
+	{Group: QliksenseGroup, Kind: QliksenseKind}:                      true,
+}
+
+// IsProtectedKind returns true for the kinds that are never pruned: namespaces, persistent volume claims, CRDs and
+// the CR. Deleting them also deletes data or other resources.
+func IsProtectedKind(apiVersion, kind string) bool {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return false
+	}
+	return pruneProtectedKinds[schema.GroupKind{Group: gv.Group, Kind: kind}]
+}
+
+// PrunedObject is an object that carries the owner labels but is not in the manifests
+type PrunedObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Kept is set for kinds that are never deleted
+	Kept bool
+}
+
+func (p *PrunedObject) String() string {
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s/%s", p.Kind, p.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", p.Kind, p.Namespace, p.Name)
+}
+
+// Prune deletes the objects that carry all the selector labels but are not in the manifests.
+// Objects are looked up for the kinds of manifests and previousManifests, namespaced kinds only in namespace.
+// Cluster-scoped objects are only pruned if they are in previousManifests, as installs in other namespaces
+// may carry the same labels. With dryRun nothing is deleted.
+// Namespaces, persistent volume claims and CRDs are reported as kept and never deleted.
+func (e *ApplyEngine) Prune(manifests, previousManifests, namespace string, selector map[string]string, dryRun bool) ([]*PrunedObject, error) {
+	if len(selector) == 0 {
+		return nil, fmt.Errorf("cannot prune without a label selector")
+	}
+	objs, err := decodeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+	previousObjs, err := decodeManifests(previousManifests)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := map[string]bool{}
+	kinds := map[schema.GroupKind]string{}
+	for _, obj := range objs {
+		if _, _, err := e.resourceFor(obj, namespace, false); err != nil && !meta.IsNoMatchError(err) {
+			return nil, e.objectError(pruneOperation, obj, err)
+		}
+		rendered[objectKey(obj)] = true
+		gvk := obj.GroupVersionKind()
+		kinds[gvk.GroupKind()] = gvk.Version
+	}
+	previous := map[string]bool{}
+	for _, obj := range previousObjs {
+		previous[objectKey(obj)] = true
+		gvk := obj.GroupVersionKind()
+		if _, ok := kinds[gvk.GroupKind()]; !ok {
+			kinds[gvk.GroupKind()] = gvk.Version
+		}
+	}
+	groupKinds := make([]schema.GroupKind, 0, len(kinds))
+	for gk := range kinds {
+		groupKinds = append(groupKinds, gk)
+	}
+	sort.Slice(groupKinds, func(i, j int) bool {
+		return groupKinds[i].String() < groupKinds[j].String()
+	})
+
+	listOptions := metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()}
+	var pruned []*PrunedObject
+	var errs ObjectErrors
+	for _, gk := range groupKinds {
+		mapping, err := e.mapper.RESTMapping(gk, kinds[gk])
+		if meta.IsNoMatchError(err) {
+			continue
+		} else if err != nil {
+			return pruned, err
+		}
+		list, err := e.listObjects(mapping, namespace, listOptions)
+		if err != nil {
+			return pruned, err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if rendered[objectKey(obj)] || (obj.GetNamespace() == "" && !previous[objectKey(obj)]) {
+				continue
+			}
+			p := &PrunedObject{
+				Kind:      obj.GetKind(),
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Kept:      pruneProtectedKinds[gk],
+			}
+			pruned = append(pruned, p)
+			if p.Kept || dryRun {
+				continue
+			}
+			if err := e.deleteObject(obj, namespace); err != nil {
+				errs = append(errs, e.objectError(pruneOperation, obj, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return pruned, errs
+	}
+	return pruned, nil
+}
+
+// listObjects lists the objects of mapping, those of namespace for a namespaced kind
+func (e *ApplyEngine) listObjects(mapping *meta.RESTMapping, namespace string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return e.client.Resource(mapping.Resource).List(opts)
+	}
+	if namespace == "" {
+		namespace = e.defaultNamespace
+	}
+	return e.client.Resource(mapping.Resource).Namespace(namespace).List(opts)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func Test_stamp(t *testing.T) {
+	engine, _ := newTestApplyEngine()
+	engine.Labels = map[string]string{OwnerLabel: "qlik-test"}
+	engine.Annotations = map[string]string{RevisionAnnotation: "3"}
+	obj := newTestObject("ConfigMap", "engine-configs", nil)
+	obj.SetLabels(map[string]string{"app": "engine"})
+	engine.stamp(obj)
+	if labels := obj.GetLabels(); labels["app"] != "engine" || labels[OwnerLabel] != "qlik-test" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+	if annotations := obj.GetAnnotations(); annotations[RevisionAnnotation] != "3" {
+		t.Fatalf("unexpected annotations: %v", annotations)
+	}
+}
+
+func TestApplyEngine_Prune(t *testing.T) {
+	engine, client := newTestApplyEngine()
+	owned := map[string]string{OwnerLabel: "qlik-test"}
+	configMapsResource := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	clusterRolesResource := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+
+	rendered := newTestObject("ConfigMap", "engine-configs", nil)
+	stale := newTestObject("ConfigMap", "old-configs", nil)
+	foreign := newTestObject("ConfigMap", "foreign-configs", nil)
+	staleRole := newTestObject("ClusterRole", "old-role", nil)
+	staleRole.SetAPIVersion("rbac.authorization.k8s.io/v1")
+	staleRole.SetNamespace("")
+	// an install of a context of the same name in another namespace
+	otherInstall := newTestObject("ConfigMap", "old-configs", nil)
+	otherInstall.SetNamespace("other")
+	otherRole := newTestObject("ClusterRole", "other-role", nil)
+	otherRole.SetAPIVersion("rbac.authorization.k8s.io/v1")
+	otherRole.SetNamespace("")
+	for _, obj := range []*unstructured.Unstructured{rendered, stale, staleRole, otherInstall, otherRole} {
+		obj.SetLabels(owned)
+	}
+	if _, err := client.Resource(configMapsResource).Namespace("other").Create(otherInstall, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	} else if _, err := client.Resource(clusterRolesResource).Create(otherRole, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range []*unstructured.Unstructured{rendered, stale, foreign} {
+		if _, err := client.Resource(configMapsResource).Namespace("qliksense").Create(obj, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.Resource(clusterRolesResource).Create(staleRole, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	manifests := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: engine-configs
+`
+	previous := `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: old-role
+`
+	pruned, err := engine.Prune(manifests, previous, "qliksense", owned, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pruned) != 2 || pruned[0].String() != "ClusterRole/old-role" || pruned[1].String() != "ConfigMap/qliksense/old-configs" {
+		t.Fatalf("unexpected pruned objects: %v", pruned)
+	}
+	if _, err := client.Resource(configMapsResource).Namespace("qliksense").Get("old-configs", metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected old-configs to be kept on dry run: %v", err)
+	}
+
+	if _, err := engine.Prune(manifests, previous, "qliksense", owned, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list, err := client.Resource(configMapsResource).Namespace("qliksense").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := unstructuredNames(list.Items)
+	if len(names) != 2 || names[0] != "engine-configs" || names[1] != "foreign-configs" {
+		t.Fatalf("unexpected config maps after prune: %v", names)
+	}
+	if _, err := client.Resource(clusterRolesResource).Get("old-role", metav1.GetOptions{}); err == nil {
+		t.Fatal("expected old-role to be deleted")
+	}
+	if _, err := client.Resource(configMapsResource).Namespace("other").Get("old-configs", metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected the objects of the install in another namespace to be kept: %v", err)
+	} else if _, err := client.Resource(clusterRolesResource).Get("other-role", metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected the cluster-scoped objects not in the previous manifests to be kept: %v", err)
+	}
+}
+
+func TestIsProtectedKind(t *testing.T) {
+	tests := []struct {
+		apiVersion string
+		kind       string
+		want       bool
+	}{
+		{apiVersion: "v1", kind: "Namespace", want: true},
+		{apiVersion: "v1", kind: PersistentVolumeClaimKind, want: true},
+		{apiVersion: "apiextensions.k8s.io/v1", kind: "CustomResourceDefinition", want: true},
+		{apiVersion: "qlik.com/v1", kind: QliksenseKind, want: true},
+		{apiVersion: "apps/v1", kind: "Deployment", want: false},
+		{apiVersion: "example.com/v1", kind: "Namespace", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsProtectedKind(tt.apiVersion, tt.kind); got != tt.want {
+			t.Errorf("IsProtectedKind(%s, %s) = %v, want %v", tt.apiVersion, tt.kind, got, tt.want)
+		}
+	}
+}
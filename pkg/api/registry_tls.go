@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const registriesTLSFileName = "image-registry-tls.yaml"
+
+// RegistryTLS is how the CLI verifies an image registry and authenticates to it with TLS.
+// Without settings the certificate of a registry is verified with the system CAs.
+type RegistryTLS struct {
+	// CACert is a PEM bundle of CAs trusted in addition to the system CAs
+	CACert string `yaml:"caCert,omitempty"`
+	// ClientCert and ClientKey are the PEM certificate and key for mutual TLS
+	ClientCert string `yaml:"clientCert,omitempty"`
+	ClientKey  string `yaml:"clientKey,omitempty"`
+	// Insecure skips the verification of the certificate of the registry
+	Insecure bool `yaml:"insecure,omitempty"`
+}
+
+// RegistriesTLS holds the TLS settings by registry host, i.e. registry.example.com:5000
+type RegistriesTLS map[string]*RegistryTLS
+
+// IsEmpty returns true if the settings change nothing to the defaults
+func (t *RegistryTLS) IsEmpty() bool {
+	return t == nil || (t.CACert == "" && t.ClientCert == "" && t.ClientKey == "" && !t.Insecure)
+}
+
+// Validate checks that a client certificate comes with its key and that the files exist
+func (t *RegistryTLS) Validate() error {
+	if (t.ClientCert == "") != (t.ClientKey == "") {
+		return errors.New("a client certificate and its key must be set together")
+	}
+	for _, f := range []string{t.CACert, t.ClientCert, t.ClientKey} {
+		if f == "" {
+			continue
+		}
+		if info, err := os.Stat(f); err != nil {
+			return err
+		} else if info.IsDir() {
+			return fmt.Errorf("%s is a directory", f)
+		}
+	}
+	return nil
+}
+
+// RegistryHost returns the host and port of a registry, given with or without scheme and path
+func RegistryHost(registry string) string {
+	host := registry
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+// GetRegistriesTLS returns the TLS settings of the registries of the current context
+func (qc *QliksenseConfig) GetRegistriesTLS() (RegistriesTLS, error) {
+	registries := RegistriesTLS{}
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(filepath.Join(contextDir, registriesTLSFileName))
+	if os.IsNotExist(err) {
+		return registries, nil
+	} else if err != nil {
+		return nil, err
+	} else if err := yaml.Unmarshal(content, &registries); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", registriesTLSFileName, err)
+	}
+	return registries, nil
+}
+
+// SetRegistryTLS sets the TLS settings of the registry in the current context, empty settings remove them.
+// The files are recorded with their absolute path.
+func (qc *QliksenseConfig) SetRegistryTLS(registry string, tls *RegistryTLS) error {
+	registries, err := qc.GetRegistriesTLS()
+	if err != nil {
+		return err
+	}
+	host := RegistryHost(registry)
+	if tls.IsEmpty() {
+		delete(registries, host)
+	} else {
+		if err := tls.Validate(); err != nil {
+			return err
+		}
+		abs := *tls
+		for _, f := range []*string{&abs.CACert, &abs.ClientCert, &abs.ClientKey} {
+			if *f != "" {
+				if *f, err = filepath.Abs(*f); err != nil {
+					return err
+				}
+			}
+		}
+		registries[host] = &abs
+	}
+	contextDir, err := qc.GetCurrentContextDir()
+	if err != nil {
+		return err
+	}
+	if len(registries) == 0 {
+		if err := os.Remove(filepath.Join(contextDir, registriesTLSFileName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	content, err := yaml.Marshal(registries)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(contextDir, os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(contextDir, registriesTLSFileName), content, 0644)
+}
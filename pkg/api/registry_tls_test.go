@@ -0,0 +1,57 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		registry string
+		want     string
+	}{
+		{registry: "registry.example.com", want: "registry.example.com"},
+		{registry: "https://registry.example.com:5000", want: "registry.example.com:5000"},
+		{registry: "registry.example.com:5000/qlik", want: "registry.example.com:5000"},
+		{registry: "http://127.0.0.1:5000/qlik/", want: "127.0.0.1:5000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.registry, func(t *testing.T) {
+			if got := RegistryHost(tt.registry); got != tt.want {
+				t.Errorf("RegistryHost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryTLS_Validate(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "registry-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	cert := filepath.Join(tmp, "client.pem")
+	if err := ioutil.WriteFile(cert, []byte("cert"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		tls     *RegistryTLS
+		wantErr bool
+	}{
+		{name: "insecure", tls: &RegistryTLS{Insecure: true}},
+		{name: "client certificate", tls: &RegistryTLS{ClientCert: cert, ClientKey: cert}},
+		{name: "client certificate without key", tls: &RegistryTLS{ClientCert: cert}, wantErr: true},
+		{name: "missing CA", tls: &RegistryTLS{CACert: filepath.Join(tmp, "ca.pem")}, wantErr: true},
+		{name: "CA is a directory", tls: &RegistryTLS{CACert: tmp}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tls.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
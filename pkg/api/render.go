@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// how secrets are rendered
+const (
+	SecretsPlain     = "plain"
+	SecretsRedacted  = "redacted"
+	SecretsEncrypted = "encrypted"
+
+	secretKind = "Secret"
+)
+
+// RenderOptions select how secrets are rendered. With SecretsEncrypted every secret is piped as yaml
+// through EncryptCommand, i.e. "sops --encrypt --input-type yaml --output-type yaml /dev/stdin" or "kubeseal -o yaml",
+// and its output is written instead.
+type RenderOptions struct {
+	Secrets        string
+	EncryptCommand string
+}
+
+// RenderedObject is the yaml of one object and the name of the file it is written to
+type RenderedObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+	FileName  string
+	Content   []byte
+	// Secret is set for objects of kind Secret
+	Secret bool
+}
+
+func (o *RenderOptions) Validate() error {
+	if o.Secrets != SecretsPlain && o.Secrets != SecretsRedacted && o.Secrets != SecretsEncrypted {
+		return fmt.Errorf("unsupported secrets %s, use %s, %s or %s", o.Secrets, SecretsPlain, SecretsRedacted, SecretsEncrypted)
+	} else if o.Secrets == SecretsEncrypted && strings.TrimSpace(o.EncryptCommand) == "" {
+		return fmt.Errorf("an encrypt command is required to render %s secrets", SecretsEncrypted)
+	}
+	return nil
+}
+
+// RenderObjects splits the manifests into one yaml document per object, rendering secrets as the options select
+func RenderObjects(manifests string, opts *RenderOptions) ([]*RenderedObject, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	objs, err := decodeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+	var rendered []*RenderedObject
+	for _, obj := range objs {
+		isSecret := obj.GetKind() == secretKind && obj.GroupVersionKind().Group == ""
+		if isSecret && opts.Secrets == SecretsRedacted {
+			redactSecretData(obj)
+		}
+		content, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		if isSecret && opts.Secrets == SecretsEncrypted {
+			if content, err = encryptSecret(content, opts.EncryptCommand); err != nil {
+				return nil, fmt.Errorf("unable to encrypt secret %s: %w", obj.GetName(), err)
+			}
+		}
+		rendered = append(rendered, &RenderedObject{
+			Kind:      obj.GetKind(),
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			FileName:  renderedFileName(obj),
+			Content:   content,
+			Secret:    isSecret,
+		})
+	}
+	return rendered, nil
+}
+
+// renderedFileName is <namespace>_<kind>_<name>.yaml, without the namespace for objects that have none
+func renderedFileName(obj *unstructured.Unstructured) string {
+	parts := []string{strings.ToLower(obj.GetKind()), obj.GetName()}
+	if obj.GetNamespace() != "" {
+		parts = append([]string{obj.GetNamespace()}, parts...)
+	}
+	return strings.ReplaceAll(strings.Join(parts, "_"), ":", "-") + ".yaml"
+}
+
+// redactSecretData replaces every value of the secret, data is moved into stringData so that the secret stays valid
+func redactSecretData(obj *unstructured.Unstructured) {
+	redacted := map[string]interface{}{}
+	for _, field := range []string{"data", "stringData"} {
+		data, _, _ := unstructured.NestedMap(obj.Object, field)
+		for k := range data {
+			redacted[k] = maskedValue
+		}
+	}
+	unstructured.RemoveNestedField(obj.Object, "data")
+	if len(redacted) > 0 {
+		unstructured.SetNestedMap(obj.Object, redacted, "stringData")
+	}
+}
+
+// RedactCRSecrets replaces the values of the secrets of the CR as RenderObjects replaces the values of secrets
+func RedactCRSecrets(cr *QliksenseCR) {
+	for _, nvs := range cr.Spec.Secrets {
+		for i := range nvs {
+			if nvs[i].Value != "" {
+				nvs[i].Value = maskedValue
+			}
+		}
+	}
+	if cr.Spec.Git != nil && cr.Spec.Git.AccessToken != "" {
+		git := *cr.Spec.Git
+		git.AccessToken = maskedValue
+		cr.Spec.Git = &git
+	}
+}
+
+// encryptSecret pipes the secret through the command and returns its output
+func encryptSecret(content []byte, command string) ([]byte, error) {
+	args := strings.Fields(command)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const testRenderManifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: engine
+data:
+  key: value
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: mongodb
+  namespace: qliksense
+data:
+  uri: bW9uZ29kYjovL21vbmdv
+stringData:
+  password: secret
+`
+
+func TestRenderObjects(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       *RenderOptions
+		wantSecret []string
+		dontWant   []string
+		wantErr    bool
+	}{
+		{
+			name:       "plain",
+			opts:       &RenderOptions{Secrets: SecretsPlain},
+			wantSecret: []string{"uri: bW9uZ29kYjovL21vbmdv", "password: secret"},
+		},
+		{
+			name:       "redacted",
+			opts:       &RenderOptions{Secrets: SecretsRedacted},
+			wantSecret: []string{"stringData:", "uri: '***'", "password: '***'"},
+			dontWant:   []string{"bW9uZ29kYjovL21vbmdv", "secret\n", "data:\n  uri"},
+		},
+		{
+			name:       "encrypted",
+			opts:       &RenderOptions{Secrets: SecretsEncrypted, EncryptCommand: "tr a-z A-Z"},
+			wantSecret: []string{"KIND: SECRET"},
+		},
+		{
+			name:    "encrypted without command",
+			opts:    &RenderOptions{Secrets: SecretsEncrypted},
+			wantErr: true,
+		},
+		{
+			name:    "unknown",
+			opts:    &RenderOptions{Secrets: "base64"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opts.EncryptCommand != "" {
+				if _, err := exec.LookPath(strings.Fields(tt.opts.EncryptCommand)[0]); err != nil {
+					t.Skip(err)
+				}
+			}
+			objs, err := RenderObjects(testRenderManifests, tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(objs) != 2 {
+				t.Fatalf("expected 2 objects, got %d", len(objs))
+			}
+			if objs[0].FileName != "configmap_engine.yaml" || objs[1].FileName != "qliksense_secret_mongodb.yaml" {
+				t.Errorf("unexpected file names %s and %s", objs[0].FileName, objs[1].FileName)
+			}
+			if !strings.Contains(string(objs[0].Content), "key: value") {
+				t.Errorf("expected the config map unchanged, got:\n%s", objs[0].Content)
+			}
+			secret := string(objs[1].Content)
+			for _, want := range tt.wantSecret {
+				if !strings.Contains(secret, want) {
+					t.Errorf("expected %q in the secret, got:\n%s", want, secret)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(secret, dontWant) {
+					t.Errorf("unexpected %q in the secret, got:\n%s", dontWant, secret)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	qliksenseRevisionsDirName  = "revisions"
+	revisionInfoFileName       = "revision.yaml"
+	revisionManifestsFileName  = "manifests.enc"
+	revisionCrFileName         = "cr.yaml"
+	maxRevisionHistory         = 10
+	revisionDirPermission      = 0700
+	revisionFilePermission     = 0600
+	revisionTimestampLayout    = time.RFC3339
+	revisionNotFoundErrMessage = "revision %d not found for context %s"
+)
+
+// Revision describes what was applied into the cluster by one install, upgrade or rollback
+type Revision struct {
+	Number     int    `json:"revision" yaml:"revision"`
+	Action     string `json:"action" yaml:"action"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"`
+	Namespace  string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	CliVersion string `json:"cliVersion,omitempty" yaml:"cliVersion,omitempty"`
+	Timestamp  string `json:"timestamp" yaml:"timestamp"`
+}
+
+// GetContextRevisionsDir returns ~/.qliksense/contexts/<contx-name>/revisions
+func (qc *QliksenseConfig) GetContextRevisionsDir(contextName string) string {
+	return filepath.Join(qc.GetContextPath(contextName), qliksenseRevisionsDirName)
+}
+
+func (qc *QliksenseConfig) getRevisionDir(contextName string, number int) string {
+	return filepath.Join(qc.GetContextRevisionsDir(contextName), strconv.Itoa(number))
+}
+
+// SaveRevision stores the manifests (encrypted) and the CR (with encrypted secrets) as the next revision of the context.
+// Only the last maxRevisionHistory revisions are kept.
+func (qc *QliksenseConfig) SaveRevision(contextName string, rev *Revision, manifests []byte, cr *QliksenseCR) (*Revision, error) {
+	revisions, err := qc.ListRevisions(contextName)
+	if err != nil {
+		return nil, err
+	}
+	rev.Number = nextRevisionNumber(revisions)
+	if rev.Timestamp == "" {
+		rev.Timestamp = time.Now().UTC().Format(revisionTimestampLayout)
+	}
+
+	encryptionKey, err := qc.GetEncryptionKeyFor(contextName)
+	if err != nil {
+		return nil, err
+	}
+	revDir := qc.getRevisionDir(contextName, rev.Number)
+	if err := os.MkdirAll(revDir, revisionDirPermission); err != nil {
+		return nil, err
+	}
+	if encManifests, err := EncryptData(manifests, encryptionKey); err != nil {
+		return nil, err
+	} else if err := ioutil.WriteFile(filepath.Join(revDir, revisionManifestsFileName), encManifests, revisionFilePermission); err != nil {
+		return nil, err
+	}
+	if crBytes, err := K8sToYaml(cr); err != nil {
+		return nil, err
+	} else if err := ioutil.WriteFile(filepath.Join(revDir, revisionCrFileName), crBytes, revisionFilePermission); err != nil {
+		return nil, err
+	}
+	if revBytes, err := yaml.Marshal(rev); err != nil {
+		return nil, err
+	} else if err := ioutil.WriteFile(filepath.Join(revDir, revisionInfoFileName), revBytes, revisionFilePermission); err != nil {
+		return nil, err
+	}
+
+	revisions = append(revisions, rev)
+	for len(revisions) > maxRevisionHistory {
+		if err := os.RemoveAll(qc.getRevisionDir(contextName, revisions[0].Number)); err != nil {
+			return nil, err
+		}
+		revisions = revisions[1:]
+	}
+	return rev, nil
+}
+
+// NextRevisionNumber returns the number the next saved revision of the context gets
+func (qc *QliksenseConfig) NextRevisionNumber(contextName string) (int, error) {
+	revisions, err := qc.ListRevisions(contextName)
+	if err != nil {
+		return 0, err
+	}
+	return nextRevisionNumber(revisions), nil
+}
+
+func nextRevisionNumber(revisions []*Revision) int {
+	if len(revisions) == 0 {
+		return 1
+	}
+	return revisions[len(revisions)-1].Number + 1
+}
+
+// ListRevisions returns the revisions of the context, oldest first
+func (qc *QliksenseConfig) ListRevisions(contextName string) ([]*Revision, error) {
+	infos, err := ioutil.ReadDir(qc.GetContextRevisionsDir(contextName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var revisions []*Revision
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		number, err := strconv.Atoi(info.Name())
+		if err != nil {
+			continue
+		}
+		rev, err := qc.GetRevision(contextName, number)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+	return revisions, nil
+}
+
+// GetRevision reads the information of one revision of the context
+func (qc *QliksenseConfig) GetRevision(contextName string, number int) (*Revision, error) {
+	revBytes, err := ioutil.ReadFile(filepath.Join(qc.getRevisionDir(contextName, number), revisionInfoFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf(revisionNotFoundErrMessage, number, contextName)
+		}
+		return nil, err
+	}
+	rev := &Revision{}
+	if err := yaml.Unmarshal(revBytes, rev); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// GetRevisionManifests returns the decrypted manifests of one revision of the context
+func (qc *QliksenseConfig) GetRevisionManifests(contextName string, number int) ([]byte, error) {
+	encManifests, err := ioutil.ReadFile(filepath.Join(qc.getRevisionDir(contextName, number), revisionManifestsFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf(revisionNotFoundErrMessage, number, contextName)
+		}
+		return nil, err
+	}
+	encryptionKey, err := qc.GetEncryptionKeyFor(contextName)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptData(encManifests, encryptionKey)
+}
+
+// GetRevisionCR returns the CR snapshot of one revision of the context, secrets remain encrypted
+func (qc *QliksenseConfig) GetRevisionCR(contextName string, number int) (*QliksenseCR, error) {
+	crFile := filepath.Join(qc.getRevisionDir(contextName, number), revisionCrFileName)
+	if !FileExists(crFile) {
+		return nil, fmt.Errorf(revisionNotFoundErrMessage, number, contextName)
+	}
+	return GetCRObject(crFile)
+}
+
+// GetLatestRevision returns the most recent revision of the context or nil if there is none
+func (qc *QliksenseConfig) GetLatestRevision(contextName string) (*Revision, error) {
+	revisions, err := qc.ListRevisions(contextName)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	return revisions[len(revisions)-1], nil
+}
+
+// GetPreviousRevision returns the revision before the most recent one
+func (qc *QliksenseConfig) GetPreviousRevision(contextName string) (*Revision, error) {
+	revisions, err := qc.ListRevisions(contextName)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) < 2 {
+		return nil, errors.New("no previous revision recorded for the context " + contextName)
+	}
+	return revisions[len(revisions)-2], nil
+}
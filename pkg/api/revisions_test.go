@@ -0,0 +1,91 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndListRevisions(t *testing.T) {
+	td, dir := setup()
+	defer td()
+	createCRFile(dir)
+	setupGenerateKey(dir)
+	qc := NewQConfig(dir)
+	qct, err := qc.SetCrLocation("contx1", filepath.Join("contexts", "contx1", "contx1.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qcr, err := qct.GetCurrentCR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	qcr.Spec.AddToSecrets("qliksense", "mongodbUri", "mongodb://mymongo:27017", "")
+
+	manifests := []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: mysecret\ndata:\n  password: c2VjcmV0\n")
+	for i := 0; i < maxRevisionHistory+2; i++ {
+		ecr, err := qct.GetEncryptedCr(qcr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := qct.SaveRevision("contx1", &Revision{Action: "install", Version: "v1.0.0"}, manifests, ecr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := qct.ListRevisions("contx1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != maxRevisionHistory {
+		t.Fatalf("expected %d revisions, got %d", maxRevisionHistory, len(revisions))
+	}
+	if revisions[0].Number != 3 || revisions[len(revisions)-1].Number != maxRevisionHistory+2 {
+		t.Fatalf("expected revisions 3 to %d, got %d to %d", maxRevisionHistory+2, revisions[0].Number, revisions[len(revisions)-1].Number)
+	}
+	if _, err := qct.GetRevision("contx1", 1); err == nil {
+		t.Fatal("expected revision 1 to be pruned")
+	}
+	if prev, err := qct.GetPreviousRevision("contx1"); err != nil {
+		t.Fatal(err)
+	} else if prev.Number != maxRevisionHistory+1 {
+		t.Fatalf("expected previous revision %d, got %d", maxRevisionHistory+1, prev.Number)
+	}
+
+	got, err := qct.GetRevisionManifests("contx1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(manifests) {
+		t.Fatalf("expected manifests: %s, got: %s", manifests, got)
+	}
+
+	rcr, err := qct.GetRevisionCR("contx1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcr.Spec.GetFromSecrets("qliksense", "mongodbUri") == "mongodb://mymongo:27017" {
+		t.Fatal("expected the secret of the revision cr to be encrypted")
+	}
+	if qcr.Spec.GetFromSecrets("qliksense", "mongodbUri") != "mongodb://mymongo:27017" {
+		t.Fatal("expected the secret of the original cr to be unchanged")
+	}
+	dcr, err := qct.GetDecryptedCr(rcr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dcr.Spec.GetFromSecrets("qliksense", "mongodbUri") != "mongodb://mymongo:27017" {
+		t.Fatalf("expected decrypted secret mongodb://mymongo:27017, got: %s", dcr.Spec.GetFromSecrets("qliksense", "mongodbUri"))
+	}
+}
+
+func TestListRevisionsEmpty(t *testing.T) {
+	td, dir := setup()
+	defer td()
+	qc := NewQConfig(dir)
+	if revisions, err := qc.ListRevisions("contx1"); err != nil || len(revisions) != 0 {
+		t.Fatalf("expected no revisions and no error, got %v, %v", revisions, err)
+	}
+	if _, err := qc.GetPreviousRevision("contx1"); err == nil {
+		t.Fatal("expected an error without a previous revision")
+	}
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	DeploymentKind  = "Deployment"
+	StatefulSetKind = "StatefulSet"
+	JobKind         = "Job"
+)
+
+var qliksenseResource = schema.GroupVersionResource{Group: QliksenseGroup, Version: QliksenseApiVersion, Resource: "qliksenses"}
+
+// WorkloadRef identifies a Deployment, StatefulSet, Job or Qliksense CR to wait for
+type WorkloadRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (r WorkloadRef) String() string {
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// WorkloadStatus is the readiness of a workload at the last check
+type WorkloadStatus struct {
+	WorkloadRef
+	Ready   bool
+	Failed  bool
+	Message string
+}
+
+// ErrInterrupted is returned by the steps of a command stopped by an interrupt
+var ErrInterrupted = errors.New("interrupted")
+
+// RolloutWatcher polls workloads until they are ready
+type RolloutWatcher struct {
+	Clientset kubernetes.Interface
+	Dynamic   dynamic.Interface
+	Interval  time.Duration
+	Out       io.Writer
+	// Stop ends the wait with ErrInterrupted once closed, nil never does
+	Stop <-chan struct{}
+}
+
+// NewRolloutWatcherFromKubeConfig creates a RolloutWatcher for the current kube context
+func NewRolloutWatcherFromKubeConfig() (*RolloutWatcher, error) {
+	restConfig, err := GetKubeRestConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &RolloutWatcher{
+		Clientset: clientset,
+		Dynamic:   dynamicClient,
+		Interval:  5 * time.Second,
+		Out:       os.Stdout,
+	}, nil
+}
+
+// Wait polls the workloads until all of them are ready or failed, or the timeout is reached.
+// Every change of a workload status is printed, the last statuses are returned.
+func (w *RolloutWatcher) Wait(workloads []WorkloadRef, timeout time.Duration) ([]*WorkloadStatus, error) {
+	statuses := make([]*WorkloadStatus, len(workloads))
+	deadline := time.Now().Add(timeout)
+	for {
+		done := true
+		readyCount := 0
+		for i, ref := range workloads {
+			if statuses[i] != nil && (statuses[i].Ready || statuses[i].Failed) {
+				readyCount += boolToInt(statuses[i].Ready)
+				continue
+			}
+			status := w.getStatus(ref)
+			if statuses[i] == nil || statuses[i].Message != status.Message {
+				fmt.Fprintf(w.Out, "%s: %s\n", ref, status.Message)
+			}
+			statuses[i] = status
+			readyCount += boolToInt(status.Ready)
+			done = done && (status.Ready || status.Failed)
+		}
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			return statuses, fmt.Errorf("%d of %d workloads are not ready after %v", len(workloads)-readyCount, len(workloads), timeout)
+		}
+		fmt.Fprintf(w.Out, "%d/%d workloads ready\n", readyCount, len(workloads))
+		select {
+		case <-w.Stop:
+			return statuses, ErrInterrupted
+		case <-time.After(w.Interval):
+		}
+	}
+	failed := 0
+	for _, status := range statuses {
+		if !status.Ready {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return statuses, fmt.Errorf("%d of %d workloads failed", failed, len(workloads))
+	}
+	return statuses, nil
+}
+
+func (w *RolloutWatcher) getStatus(ref WorkloadRef) *WorkloadStatus {
+	status := &WorkloadStatus{WorkloadRef: ref}
+	var err error
+	switch ref.Kind {
+	case DeploymentKind:
+		var d *appsv1.Deployment
+		if d, err = w.Clientset.AppsV1().Deployments(ref.Namespace).Get(ref.Name, v1.GetOptions{}); err == nil {
+			status.Ready, status.Failed, status.Message = deploymentStatus(d)
+		}
+	case StatefulSetKind:
+		var s *appsv1.StatefulSet
+		if s, err = w.Clientset.AppsV1().StatefulSets(ref.Namespace).Get(ref.Name, v1.GetOptions{}); err == nil {
+			status.Ready, status.Failed, status.Message = statefulSetStatus(s)
+		}
+	case JobKind:
+		var j *batchv1.Job
+		if j, err = w.Clientset.BatchV1().Jobs(ref.Namespace).Get(ref.Name, v1.GetOptions{}); err == nil {
+			status.Ready, status.Failed, status.Message = jobStatus(j)
+		}
+	case QliksenseKind:
+		var u *unstructured.Unstructured
+		if u, err = w.Dynamic.Resource(qliksenseResource).Namespace(ref.Namespace).Get(ref.Name, v1.GetOptions{}); err == nil {
+			status.Ready, status.Failed, status.Message = qliksenseCrStatus(u)
+		}
+	default:
+		status.Failed = true
+		status.Message = "unsupported kind"
+	}
+	if k8serrors.IsNotFound(err) {
+		status.Message = "not found yet"
+	} else if err != nil {
+		status.Message = err.Error()
+	}
+	return status
+}
+
+func deploymentStatus(d *appsv1.Deployment) (ready, failed bool, message string) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return false, false, "waiting for the spec to be observed"
+	}
+	for _, cond := range d.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return false, true, "progress deadline exceeded"
+		}
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	if d.Status.UpdatedReplicas < replicas {
+		return false, false, fmt.Sprintf("%d/%d replicas updated", d.Status.UpdatedReplicas, replicas)
+	}
+	if d.Status.Replicas > d.Status.UpdatedReplicas {
+		return false, false, fmt.Sprintf("%d old replicas pending termination", d.Status.Replicas-d.Status.UpdatedReplicas)
+	}
+	if d.Status.AvailableReplicas < d.Status.UpdatedReplicas {
+		return false, false, fmt.Sprintf("%d/%d replicas available", d.Status.AvailableReplicas, replicas)
+	}
+	return true, false, fmt.Sprintf("%d/%d replicas available", d.Status.AvailableReplicas, replicas)
+}
+
+func statefulSetStatus(s *appsv1.StatefulSet) (ready, failed bool, message string) {
+	if s.Generation > s.Status.ObservedGeneration {
+		return false, false, "waiting for the spec to be observed"
+	}
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	if s.Status.ReadyReplicas < replicas {
+		return false, false, fmt.Sprintf("%d/%d replicas ready", s.Status.ReadyReplicas, replicas)
+	}
+	if s.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType && s.Status.UpdateRevision != s.Status.CurrentRevision {
+		return false, false, fmt.Sprintf("%d/%d replicas updated", s.Status.UpdatedReplicas, replicas)
+	}
+	return true, false, fmt.Sprintf("%d/%d replicas ready", s.Status.ReadyReplicas, replicas)
+}
+
+func jobStatus(j *batchv1.Job) (ready, failed bool, message string) {
+	for _, cond := range j.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == apiv1.ConditionTrue {
+			return false, true, "failed: " + cond.Message
+		}
+	}
+	completions := int32(1)
+	if j.Spec.Completions != nil {
+		completions = *j.Spec.Completions
+	}
+	if j.Status.Succeeded < completions {
+		return false, false, fmt.Sprintf("%d/%d completions", j.Status.Succeeded, completions)
+	}
+	return true, false, fmt.Sprintf("%d/%d completions", j.Status.Succeeded, completions)
+}
+
+// qliksenseCrStatus checks the observed generation and the Ready condition if the operator reports them,
+// otherwise the CR is ready once it exists
+func qliksenseCrStatus(u *unstructured.Unstructured) (ready, failed bool, message string) {
+	if observed, found, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration"); found && observed < u.GetGeneration() {
+		return false, false, "waiting for the operator to observe the CR"
+	}
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != "Ready" {
+			continue
+		}
+		if cond["status"] != string(apiv1.ConditionTrue) {
+			return false, false, fmt.Sprintf("not ready: %v", cond["message"])
+		}
+	}
+	return true, false, "accepted by the operator"
+}
+
+// PrintRolloutSummary prints the final status of every workload
+func PrintRolloutSummary(out io.Writer, statuses []*WorkloadStatus) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tSTATUS\tMESSAGE")
+	for _, status := range statuses {
+		if status == nil {
+			continue
+		}
+		result := "NOT READY"
+		if status.Ready {
+			result = "READY"
+		} else if status.Failed {
+			result = "FAILED"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status.Kind, status.Namespace, status.Name, result, status.Message)
+	}
+	w.Flush()
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
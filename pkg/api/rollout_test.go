@@ -0,0 +1,168 @@
+package api
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_deploymentStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		wantReady  bool
+		wantFailed bool
+	}{
+		{
+			name: "available",
+			deployment: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+				Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			},
+			wantReady: true,
+		},
+		{
+			name: "not observed",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: v1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			},
+		},
+		{
+			name: "old replicas",
+			deployment: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+				Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1},
+			},
+		},
+		{
+			name: "deadline exceeded",
+			deployment: &appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+				Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded"},
+				}},
+			},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, failed, _ := deploymentStatus(tt.deployment)
+			if ready != tt.wantReady || failed != tt.wantFailed {
+				t.Errorf("deploymentStatus() = %v, %v, want %v, %v", ready, failed, tt.wantReady, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func Test_statefulSetStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		statefulSet *appsv1.StatefulSet
+		wantReady   bool
+	}{
+		{
+			name: "ready",
+			statefulSet: &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: int32Ptr(1), UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType}},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 1, CurrentRevision: "r1", UpdateRevision: "r1"},
+			},
+			wantReady: true,
+		},
+		{
+			name: "rolling",
+			statefulSet: &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: int32Ptr(1), UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType}},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 1, CurrentRevision: "r1", UpdateRevision: "r2"},
+			},
+		},
+		{
+			name: "not ready",
+			statefulSet: &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: int32Ptr(3)},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ready, _, _ := statefulSetStatus(tt.statefulSet); ready != tt.wantReady {
+				t.Errorf("statefulSetStatus() = %v, want %v", ready, tt.wantReady)
+			}
+		})
+	}
+}
+
+func Test_jobStatus(t *testing.T) {
+	if ready, failed, _ := jobStatus(&batchv1.Job{Status: batchv1.JobStatus{Succeeded: 1}}); !ready || failed {
+		t.Errorf("expected a succeeded job to be ready")
+	}
+	failedJob := &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+		{Type: batchv1.JobFailed, Status: apiv1.ConditionTrue, Message: "BackoffLimitExceeded"},
+	}}}
+	if ready, failed, _ := jobStatus(failedJob); ready || !failed {
+		t.Errorf("expected a failed job to be failed")
+	}
+}
+
+func TestRolloutWatcher_Wait(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "engine", Namespace: "test-ns"},
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: v1.ObjectMeta{Name: "mongodb", Namespace: "test-ns"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: int32Ptr(1)},
+		},
+	)
+	cr := &unstructured.Unstructured{}
+	cr.SetAPIVersion(QliksenseGroup + "/" + QliksenseApiVersion)
+	cr.SetKind(QliksenseKind)
+	cr.SetName("qlik-test")
+	cr.SetNamespace("test-ns")
+	watcher := &RolloutWatcher{
+		Clientset: clientset,
+		Dynamic:   dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), cr),
+		Interval:  10 * time.Millisecond,
+		Out:       ioutil.Discard,
+	}
+
+	ready := []WorkloadRef{
+		{Kind: DeploymentKind, Namespace: "test-ns", Name: "engine"},
+		{Kind: QliksenseKind, Namespace: "test-ns", Name: "qlik-test"},
+	}
+	if statuses, err := watcher.Wait(ready, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !statuses[0].Ready || !statuses[1].Ready {
+		t.Fatalf("expected all workloads to be ready, got %v, %v", statuses[0], statuses[1])
+	}
+
+	notReady := append(ready, WorkloadRef{Kind: StatefulSetKind, Namespace: "test-ns", Name: "mongodb"})
+	statuses, err := watcher.Wait(notReady, 50*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if statuses[2].Ready || statuses[2].Message != "0/1 replicas ready" {
+		t.Fatalf("unexpected statefulset status: %v", statuses[2])
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	watcher.Stop = stop
+	if _, err := watcher.Wait(notReady, time.Minute); err != ErrInterrupted {
+		t.Fatalf("expected the wait to be interrupted, got %v", err)
+	}
+}
@@ -33,6 +33,15 @@ type ContextSpec struct {
 type Context struct {
 	Name   string `json:"name,omitempty" yaml:"name,omitempty"`
 	CrFile string `json:"crFile,omitempty" yaml:"crFile,omitempty"`
+	// KubeConfig, KubeContext and Namespace optionally bind the context to a cluster and namespace
+	KubeConfig  string `json:"kubeConfig,omitempty" yaml:"kubeConfig,omitempty"`
+	KubeContext string `json:"kubeContext,omitempty" yaml:"kubeContext,omitempty"`
+	Namespace   string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
+// IsBound returns true if the context is bound to a kube config, kube context or namespace
+func (c *Context) IsBound() bool {
+	return c.KubeConfig != "" || c.KubeContext != "" || c.Namespace != ""
 }
 
 // Metadata is exported
@@ -121,6 +121,11 @@ func getSvcAndKey(arg string) []string {
 }
 
 func ExecuteTaskWithBlinkingStdoutFeedback(task func() (interface{}, error), feedback string) (result interface{}, err error) {
+	return ExecuteTaskWithBlinkingFeedback(os.Stdout, task, feedback)
+}
+
+// ExecuteTaskWithBlinkingFeedback runs the task, blinking feedback on out until it is done
+func ExecuteTaskWithBlinkingFeedback(out io.Writer, task func() (interface{}, error), feedback string) (result interface{}, err error) {
 	taskDone := make(chan bool)
 	go func() {
 		result, err = task()
@@ -130,9 +135,9 @@ func ExecuteTaskWithBlinkingStdoutFeedback(task func() (interface{}, error), fee
 	progressOffTicker := time.NewTicker(1000 * time.Millisecond)
 	printProgress := func(on bool) {
 		if on {
-			fmt.Printf("%s\r", feedback)
+			fmt.Fprintf(out, "%s\r", feedback)
 		} else {
-			fmt.Printf("%s\r", strings.Repeat(" ", len(feedback)))
+			fmt.Fprintf(out, "%s\r", strings.Repeat(" ", len(feedback)))
 		}
 	}
 	for {
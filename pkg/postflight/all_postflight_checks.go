@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	. "github.com/logrusorgru/aurora"
-	ansi "github.com/mattn/go-colorable"
 	"github.com/pkg/errors"
 )
 
@@ -12,11 +11,11 @@ func (qp *QliksensePostflight) RunAllPostflightChecks(namespace string, kubeConf
 	checkCount := 0
 	totalCount := 0
 
-	out := ansi.NewColorableStdout()
+	out := qp.Out()
 	// Postflight db migration check
 	if err := qp.DbMigrationCheck(namespace, kubeConfigContents); err != nil {
 		fmt.Fprintf(out, "%s\n", Red("FAILED"))
-		fmt.Printf("Error: %v\n\n", err)
+		fmt.Fprintf(out, "Error: %v\n\n", err)
 	} else {
 		fmt.Fprintf(out, "%s\n\n", Green("PASSED"))
 		checkCount++
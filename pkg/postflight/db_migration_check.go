@@ -11,12 +11,13 @@ import (
 const initContainerNameToCheck = "migration"
 
 func (p *QliksensePostflight) DbMigrationCheck(namespace string, kubeConfigContents []byte) error {
-	fmt.Printf("Postflight db migration check... \n")
+	out := p.Out()
+	fmt.Fprintf(out, "Postflight db migration check... \n")
 	p.CG.LogVerboseMessage("\n----------------------------------- \n")
 	clientset, _, err := p.CG.GetK8SClientSet(kubeConfigContents, "")
 	if err != nil {
 		err = fmt.Errorf("unable to create a kubernetes client: %v", err)
-		fmt.Printf("%s\n", err)
+		fmt.Fprintf(out, "%s\n", err)
 		return err
 	}
 
@@ -30,7 +31,7 @@ func (p *QliksensePostflight) DbMigrationCheck(namespace string, kubeConfigConte
 	for _, deployment := range deployments.Items {
 		api.LogDebugMessage("Deployment name: %s\n", deployment.GetName())
 		if logsMap, err = p.CG.GetPodsAndPodLogsFromFailedInitContainer(clientset, deployment.Spec.Template.Labels, namespace, initContainerNameToCheck); err != nil {
-			fmt.Printf("%s\n", err)
+			fmt.Fprintf(out, "%s\n", err)
 			return err
 		}
 		p.filterLogsForErrors(logsMap, namespace)
@@ -44,7 +45,7 @@ func (p *QliksensePostflight) DbMigrationCheck(namespace string, kubeConfigConte
 	for _, statefulset := range statefulsets.Items {
 		api.LogDebugMessage("Statefulset name: %s\n", statefulset.GetName())
 		if logsMap, err = p.CG.GetPodsAndPodLogsFromFailedInitContainer(clientset, statefulset.Spec.Template.Labels, namespace, initContainerNameToCheck); err != nil {
-			fmt.Printf("%s\n", err)
+			fmt.Fprintf(out, "%s\n", err)
 			return err
 		}
 		p.filterLogsForErrors(logsMap, namespace)
@@ -54,6 +55,7 @@ func (p *QliksensePostflight) DbMigrationCheck(namespace string, kubeConfigConte
 }
 
 func (p *QliksensePostflight) filterLogsForErrors(logsMap map[string]string, namespace string) {
+	out := p.Out()
 	errorLogsPresent := false
 	for podName, podLog := range logsMap {
 		containerLogs := strings.Split(podLog, "\n")
@@ -61,14 +63,14 @@ func (p *QliksensePostflight) filterLogsForErrors(logsMap map[string]string, nam
 			for _, logLine := range containerLogs {
 				if strings.Contains(strings.ToLower(logLine), "error") {
 					errorLogsPresent = true
-					fmt.Printf("Logs from pod: %s\n%s\n", podName, logLine)
+					fmt.Fprintf(out, "Logs from pod: %s\n%s\n", podName, logLine)
 				}
 			}
 			if errorLogsPresent {
-				fmt.Printf("To view more logs in this context, please run the command: kubectl logs -n %s %s %s\n", namespace, podName, initContainerNameToCheck)
+				fmt.Fprintf(out, "To view more logs in this context, please run the command: kubectl logs -n %s %s %s\n", namespace, podName, initContainerNameToCheck)
 			}
 		} else {
-			fmt.Printf("no logs obtained\n\n")
+			fmt.Fprintf(out, "no logs obtained\n\n")
 		}
 	}
 }
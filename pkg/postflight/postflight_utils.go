@@ -1,6 +1,9 @@
 package postflight
 
 import (
+	"io"
+
+	ansi "github.com/mattn/go-colorable"
 	"github.com/qlik-oss/sense-installer/pkg/api"
 	"github.com/qlik-oss/sense-installer/pkg/qliksense"
 )
@@ -14,3 +17,11 @@ type QliksensePostflight struct {
 	P  *PostflightOptions
 	CG *api.ClientGoUtils
 }
+
+// Out returns the writer the checks print to, the output of Q if set and a colorable stdout otherwise
+func (qp *QliksensePostflight) Out() io.Writer {
+	if qp.Q != nil && qp.Q.Out != nil {
+		return qp.Q.Out
+	}
+	return ansi.NewColorableStdout()
+}
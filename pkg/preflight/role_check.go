@@ -102,20 +102,20 @@ Please check the value in the "Profile" field of your CR. `, strings.ToLower(ent
 	namespace = "" // namespace is handled when generating the manifests
 
 	// check if entity already exists in the cluster, if so - delete it
-	api.KubectlDeleteVerbose(sa, namespace, qp.P.Verbose)
+	api.KubeDeleteVerbose(sa, namespace, qp.P.Verbose)
 	if cleanup {
 		return nil
 	}
 
 	defer func() {
 		qp.CG.LogVerboseMessage("Cleaning up resources...\n")
-		err := api.KubectlDeleteVerbose(sa, namespace, qp.P.Verbose)
+		err := api.KubeDeleteVerbose(sa, namespace, qp.P.Verbose)
 		if err != nil {
 			qp.CG.LogVerboseMessage("Preflight cleanup failed!\n")
 		}
 	}()
 
-	err = api.KubectlApplyVerbose(sa, namespace, qp.P.Verbose)
+	err = api.KubeApplyVerbose(sa, namespace, qp.P.Verbose)
 	if err != nil {
 		err := fmt.Errorf("Failed to create entity on the cluster: %v", err)
 		return err
@@ -2,6 +2,7 @@ package qliksense
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -74,7 +75,7 @@ func (q *Qliksense) About(gitRef, profile string) (*VersionOutput, error) {
 func (q *Qliksense) AboutDir(configDirectory, profile string) (*VersionOutput, error) {
 	if chartVersion, err := getChartVersion(filepath.Join(configDirectory, "manifests", "base", "transformers", "release", "annotations.yaml"), "app.kubernetes.io/version"); err != nil {
 		return nil, err
-	} else if kuzManifest, err := executeKustomizeBuildWithStdoutProgress(filepath.Join(configDirectory, "manifests", profile)); err != nil {
+	} else if kuzManifest, err := executeKustomizeBuildWithProgress(q.out(), filepath.Join(configDirectory, "manifests", profile)); err != nil {
 		return nil, err
 	} else if images, err := getImageList(kuzManifest); err != nil {
 		return nil, err
@@ -184,8 +185,7 @@ func getImageList(yamlContent []byte) ([]string, error) {
 			break
 		}
 		traverseYamlDecodedMapRecursively(reflect.ValueOf(resource), []string{}, func(path []string, val interface{}) {
-			if len(path) >= 2 && path[len(path)-1] == "image" &&
-				(path[len(path)-2] == "containers" || path[len(path)-2] == "initContainers") {
+			if isContainerImagePath(path) {
 				if image, ok := val.(string); ok {
 					imageMap[image] = true
 				}
@@ -202,6 +202,69 @@ func getImageList(yamlContent []byte) ([]string, error) {
 	return sortedImageList, nil
 }
 
+// isContainerImagePath returns true for the path of the image of a container or an init container
+func isContainerImagePath(path []string) bool {
+	return len(path) >= 2 && path[len(path)-1] == "image" &&
+		(path[len(path)-2] == "containers" || path[len(path)-2] == "initContainers")
+}
+
+// replaceImages replaces the images of the containers and init containers of the resources with their new image
+// of newImages, the resources are written in the same order
+func replaceImages(yamlContent []byte, newImages map[string]string) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(yamlContent))
+	var resources [][]byte
+	for {
+		var resource interface{}
+		err := decoder.Decode(&resource)
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			break
+		} else if resource == nil {
+			continue
+		}
+		resource = replaceYamlDecodedValuesRecursively(resource, []string{}, func(path []string, val interface{}) interface{} {
+			if image, ok := val.(string); ok && isContainerImagePath(path) {
+				if newImage, ok := newImages[image]; ok {
+					return newImage
+				}
+			}
+			return val
+		})
+		b, err := yaml.Marshal(resource)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, b)
+	}
+	return bytes.Join(resources, []byte("---\n")), nil
+}
+
+// replaceYamlDecodedValuesRecursively returns val with every scalar replaced with what replaceFunc returns for it
+func replaceYamlDecodedValuesRecursively(val interface{}, path []string, replaceFunc func(path []string, val interface{}) interface{}) interface{} {
+	switch v := val.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = replaceYamlDecodedValuesRecursively(v[i], path, replaceFunc)
+		}
+		return v
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			v[key] = replaceYamlDecodedValuesRecursively(value, append(path, fmt.Sprint(key)), replaceFunc)
+		}
+		return v
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = replaceYamlDecodedValuesRecursively(value, append(path, key), replaceFunc)
+		}
+		return v
+	case nil:
+		return v
+	}
+	return replaceFunc(path, val)
+}
+
 func traverseYamlDecodedMapRecursively(val reflect.Value, path []string, visitorFunc func(path []string, val interface{})) {
 	kind := val.Kind()
 	switch kind {
@@ -7,6 +7,7 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	qapi "github.com/qlik-oss/sense-installer/pkg/api"
@@ -231,6 +232,50 @@ spec:
 	}
 }
 
+func Test_replaceImages(t *testing.T) {
+	manifests := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: engine
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.31
+      containers:
+      - name: engine
+        image: qlik/engine:1.0
+        env:
+        - name: image
+          value: qlik/engine:1.0
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: images
+data:
+  image: qlik/engine:1.0
+`
+	replaced, err := replaceImages([]byte(manifests), map[string]string{
+		"busybox:1.31":    "busybox@sha256:1111",
+		"qlik/engine:1.0": "qlik/engine@sha256:2222",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if images, err := getImageList(replaced); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !reflect.DeepEqual(images, []string{"busybox@sha256:1111", "qlik/engine@sha256:2222"}) {
+		t.Errorf("expected the images to be replaced, got %v", images)
+	}
+	if count := strings.Count(string(replaced), "qlik/engine:1.0"); count != 2 {
+		t.Errorf("expected the values other than images to be kept, got %d of them in:\n%s", count, replaced)
+	} else if count := strings.Count(string(replaced), "---\n"); count != 1 {
+		t.Errorf("expected 2 resources, got:\n%s", replaced)
+	}
+}
+
 func Test_About_getConfigDirectory(t *testing.T) {
 	verifyAsdBranch := func(configDir string) (ok bool, reason string) {
 		tmpDir := os.TempDir()
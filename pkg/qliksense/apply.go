@@ -4,5 +4,5 @@ func (q *Qliksense) ApplyCRFromBytes(crBytes []byte, opts *InstallCommandOptions
 	if err := q.LoadCr(crBytes, overwriteExistingContext); err != nil {
 		return err
 	}
-	return q.InstallQK8s("", opts)
+	return q.installQK8s("", opts, "apply")
 }
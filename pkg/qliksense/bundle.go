@@ -0,0 +1,248 @@
+package qliksense
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/qlik-oss/k-apis/pkg/config"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+	"gopkg.in/yaml.v2"
+)
+
+// layout of a bundle
+const (
+	bundleMetadataFileName = "bundle.yaml"
+	bundleRepoDirName      = "repo"
+	bundleCrdsFileName     = "crds.yaml"
+)
+
+type BundleCommandOptions struct {
+	Profile string
+	Out     string
+	Images  ImageCommandOptions
+}
+
+// BundleMetadata describes what a bundle was created from
+type BundleMetadata struct {
+	Version string   `yaml:"version"`
+	Profile string   `yaml:"profile"`
+	Images  []string `yaml:"images"`
+	Created string   `yaml:"created"`
+}
+
+// bundle is an extracted and verified bundle
+type bundle struct {
+	dir      string
+	metadata *BundleMetadata
+	out      io.Writer
+}
+
+// CreateBundle packs everything an install of the version needs into a single archive for a disconnected site:
+// the manifests repo at the version, the images of the profile, the operator and preflight images in the
+// OCI image store pullImage writes, the CRDs and a checksum of every file
+func (q *Qliksense) CreateBundle(version string, opts *BundleCommandOptions) error {
+	profile := opts.Profile
+	if profile == "" {
+		profile = defaultProfile
+	}
+	out := opts.Out
+	if out == "" {
+		out = fmt.Sprintf("qliksense-%s-%s.tar.gz", version, profile)
+	}
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		fmt.Fprintln(q.out(), "cannot get the current-context cr", err)
+		return err
+	}
+	encKey, err := qConfig.GetEncryptionKeyFor(qcr.GetName())
+	if err != nil {
+		return err
+	}
+
+	bundleDir, err := ioutil.TempDir("", "qliksense-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(bundleDir)
+
+	fmt.Fprintf(q.out(), "fetching version [%s] from %s\n", version, qcr.GetFetchUrl())
+	repoDir := filepath.Join(bundleDir, bundleRepoDirName)
+	tempRepoDir, err := fetchToTempDir(qcr.GetFetchUrl(), version, qcr.GetFetchAccessToken(encKey))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(filepath.Dir(tempRepoDir))
+	if err := qapi.CopyDirectory(tempRepoDir, repoDir); err != nil {
+		return err
+	}
+
+	versionOut, err := q.AboutDir(repoDir, profile)
+	if err != nil {
+		return err
+	}
+	images := versionOut.Images
+	if err := q.appendAdditionalImages(&images, qcr); err != nil {
+		return err
+	}
+	imagesDir, err := setupImagesDir(bundleDir)
+	if err != nil {
+		return err
+	}
+	platform, err := opts.Images.imagePlatform()
+	if err != nil {
+		return err
+	}
+	registries, err := getImageRegistries(q.out(), qConfig)
+	if err != nil {
+		return err
+	}
+	defer registries.close()
+	if err := transferImages(q.out(), q.interrupted, "pulled", "qliksense bundle create", images, opts.Images.parallel(), func(image string, out io.Writer) (bool, error) {
+		return pullImage(image, imagesDir, platform, registries, out)
+	}); err != nil {
+		return err
+	}
+	if err := q.writeVersionOutput(versionOut, imagesDir, version); err != nil {
+		return err
+	}
+
+	bundleCr := &qapi.QliksenseCR{}
+	bundleCr.Spec = &config.CRSpec{ManifestsRoot: repoDir, Profile: profile}
+	crds, err := getQliksenseInitCrds(bundleCr)
+	if err != nil {
+		return err
+	}
+	if customCrds, err := getCustomCrds(bundleCr); err != nil {
+		return err
+	} else if customCrds != "" {
+		crds = crds + "\n---\n" + customCrds
+	}
+	crds = crds + "\n---\n" + q.GetOperatorCRDString()
+	if err := ioutil.WriteFile(filepath.Join(bundleDir, bundleCrdsFileName), []byte(crds), 0644); err != nil {
+		return err
+	}
+
+	metadata := &BundleMetadata{
+		Version: version,
+		Profile: profile,
+		Images:  images,
+		Created: time.Now().UTC().Format(time.RFC3339),
+	}
+	if b, err := yaml.Marshal(metadata); err != nil {
+		return err
+	} else if err := ioutil.WriteFile(filepath.Join(bundleDir, bundleMetadataFileName), b, 0644); err != nil {
+		return err
+	}
+
+	if err := qapi.WriteChecksums(bundleDir); err != nil {
+		return err
+	}
+	fmt.Fprintf(q.out(), "Writing bundle %s\n", out)
+	return qapi.ArchiveDirectory(bundleDir, out)
+}
+
+// openBundle extracts the bundle into a temporary directory in qliksenseHome and verifies the checksums of its files,
+// its content is then moved into place without another copy. The progress of the bundle is written to out.
+func openBundle(out io.Writer, bundleFile, qliksenseHome string) (*bundle, error) {
+	dir, err := ioutil.TempDir(qliksenseHome, ".bundle-")
+	if err != nil {
+		return nil, err
+	}
+	b := &bundle{dir: dir, out: out}
+	fmt.Fprintf(out, "Extracting bundle %s\n", bundleFile)
+	if written, err := qapi.ExtractArchive(bundleFile, dir); err != nil {
+		b.close()
+		return nil, err
+	} else if err := qapi.VerifyExtractedChecksums(dir, written); err != nil {
+		b.close()
+		return nil, fmt.Errorf("bundle %s is corrupted: %w", bundleFile, err)
+	}
+	metadata := &BundleMetadata{}
+	if content, err := ioutil.ReadFile(filepath.Join(dir, bundleMetadataFileName)); err != nil {
+		b.close()
+		return nil, err
+	} else if err := yaml.Unmarshal(content, metadata); err != nil {
+		b.close()
+		return nil, err
+	}
+	b.metadata = metadata
+	return b, nil
+}
+
+func (b *bundle) close() {
+	os.RemoveAll(b.dir)
+}
+
+// importRepo moves the manifests repo of the bundle where fetch puts it and points the current CR to it
+func (b *bundle) importRepo(qConfig *qapi.QliksenseConfig) error {
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		return err
+	}
+	version := b.metadata.Version
+	fmt.Fprintf(b.out, "fetching version [%s] from the bundle\n", version)
+	if err := qapi.MoveDirectory(filepath.Join(b.dir, bundleRepoDirName), qConfig.BuildRepoPath(version)); err != nil {
+		return err
+	}
+	qcr.Spec.ManifestsRoot = qConfig.BuildCurrentManifestsRoot(version)
+	qcr.Spec.Profile = b.metadata.Profile
+	qcr.AddLabelToCr("version", version)
+	return qConfig.WriteCurrentContextCR(qcr)
+}
+
+// importImages moves the images of the bundle into the image store in qliksenseHome, push takes them from there.
+// An image of the store is replaced by the image of the bundle, blobs already in the store are kept.
+func (b *bundle) importImages(qliksenseHome string) error {
+	imagesDir, err := setupImagesDir(qliksenseHome)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b.out, "Loading %d images from the bundle\n", len(b.metadata.Images))
+	bundleImagesDir := filepath.Join(b.dir, imagesDirName)
+	bundleIndexDir := filepath.Join(bundleImagesDir, imageIndexDirName)
+	if err := filepath.Walk(bundleIndexDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		// an image is a directory of the index with an OCI index
+		if _, err := os.Stat(filepath.Join(path, ociIndexFileName)); os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(bundleIndexDir, path)
+		if err != nil {
+			return err
+		} else if err := qapi.MoveDirectory(path, filepath.Join(imagesDir, imageIndexDirName, rel)); err != nil {
+			return err
+		}
+		return filepath.SkipDir
+	}); err != nil {
+		return err
+	}
+	bundleBlobsDir := filepath.Join(bundleImagesDir, imageSharedBlobsDirName)
+	return filepath.Walk(bundleBlobsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(bundleBlobsDir, path)
+		if err != nil {
+			return err
+		}
+		// blobs are named by their digest, a blob already in the store has the same content
+		blob := filepath.Join(imagesDir, imageSharedBlobsDirName, rel)
+		if _, err := os.Stat(blob); err == nil {
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		} else if err := os.MkdirAll(filepath.Dir(blob), os.ModePerm); err != nil {
+			return err
+		}
+		return os.Rename(path, blob)
+	})
+}
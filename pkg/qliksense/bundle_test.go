@@ -0,0 +1,106 @@
+package qliksense
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_openBundle(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	bundleDir := filepath.Join(tmp, "bundle")
+	if err := os.MkdirAll(filepath.Join(bundleDir, bundleRepoDirName), os.ModePerm); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(filepath.Join(bundleDir, bundleMetadataFileName), []byte("version: v1.0.0\nprofile: docker-desktop\nimages:\n- qlik/engine:1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(filepath.Join(bundleDir, bundleCrdsFileName), []byte("kind: CustomResourceDefinition\n"), 0644); err != nil {
+		t.Fatal(err)
+	} else if err := qapi.WriteChecksums(bundleDir); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(tmp, "bundle.tar.gz")
+	if err := qapi.ArchiveDirectory(bundleDir, archive); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := openBundle(ioutil.Discard, archive, tmp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer b.close()
+	if b.metadata.Version != "v1.0.0" || b.metadata.Profile != "docker-desktop" || len(b.metadata.Images) != 1 {
+		t.Errorf("unexpected metadata %+v", b.metadata)
+	}
+
+	// a bundle changed after it was created is refused
+	if err := ioutil.WriteFile(filepath.Join(bundleDir, bundleCrdsFileName), []byte("kind: Secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	} else if err := qapi.ArchiveDirectory(bundleDir, archive); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := openBundle(ioutil.Discard, archive, tmp); err == nil {
+		b.close()
+		t.Error("expected a corrupted bundle to be refused")
+	}
+}
+
+func Test_bundle_importImages(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	writeFiles := func(files map[string]string) {
+		for name, content := range files {
+			if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+				t.Fatal(err)
+			} else if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	home := filepath.Join(tmp, "home")
+	storeDir := filepath.Join(home, imagesDirName)
+	bundleDir := filepath.Join(home, ".bundle-test")
+	writeFiles(map[string]string{
+		filepath.Join(storeDir, imageIndexDirName, "engine", "1.0", ociIndexFileName):                    "stale",
+		filepath.Join(storeDir, imageIndexDirName, "engine", "1.0", "stale"):                             "stale",
+		filepath.Join(storeDir, imageSharedBlobsDirName, "sha256", "shared"):                             "shared",
+		filepath.Join(bundleDir, imagesDirName, imageIndexDirName, "engine", "1.0", ociIndexFileName):    "engine",
+		filepath.Join(bundleDir, imagesDirName, imageIndexDirName, "edge-auth", "2.0", ociIndexFileName): "edge-auth",
+		filepath.Join(bundleDir, imagesDirName, imageSharedBlobsDirName, "sha256", "shared"):             "shared",
+		filepath.Join(bundleDir, imagesDirName, imageSharedBlobsDirName, "sha256", "layer"):              "layer",
+	})
+
+	b := &bundle{dir: bundleDir, metadata: &BundleMetadata{Images: []string{"qlik/engine:1.0", "qlik/edge-auth:2.0"}}, out: ioutil.Discard}
+	if err := b.importImages(home); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, want := range map[string]string{
+		filepath.Join(imageIndexDirName, "engine", "1.0", ociIndexFileName):    "engine",
+		filepath.Join(imageIndexDirName, "edge-auth", "2.0", ociIndexFileName): "edge-auth",
+		filepath.Join(imageSharedBlobsDirName, "sha256", "shared"):             "shared",
+		filepath.Join(imageSharedBlobsDirName, "sha256", "layer"):              "layer",
+	} {
+		if content, err := ioutil.ReadFile(filepath.Join(storeDir, name)); err != nil || string(content) != want {
+			t.Errorf("expected %s to be %q, got %q, %v", name, want, content, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(storeDir, imageIndexDirName, "engine", "1.0", "stale")); !os.IsNotExist(err) {
+		t.Errorf("expected the image of the store to be replaced, got %v", err)
+	}
+	// moved, not copied
+	if _, err := os.Stat(filepath.Join(bundleDir, imagesDirName, imageIndexDirName, "engine", "1.0")); !os.IsNotExist(err) {
+		t.Errorf("expected the image to be moved out of the bundle, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(bundleDir, imagesDirName, imageSharedBlobsDirName, "sha256", "layer")); !os.IsNotExist(err) {
+		t.Errorf("expected the blob to be moved out of the bundle, got %v", err)
+	}
+}
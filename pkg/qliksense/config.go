@@ -5,13 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/qlik-oss/k-apis/pkg/config"
 
-	"github.com/mitchellh/go-homedir"
 	"gopkg.in/yaml.v2"
 
 	"github.com/qlik-oss/k-apis/pkg/cr"
@@ -34,7 +32,7 @@ func (q *Qliksense) ConfigApplyQK8s() error {
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCurrentCR()
 	if err != nil {
-		fmt.Println("cannot get the current-context cr", err)
+		fmt.Fprintln(q.out(), "cannot get the current-context cr", err)
 		return err
 	}
 	// check if acceptEULA is yes or not
@@ -43,7 +41,7 @@ func (q *Qliksense) ConfigApplyQK8s() error {
 	}
 
 	// create patch dependent resources
-	fmt.Println("Installing resources used by the kuztomize patch")
+	fmt.Fprintln(q.out(), "Installing resources used by the kuztomize patch")
 	if err := q.createK8sResourceBeforePatch(qcr); err != nil {
 		return err
 	}
@@ -76,35 +74,136 @@ func (q *Qliksense) configEjson() error {
 }
 
 func (q *Qliksense) applyConfigToK8s(qcr *qapi.QliksenseCR) error {
-	if err := q.configEjson(); err != nil {
+	mByte, err := q.generateManifests(qcr, config.KeysActionRestoreOrRotate)
+	if err != nil {
 		return err
 	}
-
-	userHomeDir, err := homedir.Dir()
+	fmt.Fprintln(q.out(), "Applying manifests to the cluster")
+	// keep the objects stamped for the latest revision, no new revision is recorded
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	revision := 0
+	if rev, err := qConfig.GetLatestRevision(qcr.GetName()); err != nil {
+		return err
+	} else if rev != nil {
+		revision = rev.Number
+	}
+	engine, err := q.newContextApplyEngine(qcr.GetName(), revision)
 	if err != nil {
-		fmt.Printf(`error fetching user's home directory: %v\n`, err)
 		return err
 	}
-	qcr.SetNamespace(qapi.GetKubectlNamespace())
+	if err := engine.Apply(string(mByte), qcr.GetNamespace()); err != nil {
+		return err
+	}
+	return recordInventory(qConfig, engine, qcr.GetName(), qapi.InventoryManifests, string(mByte), qcr.GetNamespace())
+}
+
+// generatePatches generates the patches for the CR with the selected kube config, the keys are
+// backed up to and restored from the cluster
+func (q *Qliksense) generatePatches(qcr *qapi.QliksenseCR, keysAction config.KeysAction) error {
+	kubeConfigPath, cleanup, err := qapi.WriteKubeConfigToTempFile()
+	if err != nil && keysAction != config.KeysActionDoNothing {
+		fmt.Fprintln(q.out(), "cannot load kube config", err)
+		return err
+	} else if err != nil {
+		// the cluster is not used without a keys action
+		qapi.LogDebugMessage("cannot load kube config: %v\n", err)
+	} else {
+		defer cleanup()
+	}
+	cr.GeneratePatches(&qcr.KApiCr, keysAction, kubeConfigPath)
+	return nil
+}
+
+// generateManifests generates the patches for the decrypted CR and builds the manifests of its profile
+func (q *Qliksense) generateManifests(qcr *qapi.QliksenseCR, keysAction config.KeysAction) ([]byte, error) {
+	if err := q.configEjson(); err != nil {
+		return nil, err
+	}
+
+	namespace, err := qapi.GetKubeNamespace()
+	if err != nil {
+		fmt.Fprintf(q.out(), "WARNING: %v, the manifests are generated without a namespace\n", err)
+	}
+	qcr.SetNamespace(namespace)
+	// the cr holds decrypted secrets, only show it for debugging
 	b, _ := yaml.Marshal(qcr.KApiCr)
-	fmt.Printf("%v", string(b))
-	// os.Exit(0)
+	qapi.LogDebugMessage("%v", string(b))
 	// generate patches
-	cr.GeneratePatches(&qcr.KApiCr, config.KeysActionRestoreOrRotate, path.Join(userHomeDir, ".kube", "config"))
+	if err := q.generatePatches(qcr, keysAction); err != nil {
+		return nil, err
+	}
 	// apply generated manifests
 	profilePath := filepath.Join(qcr.Spec.GetManifestsRoot(), qcr.Spec.GetProfileDir())
-	fmt.Printf("Generating manifests for profile: %v\n", profilePath)
+	fmt.Fprintf(q.out(), "Generating manifests for profile: %v\n", profilePath)
 	mByte, err := ExecuteKustomizeBuild(profilePath)
 	if err != nil {
-		fmt.Printf("error generating manifests: %v\n", err)
-		return err
+		fmt.Fprintf(q.out(), "error generating manifests: %v\n", err)
+		return nil, err
 	}
-	fmt.Println("Applying manifests to the cluster")
-	if err = qapi.KubectlApply(string(mByte), qcr.GetNamespace()); err != nil {
-		return err
+	return q.mapManifestImages(qcr, mByte)
+}
+
+// mapManifestImages renames the images of the manifests with the image registry mapping of the current context.
+// The manifests flatten images into the image registry of the CR themselves, an image they flattened is mapped
+// as the image of the CR it was flattened from. Images the mapping would tell apart but the manifests flattened,
+// or named, alike are an error.
+func (q *Qliksense) mapManifestImages(qcr *qapi.QliksenseCR, manifests []byte) ([]byte, error) {
+	registry := qcr.Spec.GetImageRegistry()
+	if registry == "" {
+		return manifests, nil
+	}
+	mapping, err := qapi.NewQConfig(q.QliksenseHome).GetImageRegistryMapping()
+	if err != nil || mapping.IsFlatten() {
+		return manifests, err
+	}
+	imagesDir, err := setupImagesDir(q.QliksenseHome)
+	if err != nil {
+		return nil, err
+	}
+	sourceImages, err := q.getImagesOfCurrentCR(qcr, imagesDir)
+	if err != nil {
+		return nil, err
+	}
+	flattened := map[string]string{}
+	for _, image := range sourceImages {
+		target := getTargetImage(image, registry, nil)
+		if previous, ok := flattened[target]; ok && previous != image && mapping.TargetImage(previous, registry) != mapping.TargetImage(image, registry) {
+			return nil, fmt.Errorf("images %s and %s are both flattened to %s, add a rule to the image registry mapping for one of them", previous, image, target)
+		}
+		flattened[target] = image
+	}
+	images, err := getImageList(manifests)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	// the images are renamed by name, every tag of a name has to map to the same repository
+	mapped := map[string]string{}
+	newNames := map[string]string{}
+	for _, image := range images {
+		source := image
+		if flattenedFrom, ok := flattened[image]; ok {
+			source = flattenedFrom
+		}
+		name, _ := qapi.SplitImageReference(image)
+		sourceName, _ := qapi.SplitImageReference(source)
+		newName := mapping.TargetRepository(sourceName, registry)
+		if previous, ok := mapped[name]; ok && previous != newName {
+			return nil, fmt.Errorf("images %s map to both %s and %s, add a rule to the image registry mapping for one of them", name, previous, newName)
+		}
+		mapped[name] = newName
+		if newName != name {
+			newNames[name] = newName
+		}
+	}
+	if len(newNames) == 0 {
+		return manifests, nil
+	}
+	renamed, err := kustomizeForImageRegistry(string(manifests), "", newNames)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(renamed), nil
 }
 
 func (q *Qliksense) ConfigViewCR() error {
@@ -130,12 +229,12 @@ func (q *Qliksense) getCRString(contextName string) (string, error) {
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCR(contextName)
 	if err != nil {
-		fmt.Println("cannot get the context cr", err)
+		fmt.Fprintln(q.out(), "cannot get the context cr", err)
 		return "", err
 	}
 	out, err := qapi.K8sToYaml(qcr)
 	if err != nil {
-		fmt.Println("cannot unmarshal cr ", err)
+		fmt.Fprintln(q.out(), "cannot unmarshal cr ", err)
 		return "", err
 	}
 	return string(out), nil
@@ -146,7 +245,7 @@ func (q *Qliksense) getCurrentCrDependentResourceAsString() (string, error) {
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCR(qConfig.Spec.CurrentContext)
 	if err != nil {
-		fmt.Println("cannot get the context cr", err)
+		fmt.Fprintln(q.out(), "cannot get the context cr", err)
 		return "", err
 	}
 	var crString strings.Builder
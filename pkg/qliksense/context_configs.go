@@ -284,12 +284,16 @@ func (q *Qliksense) processSetOpsRunner(arg string, cr *api.QliksenseCR) error {
 }
 
 // SetContextConfig - set the context for qliksense kubernetes resources to live in
-func (q *Qliksense) SetContextConfig(args []string) error {
+// and optionally binds it to a kube config, kube context and namespace
+func (q *Qliksense) SetContextConfig(args []string, opts *ContextBindingOptions) error {
 	if len(args) == 1 {
 		err := q.SetUpQliksenseContext(args[0])
 		if err != nil {
 			return err
 		}
+		if opts.isSet() {
+			return q.bindContext(args[0], opts)
+		}
 	} else {
 		err := fmt.Errorf("Please provide a name to configure the context with")
 		log.Println(err)
@@ -307,11 +311,11 @@ func (q *Qliksense) ListContextConfigs() error {
 	}
 	out := ansi.NewColorableStdout()
 	w := tabwriter.NewWriter(out, 5, 8, 0, '\t', 0)
-	fmt.Fprintln(w, Underline("Context Name"), "\t", Underline("CR File Location"))
+	fmt.Fprintln(w, Underline("Context Name"), "\t", Underline("CR File Location"), "\t", Underline("Kube Context"), "\t", Underline("Namespace"))
 	w.Flush()
 	if len(qliksenseConfig.Spec.Contexts) > 0 {
 		for _, cont := range qliksenseConfig.Spec.Contexts {
-			fmt.Fprintln(w, cont.Name, "\t", qliksenseConfig.GetCRFilePath(cont.Name), "\t")
+			fmt.Fprintln(w, cont.Name, "\t", qliksenseConfig.GetCRFilePath(cont.Name), "\t", cont.KubeContext, "\t", cont.Namespace, "\t")
 		}
 		w.Flush()
 		fmt.Fprintln(out, "")
@@ -516,13 +520,31 @@ func readTargetfile(targetFile string) ([]byte, error) {
 	return k8sSecret, nil
 }
 
-func (q *Qliksense) SetImageRegistry(registry, pushUsername, pushPassword, pullUsername, pullPassword string) error {
+// SetImageRegistry sets the image registry of the current context with the credentials and the TLS settings
+// images are pushed and pulled with. Without TLS settings the certificate of the registry is verified with the system CAs.
+func (q *Qliksense) SetImageRegistry(registry, pushUsername, pushPassword, pullUsername, pullPassword string, fromDockerConfig bool, tls *api.RegistryTLS) error {
 	qConfig := api.NewQConfig(q.QliksenseHome)
 	qliksenseCR, err := qConfig.GetCurrentCR()
 	if err != nil {
 		return err
 	}
-	if pushUsername != "" {
+	if err := qConfig.SetRegistryTLS(registry, tls); err != nil {
+		return err
+	}
+	if fromDockerConfig {
+		// push and the pull secret read the credentials from the docker config when they need them, as tokens
+		// of credential helpers expire. They are checked once here.
+		if dockerConfigJsonSecret, err := api.GetDockerConfigJsonSecretFromDockerConfig(registry); err != nil {
+			return err
+		} else if dockerConfigJsonSecret == nil {
+			return fmt.Errorf("docker has no credentials for %s, log in with docker login first", registry)
+		}
+		if err := qConfig.DeletePushDockerConfigJsonSecret(); err != nil && !os.IsNotExist(err) {
+			return err
+		} else if err := qConfig.DeletePullDockerConfigJsonSecret(); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if pushUsername != "" {
 		if err := qConfig.SetPushDockerConfigJsonSecret(&api.DockerConfigJsonSecret{
 			Uri:      registry,
 			Username: pushUsername,
@@ -543,11 +565,49 @@ func (q *Qliksense) SetImageRegistry(registry, pushUsername, pushPassword, pullU
 	} else if err := qConfig.DeletePullDockerConfigJsonSecret(); err != nil && !os.IsNotExist(err) {
 		return err
 	}
+	if err := qConfig.SetPullSecretFromDockerConfig(fromDockerConfig); err != nil {
+		return err
+	}
 
 	qliksenseCR.Spec.AddToConfigs("qliksense", imageRegistryConfigKey, registry)
 	return qConfig.WriteCR(qliksenseCR)
 }
 
+// SetImageRegistryMapping sets how the repositories of images are mapped to repositories of the image registry
+// of the current context, rules are given as source=target. The flatten strategy without rules removes the mapping.
+func (q *Qliksense) SetImageRegistryMapping(strategy, prefix string, rules []string) error {
+	mapping := &api.ImageRegistryMapping{Strategy: strategy, Prefix: prefix}
+	for _, rule := range rules {
+		sourceTarget := strings.SplitN(rule, "=", 2)
+		if len(sourceTarget) != 2 {
+			return fmt.Errorf("invalid rule %s, use source=target", rule)
+		}
+		mapping.Rules = append(mapping.Rules, api.ImageMappingRule{Source: sourceTarget[0], Target: sourceTarget[1]})
+	}
+	if err := mapping.Validate(); err != nil {
+		return err
+	}
+	qConfig := api.NewQConfig(q.QliksenseHome)
+	if mapping.IsFlatten() {
+		return qConfig.SetImageRegistryMapping(nil)
+	}
+	return qConfig.SetImageRegistryMapping(mapping)
+}
+
+// SetImageMirrors sets the mirrors images are pulled from their source registries through in the current context,
+// given as source=mirror. No mirrors remove them.
+func (q *Qliksense) SetImageMirrors(mirrors []string) error {
+	var rules api.ImageMirrors
+	for _, mirror := range mirrors {
+		sourceMirror := strings.SplitN(mirror, "=", 2)
+		if len(sourceMirror) != 2 {
+			return fmt.Errorf("invalid mirror %s, use source=mirror", mirror)
+		}
+		rules = append(rules, api.ImageMirrorRule{Source: sourceMirror[0], Mirror: sourceMirror[1]})
+	}
+	return api.NewQConfig(q.QliksenseHome).SetImageMirrors(rules)
+}
+
 func (q *Qliksense) SetEulaAccepted() error {
 	qConfig := api.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCurrentCR()
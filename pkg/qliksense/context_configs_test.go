@@ -359,6 +359,7 @@ spec:
 		pushPassword       string
 		pullUsername       string
 		pullPassword       string
+		tls                *api.RegistryTLS
 		expectSecretsExist bool
 	}{
 		{
@@ -379,6 +380,12 @@ spec:
 			pullPassword:       "bar-pull",
 			expectSecretsExist: true,
 		},
+		{
+			name:               "insecure",
+			registry:           "https://foobar:5000",
+			tls:                &api.RegistryTLS{Insecure: true},
+			expectSecretsExist: false,
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -394,11 +401,18 @@ spec:
 			}
 
 			if err := q.SetImageRegistry(testCase.registry, testCase.pushUsername, testCase.pushPassword,
-				testCase.pullUsername, testCase.pullPassword); err != nil {
+				testCase.pullUsername, testCase.pullPassword, false, testCase.tls); err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
 			qConfig := api.NewQConfig(q.QliksenseHome)
+			if registriesTLS, err := qConfig.GetRegistriesTLS(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if tls := registriesTLS["foobar:5000"]; testCase.tls != nil && (tls == nil || *tls != *testCase.tls) {
+				t.Fatalf("expected TLS settings %v, got %v", testCase.tls, registriesTLS)
+			} else if testCase.tls == nil && len(registriesTLS) != 0 {
+				t.Fatalf("unexpected TLS settings %v", registriesTLS)
+			}
 			if testCase.expectSecretsExist {
 				if pushSecret, err := qConfig.GetPushDockerConfigJsonSecret(); err != nil {
 					t.Fatalf("unexpected error: %v", err)
@@ -423,6 +437,66 @@ spec:
 		})
 	}
 }
+func TestSetImageRegistry_dockerConfig(t *testing.T) {
+	tmpQlikSenseHome, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpQlikSenseHome)
+	dockerConfigDir := filepath.Join(tmpQlikSenseHome, "docker")
+	if err := os.MkdirAll(dockerConfigDir, os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Unsetenv("DOCKER_CONFIG")
+	os.Setenv("DOCKER_CONFIG", dockerConfigDir)
+	login := func(username, password string) {
+		config := fmt.Sprintf(`{"auths": {"foobar:5000": {"username": "%s", "password": "%s"}}}`, username, password)
+		if err := ioutil.WriteFile(filepath.Join(dockerConfigDir, "config.json"), []byte(config), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	setupQliksenseTestDefaultContext(t, tmpQlikSenseHome, `
+apiVersion: qlik.com/v1
+kind: Qliksense
+metadata:
+  name: qlik-default
+spec:
+  profile: docker-desktop
+`)
+	q := &Qliksense{QliksenseHome: tmpQlikSenseHome}
+	login("foo", "token-1")
+	if err := q.SetImageRegistry("foobar:5000", "", "", "", "", true, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	qConfig := api.NewQConfig(q.QliksenseHome)
+	if _, err := qConfig.GetPullDockerConfigJsonSecret(); err == nil {
+		t.Fatal("expected the credentials of docker not to be stored in the pull secret")
+	}
+
+	// the pull secret gets the credentials docker has when it is applied
+	login("foo", "token-2")
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pullSecret, err := getPullDockerConfigJsonSecret(qConfig, qcr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if pullSecret == nil || pullSecret.Name != pullSecretName || pullSecret.Uri != "foobar:5000" ||
+		pullSecret.Username != "foo" || pullSecret.Password != "token-2" || pullSecret.Email != "" {
+		t.Fatalf("unexpected pull secret content: %v", pullSecret)
+	}
+
+	if err := q.SetImageRegistry("foobar:5000", "", "", "", "", false, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pullSecret, err := getPullDockerConfigJsonSecret(qConfig, qcr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if pullSecret != nil {
+		t.Fatalf("unexpected pull secret without --docker-config: %v", pullSecret)
+	}
+}
+
 func removePrivateKey() {
 	err := os.Remove(filepath.Join(testDir, secrets, contexts, qlikDefaultContext, secrets, "user_secret_key"))
 	if err != nil {
@@ -5,12 +5,10 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/mitchellh/go-homedir"
 	qapi "github.com/qlik-oss/sense-installer/pkg/api"
 	apixv1beta1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/kustomize/api/k8sdeps/kunstruct"
 	"sigs.k8s.io/kustomize/api/resmap"
 	"sigs.k8s.io/kustomize/api/resource"
@@ -55,26 +53,26 @@ func (q *Qliksense) InstallCrds(opts *CrdCommandOptions) error {
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCurrentCR()
 	if err != nil {
-		fmt.Println("cannot get the current-context cr", err)
+		fmt.Fprintln(q.out(), "cannot get the current-context cr", err)
 		return err
 	}
 
 	if engineCRD, err := getQliksenseInitCrds(qcr); err != nil {
 		return err
-	} else if err = qapi.KubectlApply(engineCRD, ""); err != nil {
+	} else if err = q.applyAndRecord(qConfig, qcr.GetName(), qapi.InventoryCRDs, engineCRD, ""); err != nil {
 		return err
 	}
 	if customCrd, err := getCustomCrds(qcr); err != nil {
 		return err
 	} else if customCrd != "" {
-		if err = qapi.KubectlApply(customCrd, ""); err != nil {
+		if err = q.applyAndRecord(qConfig, qcr.GetName(), qapi.InventoryCRDs, customCrd, ""); err != nil {
 			return err
 		}
 	}
 
 	if opts.All { // install opeartor crd
-		if err := qapi.KubectlApply(q.GetOperatorCRDString(), ""); err != nil {
-			fmt.Println("cannot do kubectl apply on opeartor CRD", err)
+		if err := q.applyAndRecord(qConfig, qcr.GetName(), qapi.InventoryCRDs, q.GetOperatorCRDString(), ""); err != nil {
+			fmt.Fprintln(q.out(), "cannot apply opeartor CRD", err)
 			return err
 		}
 	}
@@ -179,12 +177,7 @@ func checkCrdsInstalled(crds string, customResourceDefinitionInterface apixv1bet
 }
 
 func getCustomResourceDefinitionInterface() (apixv1beta1client.CustomResourceDefinitionInterface, error) {
-	homeDir, err := homedir.Dir()
-	if err != nil {
-		return nil, err
-	}
-	kubeconfigPath := filepath.Join(homeDir, ".kube", "config")
-	k8sRestConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	k8sRestConfig, err := qapi.GetKubeRestConfig()
 	if err != nil {
 		return nil, err
 	}
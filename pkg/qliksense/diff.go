@@ -0,0 +1,125 @@
+package qliksense
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jinzhu/copier"
+	"github.com/qlik-oss/k-apis/pkg/config"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+const (
+	diffOutputText = "text"
+	diffOutputJson = "json"
+)
+
+type DiffCommandOptions struct {
+	Output string
+}
+
+// DiffQK8s renders the manifests of the current context, or of the version if given, and prints how they differ
+// from the live cluster. Resources of the latest revision that are no longer rendered are reported as removed.
+func (q *Qliksense) DiffQK8s(version string, opts *DiffCommandOptions) error {
+	if opts.Output != diffOutputText && opts.Output != diffOutputJson {
+		return fmt.Errorf("unsupported output %s, use %s or %s", opts.Output, diffOutputText, diffOutputJson)
+	}
+	document := q.out()
+	if opts.Output == diffOutputJson {
+		// keep the output for the json document
+		defer q.withOut(os.Stderr)()
+	}
+	manifestsDiff, err := q.getManifestsDiff(version)
+	if manifestsDiff == nil {
+		return err
+	}
+
+	if opts.Output == diffOutputJson {
+		enc := json.NewEncoder(document)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(manifestsDiff); encErr != nil {
+			return encErr
+		}
+	} else {
+		printManifestsDiff(document, manifestsDiff)
+	}
+	return err
+}
+
+func (q *Qliksense) getManifestsDiff(version string) (*qapi.ManifestsDiff, error) {
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		fmt.Fprintln(q.out(), "cannot get the current-context cr", err)
+		return nil, err
+	}
+	if version != "" && version != qcr.GetLabelFromCr("version") {
+		originalCr := &qapi.QliksenseCR{}
+		copier.Copy(originalCr, qcr)
+		// render the version, but leave the context on the version it was before
+		defer qConfig.WriteCurrentContextCR(originalCr)
+		if err := q.switchCurrentCRToVersion(qConfig, version); err != nil {
+			return nil, err
+		} else if qcr, err = qConfig.GetCurrentCR(); err != nil {
+			return nil, err
+		}
+	}
+	if !qcr.IsRepoExist() {
+		return nil, errors.New("no manifests found for the current context, please provide a version")
+	}
+
+	dcr, err := qConfig.GetDecryptedCr(qcr)
+	if err != nil {
+		return nil, err
+	}
+	mByte, err := q.generateManifests(dcr, config.KeysActionDoNothing)
+	if err != nil {
+		return nil, err
+	}
+	previousManifests, err := getLatestRevisionManifests(qConfig, qcr.GetName())
+	if err != nil {
+		return nil, err
+	}
+	revision, err := qConfig.NextRevisionNumber(qcr.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	// stamp the objects as an apply would, so that the owner label does not show up as a change
+	engine, err := q.newContextApplyEngine(qcr.GetName(), revision)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(q.out(), "Comparing manifests with the cluster")
+	return engine.Diff(string(mByte), string(previousManifests), dcr.GetNamespace())
+}
+
+func printManifestsDiff(out io.Writer, manifestsDiff *qapi.ManifestsDiff) {
+	for _, group := range []struct {
+		title  string
+		change string
+	}{
+		{"Added resources", qapi.ChangeAdded},
+		{"Changed resources", qapi.ChangeChanged},
+		{"Removed resources", qapi.ChangeRemoved},
+	} {
+		objs := manifestsDiff.ObjectsWithChange(group.change)
+		if len(objs) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "%s:\n", group.title)
+		for _, objDiff := range objs {
+			fmt.Fprintf(out, "  %s %s/%s (+%d -%d)\n", objDiff.Kind, objDiff.Namespace, objDiff.Name, objDiff.LinesAdded, objDiff.LinesRemoved)
+		}
+		fmt.Fprintln(out)
+		for _, objDiff := range objs {
+			fmt.Fprintln(out, objDiff.Diff)
+		}
+	}
+	s := manifestsDiff.Summary
+	fmt.Fprintf(out, "%d added, %d changed, %d removed, %d unchanged (+%d -%d lines)\n",
+		s.Added, s.Changed, s.Removed, s.Unchanged, s.LinesAdded, s.LinesRemoved)
+}
@@ -3,12 +3,16 @@ package qliksense
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/manifest"
 	"github.com/containers/image/v5/signature"
 	"github.com/containers/image/v5/transports/alltransports"
 	imageTypes "github.com/containers/image/v5/types"
@@ -26,16 +30,18 @@ const (
 	imagesDirName           = "images"
 	imageIndexDirName       = "index"
 	imageSharedBlobsDirName = "blobs"
+	// imageSourceDigestFileName holds the digest of the manifest an image in the image store was pulled from
+	imageSourceDigestFileName = "source-digest"
 )
 
-func (q *Qliksense) PullImages(version, profile string) error {
+func (q *Qliksense) PullImages(version, profile string, opts *ImageCommandOptions) error {
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCurrentCR()
 	if err != nil {
 		return err
 	}
 	if !qcr.IsRepoExist() {
-		if err := fetchAndUpdateCR(qConfig, version); err != nil {
+		if err := fetchAndUpdateCR(q.out(), qConfig, version); err != nil {
 			return err
 		}
 	}
@@ -45,11 +51,16 @@ func (q *Qliksense) PullImages(version, profile string) error {
 			return err
 		}
 	}
-	return q.PullImagesForCurrentCR()
+	return q.PullImagesForCurrentCR(opts)
 }
 
-// PullImages ...
-func (q *Qliksense) PullImagesForCurrentCR() error {
+// PullImagesForCurrentCR pulls the images of the current CR into the image store, opts.Parallel images at the same time,
+// for the platform of opts
+func (q *Qliksense) PullImagesForCurrentCR(opts *ImageCommandOptions) error {
+	platform, err := opts.imagePlatform()
+	if err != nil {
+		return err
+	}
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCurrentCR()
 	if err != nil {
@@ -74,12 +85,15 @@ func (q *Qliksense) PullImagesForCurrentCR() error {
 		return err
 	}
 
-	for _, image := range images {
-		if err := pullImage(image, imagesDir); err != nil {
-			fmt.Printf("%v\n", err)
-			return err
-		}
-		fmt.Print("---\n")
+	registries, err := getImageRegistries(q.out(), qConfig)
+	if err != nil {
+		return err
+	}
+	defer registries.close()
+	if err := transferImages(q.out(), q.interrupted, "pulled", "qliksense pull", images, opts.parallel(), func(image string, out io.Writer) (bool, error) {
+		return pullImage(image, imagesDir, platform, registries, out)
+	}); err != nil {
+		return err
 	}
 
 	if version != "" && !stored {
@@ -112,45 +126,137 @@ func (q *Qliksense) appendOperatorImages(images *[]string) error {
 	}
 }
 
-func pullImage(image, imagesDir string) error {
-	srcRef, err := alltransports.ParseImageName(fmt.Sprintf("docker://%v", image))
+// pullImage copies the image for the platform into the image store, unless the image store already has the manifest
+// the image currently points to for the platform. It returns true if the image was already present.
+func pullImage(image, imagesDir string, platform *imagePlatform, registries *imageRegistries, out io.Writer) (bool, error) {
+	srcRef, err := alltransports.ParseImageName(fmt.Sprintf("docker://%v", registries.sourceImage(image)))
 	if err != nil {
-		return err
+		return false, err
 	}
 	nameTag := getImageNameParts(image)
-	targetDir := filepath.Join(imagesDir, imageIndexDirName, nameTag.name, nameTag.tag)
+	targetDir := nameTag.storeDir(imagesDir)
 	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
-		return err
+		return false, err
 	}
 
 	destRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", targetDir))
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	policyContext, err := signature.NewPolicyContext(&signature.Policy{Default: []signature.PolicyRequirement{signature.NewPRInsecureAcceptAnything()}})
+	sourceCtx := registries.systemContext(srcRef, platform.systemContext(&imageTypes.SystemContext{}))
+	destinationCtx := imageStoreContext(imagesDir)
+	sourceDigest, err := getManifestDigest(srcRef, sourceCtx)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if present, err := isImagePulled(targetDir, destRef, destinationCtx, sourceDigest, platform); err != nil {
+		return false, err
+	} else if present {
+		// the policy may have changed since the image was pulled
+		if registries.signaturePolicy() != nil {
+			if err := verifyImage(image, imagesDir, registries, out); err != nil {
+				return false, err
+			}
+		}
+		fmt.Fprintf(out, "==> Image %v is already present with digest %v\n", srcRef.StringWithinTransport(), sourceDigest)
+		return true, nil
+	}
+	// copy the manifest of the digest, in case the tag moves in the meantime
+	digestRef, err := docker.ParseReference(fmt.Sprintf("//%v@%v", reference.TrimNamed(srcRef.DockerReference()), sourceDigest))
+	if err != nil {
+		return false, err
+	}
+	policyRef, err := registries.policyReference(image, digestRef)
+	if err != nil {
+		return false, err
 	}
-	defer policyContext.Destroy()
 
-	fmt.Printf("==> Pulling image from %v\n", srcRef.StringWithinTransport())
-	if _, err := copy.Image(context.Background(), policyContext, destRef, srcRef, &copy.Options{
-		ReportWriter: os.Stdout,
-		SourceCtx: &imageTypes.SystemContext{
-			ArchitectureChoice: "amd64",
-			OSChoice:           "linux",
-		},
-		DestinationCtx: &imageTypes.SystemContext{
-			OCISharedBlobDirPath: filepath.Join(imagesDir, imageSharedBlobsDirName),
-		},
-	}); err != nil {
-		return err
+	// the index of the image store keeps a single manifest per name and tag, the image is copied into a layout of its
+	// own sharing the blobs of the image store, whose index replaces the one of the image once the copy succeeded
+	pullDir, err := ioutil.TempDir(imagesDir, ".pull-")
+	if err != nil {
+		return false, err
 	}
-	return nil
+	defer os.RemoveAll(pullDir)
+	pullRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", pullDir))
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprintf(out, "==> Pulling image for %v from %v\n", platform, srcRef.StringWithinTransport())
+	if err := copyImage(pullRef, policyRef, sourceCtx, destinationCtx, registries.signaturePolicy(), platform.listSelection(), out); err != nil {
+		var policyErr signature.PolicyRequirementError
+		if errors.As(err, &policyErr) {
+			return false, fmt.Errorf("image %v does not satisfy the signature policy: %w", image, err)
+		}
+		return false, err
+	}
+	for _, name := range []string{ociLayoutFileName, ociIndexFileName} {
+		if err := os.Rename(filepath.Join(pullDir, name), filepath.Join(targetDir, name)); err != nil {
+			return false, err
+		}
+	}
+	if err := writeImagePlatform(targetDir, platform); err != nil {
+		return false, err
+	}
+	return false, ioutil.WriteFile(filepath.Join(targetDir, imageSourceDigestFileName), []byte(sourceDigest), 0644)
+}
+
+// isImagePulled returns true if the image in targetDir was pulled from the manifest with sourceDigest for the platform
+// and its manifest is still in the image store
+func isImagePulled(targetDir string, ref imageTypes.ImageReference, sys *imageTypes.SystemContext, sourceDigest string, platform *imagePlatform) (bool, error) {
+	pulledDigest, err := readImageSourceDigest(targetDir)
+	if err != nil {
+		return false, err
+	} else if pulledDigest != sourceDigest {
+		return false, nil
+	}
+	if pulledPlatform, err := readImagePlatform(targetDir); err != nil || pulledPlatform.String() != platform.String() {
+		return false, nil
+	}
+	if _, err := getManifestDigest(ref, sys); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// readImageSourceDigest returns the digest the image in targetDir was pulled from, empty if unknown
+func readImageSourceDigest(targetDir string) (string, error) {
+	pulledDigest, err := ioutil.ReadFile(filepath.Join(targetDir, imageSourceDigestFileName))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(pulledDigest)), nil
 }
 
-func (q *Qliksense) PushImagesForCurrentCR() error {
+// getManifestDigest returns the digest of the manifest of the image, only the manifest is read
+func getManifestDigest(ref imageTypes.ImageReference, sys *imageTypes.SystemContext) (string, error) {
+	ctx := context.Background()
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	manifestBytes, _, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	d, err := manifest.Digest(manifestBytes)
+	if err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
+
+// PushImagesForCurrentCR pushes the images of the current CR from the image store to the image registry of the CR,
+// opts.Parallel images at the same time. Images missing in the image store are pulled first for the platform of opts.
+func (q *Qliksense) PushImagesForCurrentCR(opts *ImageCommandOptions) error {
+	platform, err := opts.imagePlatform()
+	if err != nil {
+		return err
+	}
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCurrentCR()
 	if err != nil {
@@ -189,12 +295,15 @@ func (q *Qliksense) PushImagesForCurrentCR() error {
 		return err
 	}
 
-	for _, image := range images {
-		if err = pushImage(image, imagesDir, dockerConfigJsonSecret); err != nil {
-			fmt.Printf("%v\n", err)
-			return err
-		}
-		fmt.Print("---\n")
+	registries, err := getImageRegistries(q.out(), qConfig)
+	if err != nil {
+		return err
+	}
+	defer registries.close()
+	if err := transferImages(q.out(), q.interrupted, "pushed", "qliksense push", images, opts.parallel(), func(image string, out io.Writer) (bool, error) {
+		return pushImage(image, imagesDir, platform, dockerConfigJsonSecret, registries, out)
+	}); err != nil {
+		return err
 	}
 
 	if version != "" && !stored {
@@ -215,53 +324,94 @@ func (q *Qliksense) appendAdditionalImages(images *[]string, qcr *qapi.Qliksense
 	return nil
 }
 
-func pushImage(image, imagesDir string, dockerConfigJsonSecret *qapi.DockerConfigJsonSecret) error {
-	imageNameParts := getImageNameParts(image)
-	srcDir := filepath.Join(imagesDir, imageIndexDirName, imageNameParts.name, imageNameParts.tag)
-	if exists, err := directoryExists(srcDir); err != nil {
-		return err
-	} else if !exists {
-		if err := pullImage(image, imagesDir); err != nil {
-			return err
-		}
-	}
-	srcRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", srcDir))
-	if err != nil {
-		return err
-	}
-
-	newImage := fmt.Sprintf("%v/%v:%v", dockerConfigJsonSecret.Uri, imageNameParts.name, imageNameParts.tag)
-	destRef, err := alltransports.ParseImageName(fmt.Sprintf("docker://%v", newImage))
+// pushImage copies the image from the image store to the registry of dockerConfigJsonSecret, unless the registry
+// already has the same manifest. An image missing in the image store is pulled for the platform first.
+// It returns true if the image was already present.
+func pushImage(image, imagesDir string, platform *imagePlatform, dockerConfigJsonSecret *qapi.DockerConfigJsonSecret,
+	registries *imageRegistries, out io.Writer) (bool, error) {
+	srcRef, err := storedImageReference(image, imagesDir, platform, registries, out)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	policyContext, err := signature.NewPolicyContext(&signature.Policy{Default: []signature.PolicyRequirement{signature.NewPRInsecureAcceptAnything()}})
+	destRef, err := alltransports.ParseImageName(fmt.Sprintf("docker://%v", registries.targetImage(image, dockerConfigJsonSecret.Uri)))
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer policyContext.Destroy()
 
-	destinationCtx := &imageTypes.SystemContext{
-		DockerInsecureSkipTLSVerify: imageTypes.OptionalBoolTrue,
-	}
+	sourceCtx := imageStoreContext(imagesDir)
+	destinationCtx := registries.systemContext(destRef, &imageTypes.SystemContext{})
 	if dockerConfigJsonSecret.Username != "" {
 		destinationCtx.DockerAuthConfig = &imageTypes.DockerAuthConfig{
 			Username: dockerConfigJsonSecret.Username,
 			Password: dockerConfigJsonSecret.Password,
 		}
 	}
-	fmt.Printf("==> Pushing image to: %v\n", destRef.StringWithinTransport())
-	if _, err = copy.Image(context.Background(), policyContext, destRef, srcRef, &copy.Options{
-		ReportWriter: os.Stdout,
-		SourceCtx: &imageTypes.SystemContext{
-			OCISharedBlobDirPath: filepath.Join(imagesDir, imageSharedBlobsDirName),
-		},
-		DestinationCtx: destinationCtx,
-	}); err != nil {
+
+	srcDigest, err := getManifestDigest(srcRef, sourceCtx)
+	if err != nil {
+		return false, err
+	}
+	// a missing image or any other error reading the destination digest means the image has to be pushed
+	if destDigest, err := getRegistryManifestDigest(destRef, destinationCtx); err == nil && destDigest == srcDigest {
+		fmt.Fprintf(out, "==> Image %v is already present with digest %v\n", destRef.StringWithinTransport(), destDigest)
+		return true, nil
+	}
+
+	fmt.Fprintf(out, "==> Pushing image to: %v\n", destRef.StringWithinTransport())
+	// a manifest list in the image store is pushed with all of its platforms
+	if err := copyImage(destRef, srcRef, sourceCtx, destinationCtx, nil, copy.CopyAllImages, out); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// storedImageReference returns the reference of the image in the image store, the image is pulled for the platform if missing
+func storedImageReference(image, imagesDir string, platform *imagePlatform, registries *imageRegistries, out io.Writer) (imageTypes.ImageReference, error) {
+	nameTag := getImageNameParts(image)
+	srcDir := nameTag.storeDir(imagesDir)
+	if exists, err := directoryExists(srcDir); err != nil {
+		return nil, err
+	} else if !exists {
+		if _, err := pullImage(image, imagesDir, platform, registries, out); err != nil {
+			return nil, err
+		}
+	}
+	return alltransports.ParseImageName(fmt.Sprintf("oci:%v", srcDir))
+}
+
+// imageStoreContext reads and writes the blobs of the images in the image store from its shared blobs directory
+func imageStoreContext(imagesDir string) *imageTypes.SystemContext {
+	return &imageTypes.SystemContext{
+		OCISharedBlobDirPath: filepath.Join(imagesDir, imageSharedBlobsDirName),
+	}
+}
+
+// copyImage copies the image of srcRef, or the images of its manifest list selected by images, to destRef
+// if it satisfies the signature policy, a nil policy accepts any image
+func copyImage(destRef, srcRef imageTypes.ImageReference, sourceCtx, destinationCtx *imageTypes.SystemContext, policy *signature.Policy,
+	images copy.ImageListSelection, out io.Writer) error {
+	policyContext, err := newPolicyContext(policy)
+	if err != nil {
 		return err
 	}
-	return nil
+	defer policyContext.Destroy()
+	_, err = copy.Image(context.Background(), policyContext, destRef, srcRef, &copy.Options{
+		ReportWriter:       out,
+		SourceCtx:          sourceCtx,
+		DestinationCtx:     destinationCtx,
+		ImageListSelection: images,
+		// the signatures are verified against the policy, the OCI image store cannot keep them
+		RemoveSignatures: true,
+	})
+	return err
+}
+
+func newPolicyContext(policy *signature.Policy) (*signature.PolicyContext, error) {
+	if policy == nil {
+		policy = &signature.Policy{Default: []signature.PolicyRequirement{signature.NewPRInsecureAcceptAnything()}}
+	}
+	return signature.NewPolicyContext(policy)
 }
 
 func directoryExists(path string) (exists bool, err error) {
@@ -279,7 +429,19 @@ func directoryExists(path string) (exists bool, err error) {
 	return exists, err
 }
 
+// getImageNameParts returns the repository of the image, with its registry as docker normalizes it, and its tag or
+// digest. The image store keeps an image in the directory of both, see storeDir, so that images of the same name from
+// different registries or organizations do not collide.
 func getImageNameParts(image string) imageNameParts {
+	if named, err := reference.ParseNormalizedNamed(image); err == nil {
+		nameTag := imageNameParts{name: named.Name(), tag: "latest"}
+		if tagged, ok := named.(reference.Tagged); ok {
+			nameTag.tag = tagged.Tag()
+		} else if digested, ok := named.(reference.Digested); ok {
+			nameTag.tag = digested.Digest().String()
+		}
+		return nameTag
+	}
 	segments := strings.Split(image, "/")
 	nameTag := strings.Split(segments[len(segments)-1], ":")
 	if len(nameTag) < 2 {
@@ -291,6 +453,25 @@ func getImageNameParts(image string) imageNameParts {
 	}
 }
 
+// storeDir returns the directory of the image in the index of the image store in imagesDir,
+// index/<registry>/<repository>/<tag>. Colons, of a registry port or a digest, are replaced as windows does not
+// allow them in file names.
+func (p imageNameParts) storeDir(imagesDir string) string {
+	return filepath.Join(imagesDir, imageIndexDirName, filepath.FromSlash(strings.ReplaceAll(p.name, ":", "_")), strings.ReplaceAll(p.tag, ":", "_"))
+}
+
+// getTargetImage returns where push puts the image in the registry, the repository is mapped with mapping,
+// flattened if nil
+func getTargetImage(image, registry string, mapping *qapi.ImageRegistryMapping) string {
+	if registry == "" {
+		return image
+	}
+	if _, suffix := qapi.SplitImageReference(image); suffix == "" {
+		image = image + ":latest"
+	}
+	return mapping.TargetImage(image, registry)
+}
+
 func setupImagesDir(qliksenseHome string) (string, error) {
 	imagesDir := filepath.Join(qliksenseHome, imagesDirName)
 
@@ -343,9 +524,9 @@ func (q *Qliksense) writeVersionOutput(versionOut *VersionOutput, imagesDir, ver
 	return nil
 }
 
-func validatePullPushFlagsOnInstall(cr *qapi.QliksenseCR, pull, push bool) error {
+func validatePullPushFlagsOnInstall(out io.Writer, cr *qapi.QliksenseCR, pull, push bool) error {
 	if pull && !push {
-		fmt.Printf("WARNING: pulling images without pushing them\n")
+		fmt.Fprintf(out, "WARNING: pulling images without pushing them\n")
 	}
 	if push {
 		return ensureImageRegistrySetInCR(cr)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -14,6 +15,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path"
@@ -138,7 +140,7 @@ func Test_Pull_Push_ImagesForCurrentCR(t *testing.T) {
 			}
 			var versionOut VersionOutput
 
-			if err := q.PullImagesForCurrentCR(); err != nil {
+			if err := q.PullImagesForCurrentCR(nil); err != nil {
 				t.Fatalf("unexpected pull error: %v", err)
 			} else if versionOutBytes, err := ioutil.ReadFile(path.Join(tmpQlikSenseHome, "images", "foo")); err != nil {
 				t.Fatalf("unexpected error reading version file: %v", err)
@@ -153,7 +155,7 @@ func Test_Pull_Push_ImagesForCurrentCR(t *testing.T) {
 			}
 
 			if testCase.expectPushSuccess {
-				if err := q.PushImagesForCurrentCR(); err != nil {
+				if err := q.PushImagesForCurrentCR(nil); err != nil {
 					t.Fatalf("unexpected push error: %v", err)
 				} else if tmpImagesDir, err := ioutil.TempDir("", "tmp-images-"); err != nil {
 					t.Fatalf("unexpected error creating tmp dir: %v", err)
@@ -165,7 +167,7 @@ func Test_Pull_Push_ImagesForCurrentCR(t *testing.T) {
 					t.Fatal("expected blobs/sha256 directory to be non-empty")
 				}
 			} else {
-				if err := q.PushImagesForCurrentCR(); err == nil {
+				if err := q.PushImagesForCurrentCR(nil); err == nil {
 					t.Fatal("unexpected push success")
 				}
 			}
@@ -272,6 +274,11 @@ spec:
 `, version, registry.url, manifestsRootDir)
 	setupQliksenseTestDefaultContext(t, tmpQlikSenseHome, cr)
 
+	// the certificate of the test registry is self-signed for localhost
+	if err := api.NewQConfig(tmpQlikSenseHome).SetRegistryTLS(registry.url, &api.RegistryTLS{Insecure: true}); err != nil {
+		return err
+	}
+
 	if clientAuth == clientAuthProvided || clientAuth == clientAuthProvidedButIncorrect {
 		if registry.username == "" || clientAuth == clientAuthProvidedButIncorrect {
 			registry.username = "bad"
@@ -575,7 +582,7 @@ func testPullImage(image, imagesDir string, registry *testRegistryV2) error {
 		return err
 	}
 	nameTag := getImageNameParts(image)
-	targetDir := filepath.Join(imagesDir, imageIndexDirName, nameTag.name, nameTag.tag)
+	targetDir := nameTag.storeDir(imagesDir)
 	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
 		return err
 	}
@@ -614,3 +621,213 @@ func testPullImage(image, imagesDir string, registry *testRegistryV2) error {
 	}
 	return nil
 }
+
+func Test_isImagePulled(t *testing.T) {
+	imagesDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(imagesDir)
+	if imagesDir, err = setupImagesDir(imagesDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	targetDir := filepath.Join(imagesDir, imageIndexDirName, "engine", "1.0")
+	sharedBlobsDir := filepath.Join(imagesDir, imageSharedBlobsDirName)
+	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	manifest := `{"schemaVersion":2,"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a","size":2},"layers":[]}`
+	manifestDigest := fmt.Sprintf("%x", sha256.Sum256([]byte(manifest)))
+	if err := os.MkdirAll(filepath.Join(sharedBlobsDir, "sha256"), os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ioutil.WriteFile(filepath.Join(sharedBlobsDir, "sha256", manifestDigest), []byte(manifest), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ioutil.WriteFile(filepath.Join(targetDir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index := fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:%s","size":%d}]}`, manifestDigest, len(manifest))
+	if err := ioutil.WriteFile(filepath.Join(targetDir, "index.json"), []byte(index), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ref, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", targetDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sys := &imageTypes.SystemContext{OCISharedBlobDirPath: sharedBlobsDir}
+	if localDigest, err := getManifestDigest(ref, sys); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if localDigest != "sha256:"+manifestDigest {
+		t.Fatalf("expected digest sha256:%s, got %s", manifestDigest, localDigest)
+	}
+
+	sourceDigest := "sha256:9e4a8b32f2d7e0f0b5c8f1a26a6bb2a4c0b4e0d36f9c1a1e8b0a2d0a3c3f9b1c"
+	if pulled, err := isImagePulled(targetDir, ref, sys, sourceDigest, defaultImagePlatform); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if pulled {
+		t.Fatal("expected an image without source digest not to be pulled")
+	}
+	if err := ioutil.WriteFile(filepath.Join(targetDir, imageSourceDigestFileName), []byte(sourceDigest), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pulled, err := isImagePulled(targetDir, ref, sys, sourceDigest, defaultImagePlatform); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !pulled {
+		t.Fatal("expected the image to be pulled")
+	}
+	if pulled, err := isImagePulled(targetDir, ref, sys, "sha256:0000000000000000000000000000000000000000000000000000000000000000", defaultImagePlatform); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if pulled {
+		t.Fatal("expected an image pulled from another digest not to be pulled")
+	}
+	if pulled, err := isImagePulled(targetDir, ref, sys, sourceDigest, &imagePlatform{os: "linux", arch: "arm64"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if pulled {
+		t.Fatal("expected an image pulled for another platform not to be pulled")
+	}
+	if err := os.Remove(filepath.Join(sharedBlobsDir, "sha256", manifestDigest)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pulled, err := isImagePulled(targetDir, ref, sys, sourceDigest, defaultImagePlatform); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if pulled {
+		t.Fatal("expected an image with a missing manifest not to be pulled")
+	}
+}
+
+// testImageServer is a registry serving the OCI images it is given over plain http, registries reach it as insecure
+type testImageServer struct {
+	*httptest.Server
+	manifests map[string][]byte
+	blobs     map[string][]byte
+}
+
+func newTestImageServer(t *testing.T) *testImageServer {
+	s := &testImageServer{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+		if r.URL.Path == "/v2/" {
+			return
+		}
+		if i := strings.LastIndex(r.URL.Path, "/manifests/"); i > 0 {
+			manifest, ok := s.manifests[r.URL.Path[len("/v2/"):i]+":"+r.URL.Path[i+len("/manifests/"):]]
+			if !ok {
+				manifest, ok = s.blobs[r.URL.Path[i+len("/manifests/"):]]
+			}
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)))
+			w.Header().Set("Content-Length", fmt.Sprint(len(manifest)))
+			if r.Method != http.MethodHead {
+				w.Write(manifest)
+			}
+			return
+		}
+		if i := strings.LastIndex(r.URL.Path, "/blobs/"); i > 0 {
+			if blob, ok := s.blobs[r.URL.Path[i+len("/blobs/"):]]; ok {
+				w.Header().Set("Content-Length", fmt.Sprint(len(blob)))
+				if r.Method != http.MethodHead {
+					w.Write(blob)
+				}
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	return s
+}
+
+// addImage serves an image with a layer holding the file name as repository:tag and returns its reference
+// and the digest of its manifest
+func (s *testImageServer) addImage(t *testing.T, repository, tag, name string) (string, string) {
+	manifest, blobs := testImageBlobs(t, name)
+	for _, blob := range append(blobs, manifest) {
+		s.blobs[fmt.Sprintf("sha256:%x", sha256.Sum256(blob))] = blob
+	}
+	s.manifests[repository+":"+tag] = manifest
+	return fmt.Sprintf("%s/%s:%s", s.host(), repository, tag), fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+}
+
+func (s *testImageServer) host() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func Test_pullImage_sameNameOfDifferentOrganizations(t *testing.T) {
+	server := newTestImageServer(t)
+	defer server.Close()
+	orgAImage, orgADigest := server.addImage(t, "orga/engine", "1.0", "orga")
+	orgBImage, orgBDigest := server.addImage(t, "orgb/engine", "1.0", "orgb")
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registries, err := newImageRegistries(ioutil.Discard, api.RegistriesTLS{server.host(): {Insecure: true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer registries.close()
+
+	for _, image := range []string{orgAImage, orgBImage} {
+		if _, err := pullImage(image, imagesDir, defaultImagePlatform, registries, ioutil.Discard); err != nil {
+			t.Fatalf("unexpected error pulling %s: %v", image, err)
+		}
+	}
+	if getImageNameParts(orgAImage).storeDir(imagesDir) == getImageNameParts(orgBImage).storeDir(imagesDir) {
+		t.Fatal("expected images of different organizations in different directories of the image store")
+	}
+	for image, digest := range map[string]string{orgAImage: orgADigest, orgBImage: orgBDigest} {
+		ref, err := storedImageReference(image, imagesDir, defaultImagePlatform, registries, ioutil.Discard)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if storedDigest, err := getManifestDigest(ref, imageStoreContext(imagesDir)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		} else if storedDigest != digest {
+			t.Errorf("expected %s to be stored with digest %s, got %s", image, digest, storedDigest)
+		}
+	}
+}
+
+func Test_storedImageReference_pullsForThePlatform(t *testing.T) {
+	server := newTestImageServer(t)
+	defer server.Close()
+	image, _ := server.addImage(t, "qlik/engine", "1.0", "engine")
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registries, err := newImageRegistries(ioutil.Discard, api.RegistriesTLS{server.host(): {Insecure: true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer registries.close()
+
+	platform, err := parseImagePlatform("linux/arm64", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := storedImageReference(image, imagesDir, platform, registries, ioutil.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pulledPlatform, err := readImagePlatform(getImageNameParts(image).storeDir(imagesDir)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if pulledPlatform.String() != platform.String() {
+		t.Errorf("expected the missing image to be pulled for %v, got %v", platform, pulledPlatform)
+	}
+}
@@ -3,6 +3,7 @@ package qliksense
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -20,6 +21,8 @@ type FetchCommandOptions struct {
 	Version     string
 	SecretName  string
 	Overwrite   bool
+	// Reporter receives the progress event of the fetch, may be nil
+	Reporter Reporter
 }
 
 const (
@@ -28,7 +31,7 @@ const (
 
 func (q *Qliksense) FetchQK8s(version string) error {
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
-	return fetchAndUpdateCR(qConfig, version)
+	return fetchAndUpdateCR(q.out(), qConfig, version)
 }
 
 func (q *Qliksense) FetchK8sWithOpts(opts *FetchCommandOptions) error {
@@ -54,7 +57,7 @@ func (q *Qliksense) FetchK8sWithOpts(opts *FetchCommandOptions) error {
 	}
 	v := getVersion(opts, cr)
 	if v != "" && qConfig.IsRepoExistForCurrent(v) {
-		if opts.Overwrite || getVerionsOverwriteConfirmation(v) == "y" {
+		if opts.Overwrite || getVerionsOverwriteConfirmation(q.out(), v) == "y" {
 			if err := qConfig.DeleteRepoForCurrent(v); err != nil {
 				return err
 			}
@@ -64,14 +67,16 @@ func (q *Qliksense) FetchK8sWithOpts(opts *FetchCommandOptions) error {
 		}
 	}
 	qConfig.WriteCR(cr)
-	return fetchAndUpdateCR(qConfig, v)
+	return RunPhase(opts.Reporter, PhaseFetch, func() error {
+		return fetchAndUpdateCR(q.out(), qConfig, v)
+	})
 }
 
 // fetchAndUpdateCR fetch
-func fetchAndUpdateCR(qConfig *qapi.QliksenseConfig, version string) error {
+func fetchAndUpdateCR(out io.Writer, qConfig *qapi.QliksenseConfig, version string) error {
 	qcr, err := qConfig.GetCurrentCR()
 	if err != nil {
-		fmt.Println("cannot get the current-context cr", err)
+		fmt.Fprintln(out, "cannot get the current-context cr", err)
 		return err
 	}
 	if version == "" {
@@ -95,7 +100,7 @@ func fetchAndUpdateCR(qConfig *qapi.QliksenseConfig, version string) error {
 		return err
 	}
 	destDir := qConfig.BuildRepoPath(version)
-	fmt.Printf("fetching version [%s] from %s\n", version, qcr.GetFetchUrl())
+	fmt.Fprintf(out, "fetching version [%s] from %s\n", version, qcr.GetFetchUrl())
 	if err := qapi.CopyDirectory(tempDest, destDir); err != nil {
 		return nil
 	}
@@ -135,12 +140,12 @@ func getVersion(opts *FetchCommandOptions, qcr *qapi.QliksenseCR) string {
 	return opts.Version
 }
 
-func getVerionsOverwriteConfirmation(version string) string {
+func getVerionsOverwriteConfirmation(out io.Writer, version string) string {
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Println("The version  [" + version + "] already exists")
+	fmt.Fprintln(out, "The version  ["+version+"] already exists")
 	cfm := "n"
 	for {
-		fmt.Print("Do you want to delete and fetch again [y/N]: ")
+		fmt.Fprint(out, "Do you want to delete and fetch again [y/N]: ")
 		cfm, _ = reader.ReadString('\n')
 		cfm = strings.Replace(cfm, "\n", "", -1)
 		cfm = strings.TrimSpace(cfm)
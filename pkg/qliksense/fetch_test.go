@@ -16,7 +16,7 @@ func TestFetchAndUpdateCR(t *testing.T) {
 	}
 	q.SetUpQliksenseContext("test1")
 	qConfig := qapi.NewQConfig(tempHome)
-	if err := fetchAndUpdateCR(qConfig, "v0.0.8"); err != nil {
+	if err := fetchAndUpdateCR(ioutil.Discard, qConfig, "v0.0.8"); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
@@ -35,7 +35,7 @@ func TestFetchAndUpdateCR(t *testing.T) {
 	//testing latest tag is fetched
 	cr.AddLabelToCr("version", "")
 	qConfig.WriteCR(cr)
-	err := fetchAndUpdateCR(qConfig, "")
+	err := fetchAndUpdateCR(ioutil.Discard, qConfig, "")
 	if err != nil {
 		t.Log(err)
 		t.Fail()
@@ -0,0 +1,131 @@
+package qliksense
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/qlik-oss/sense-installer/pkg"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+type RollbackCommandOptions struct {
+	DryRun      bool
+	ToRevision  int
+	ForceUnlock bool
+}
+
+// saveRevision records the decrypted CR (with its secrets encrypted again) and the applied manifests
+// as a new revision of the context
+func (q *Qliksense) saveRevision(qConfig *qapi.QliksenseConfig, dcr *qapi.QliksenseCR, manifests []byte, action string) error {
+	ecr, err := qConfig.GetEncryptedCr(dcr)
+	if err != nil {
+		return err
+	}
+	rev, err := qConfig.SaveRevision(dcr.GetName(), &qapi.Revision{
+		Action:     action,
+		Version:    dcr.GetLabelFromCr("version"),
+		Namespace:  dcr.GetNamespace(),
+		CliVersion: pkg.Version,
+	}, manifests, ecr)
+	if err != nil {
+		fmt.Fprintln(q.out(), "cannot record the revision", err)
+		return err
+	}
+	fmt.Fprintf(q.out(), "Recorded revision %d for context %s\n", rev.Number, dcr.GetName())
+	return nil
+}
+
+// PrintHistory lists the revisions recorded for the current context
+func (q *Qliksense) PrintHistory() error {
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	revisions, err := qConfig.ListRevisions(qConfig.Spec.CurrentContext)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		fmt.Fprintln(q.out(), "No revisions recorded for context "+qConfig.Spec.CurrentContext)
+		return nil
+	}
+	return printRevisions(q.out(), revisions)
+}
+
+func printRevisions(out io.Writer, revisions []*qapi.Revision) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tACTION\tVERSION\tNAMESPACE\tCLI VERSION\tTIMESTAMP")
+	for _, rev := range revisions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", rev.Number, rev.Action, rev.Version, rev.Namespace, rev.CliVersion, rev.Timestamp)
+	}
+	return w.Flush()
+}
+
+// RollbackQK8s reapplies the manifests and CR of a recorded revision, by default the one before the latest.
+// The context CR is restored to the one of that revision and the rollback is recorded as a new revision.
+// Resources of the context that are not in the manifests of that revision are pruned.
+func (q *Qliksense) RollbackQK8s(opts *RollbackCommandOptions) error {
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	contextName := qConfig.Spec.CurrentContext
+	var rev *qapi.Revision
+	var err error
+	if opts.ToRevision > 0 {
+		rev, err = qConfig.GetRevision(contextName, opts.ToRevision)
+	} else {
+		rev, err = qConfig.GetPreviousRevision(contextName)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(q.out(), "Rolling back to revision %d, version [%s]\n", rev.Number, rev.Version)
+	if opts.DryRun {
+		return nil
+	}
+
+	manifests, err := qConfig.GetRevisionManifests(contextName, rev.Number)
+	if err != nil {
+		return err
+	}
+	qcr, err := qConfig.GetRevisionCR(contextName, rev.Number)
+	if err != nil {
+		return err
+	}
+	dcr, err := qConfig.GetDecryptedCr(qcr)
+	if err != nil {
+		return err
+	}
+
+	namespace := rev.Namespace
+	if namespace == "" {
+		namespace = getTargetNamespace()
+	}
+	unlock, err := q.lockInstallNamespace("rollback", namespace, opts.ForceUnlock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := q.checkInterrupted(); err != nil {
+		return err
+	}
+	if err := q.installOperatorAndPatchResources(qConfig, qcr, nil); err != nil {
+		return err
+	}
+	if len(manifests) > 0 {
+		if err := q.checkInterrupted(); err != nil {
+			return err
+		}
+		fmt.Fprintln(q.out(), "Applying manifests of the revision to the cluster")
+		if err := q.applyContextManifests(qConfig, contextName, manifests, rev.Namespace, true); err != nil {
+			return err
+		}
+	}
+	if err := q.checkInterrupted(); err != nil {
+		return err
+	}
+	if err := q.applyCR(dcr); err != nil {
+		return err
+	}
+	if err := qConfig.WriteCR(qcr); err != nil {
+		return err
+	}
+	return q.saveRevision(qConfig, dcr, manifests, fmt.Sprintf("rollback to %d", rev.Number))
+}
@@ -0,0 +1,27 @@
+package qliksense
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_printRevisions(t *testing.T) {
+	var out bytes.Buffer
+	revisions := []*qapi.Revision{
+		{Number: 1, Action: "install", Version: "v1.0.0", Namespace: "qliksense", CliVersion: "v0.9.0", Timestamp: "2020-05-04T10:12:53Z"},
+		{Number: 2, Action: "upgrade", Version: "v1.1.0", Namespace: "qliksense", CliVersion: "v0.9.0", Timestamp: "2020-05-11T08:03:10Z"},
+	}
+	if err := printRevisions(&out, revisions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 revisions, got: %v", out.String())
+	}
+	if fields := strings.Fields(lines[2]); fields[0] != "2" || fields[1] != "upgrade" || fields[2] != "v1.1.0" {
+		t.Fatalf("unexpected revision line: %v", lines[2])
+	}
+}
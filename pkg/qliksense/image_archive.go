@@ -0,0 +1,244 @@
+package qliksense
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/oci/layout"
+	"github.com/containers/image/v5/transports/alltransports"
+	imageTypes "github.com/containers/image/v5/types"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+// formats of images save and load
+const (
+	ImageArchiveOci    = "oci-archive"
+	ImageArchiveDocker = "docker-archive"
+)
+
+const (
+	ociLayoutFileName         = "oci-layout"
+	ociIndexFileName          = "index.json"
+	ociImageRefNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+type ImageSaveCommandOptions struct {
+	Out    string
+	Format string
+}
+
+// archivedImageSource returns the reference of an image of an archive and a func to remove what it needed to create it
+type archivedImageSource func() (imageTypes.ImageReference, func(), error)
+
+// ociIndex is the part of the index.json of an OCI layout load needs
+type ociIndex struct {
+	Manifests []struct {
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"manifests"`
+}
+
+// SaveImages writes the images of the current CR from the image store into a single oci-archive or docker-archive
+// that skopeo, docker load or qliksense images load read. Images missing in the image store are pulled first.
+func (q *Qliksense) SaveImages(opts *ImageSaveCommandOptions) error {
+	if opts.Out == "" {
+		return errors.New("an output file is required")
+	} else if opts.Format != ImageArchiveOci && opts.Format != ImageArchiveDocker {
+		return fmt.Errorf("unsupported format %s, use %s or %s", opts.Format, ImageArchiveOci, ImageArchiveDocker)
+	}
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		return err
+	}
+	imagesDir, err := setupImagesDir(q.QliksenseHome)
+	if err != nil {
+		return err
+	}
+	images, err := q.getImagesOfCurrentCR(qcr, imagesDir)
+	if err != nil {
+		return err
+	}
+	registries, err := getImageRegistries(q.out(), qConfig)
+	if err != nil {
+		return err
+	}
+	defer registries.close()
+	return saveImages(q.out(), images, imagesDir, opts.Format, opts.Out, registries)
+}
+
+// getImagesOfCurrentCR returns the images of the profile of the current CR and the additional images
+func (q *Qliksense) getImagesOfCurrentCR(qcr *qapi.QliksenseCR, imagesDir string) ([]string, error) {
+	versionOut, _, err := q.readOrGenerateVersionOutput(imagesDir, qcr.GetLabelFromCr("version"), qcr.Spec.ManifestsRoot, qcr.Spec.Profile)
+	if err != nil {
+		return nil, err
+	}
+	images := versionOut.Images
+	if err := q.appendAdditionalImages(&images, qcr); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// saveImages writes the images of the image store in imagesDir into an archive of the format, the progress to progress
+func saveImages(progress io.Writer, images []string, imagesDir, format, archiveFile string, registries *imageRegistries) error {
+	workDir, err := ioutil.TempDir("", "qliksense-images-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+	var dockerArchives []string
+	// images are written one at a time, the OCI layout of the archive has a single index
+	if err := transferImages(progress, nil, "saved", "qliksense images save", images, 1, func(image string, out io.Writer) (bool, error) {
+		// an image is saved for the platform it was pulled for, a missing one is pulled for the default platform
+		platform, err := readImagePlatform(getImageNameParts(image).storeDir(imagesDir))
+		if err != nil {
+			return false, err
+		}
+		srcRef, err := storedImageReference(image, imagesDir, platform, registries, out)
+		if err != nil {
+			return false, err
+		}
+		var destRef imageTypes.ImageReference
+		// a docker-archive holds a single platform of a manifest list, the default one of an image of all platforms
+		listSelection := copy.CopySystemImage
+		if format == ImageArchiveOci {
+			destRef, err = layout.NewReference(workDir, image)
+			listSelection = copy.CopyAllImages
+		} else {
+			dockerArchive := filepath.Join(workDir, fmt.Sprintf("%d.tar", len(dockerArchives)))
+			dockerArchives = append(dockerArchives, dockerArchive)
+			destRef, err = alltransports.ParseImageName(fmt.Sprintf("%v:%v:%v", ImageArchiveDocker, dockerArchive, image))
+		}
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "==> Saving image %v\n", image)
+		return false, copyImage(destRef, srcRef, platform.systemContext(imageStoreContext(imagesDir)), nil, nil, listSelection, out)
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(progress, "Writing %s %s\n", format, archiveFile)
+	if format == ImageArchiveOci {
+		return qapi.TarDirectory(workDir, archiveFile)
+	}
+	return qapi.MergeDockerArchives(dockerArchives, archiveFile)
+}
+
+// LoadImages adds the images of an oci-archive or docker-archive, as written by images save, skopeo copy or docker save,
+// to the image store. Every image of the archive needs a name and a tag.
+func (q *Qliksense) LoadImages(archiveFile string) error {
+	imagesDir, err := setupImagesDir(q.QliksenseHome)
+	if err != nil {
+		return err
+	}
+	workDir, err := ioutil.TempDir("", "qliksense-images-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+	archiveDir := filepath.Join(workDir, "archive")
+	fmt.Fprintf(q.out(), "Extracting %s\n", archiveFile)
+	if err := qapi.ExtractTar(archiveFile, archiveDir); err != nil {
+		return err
+	}
+
+	var sources map[string]archivedImageSource
+	if _, err := os.Stat(filepath.Join(archiveDir, ociLayoutFileName)); err == nil {
+		sources, err = ociArchiveSources(archiveDir)
+		if err != nil {
+			return err
+		}
+	} else if items, err := qapi.ReadDockerArchiveManifest(archiveDir); err == nil {
+		sources, err = dockerArchiveSources(archiveDir, workDir, items)
+		if err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf("%s is neither an %s nor a %s", archiveFile, ImageArchiveOci, ImageArchiveDocker)
+	}
+
+	var images []string
+	for image := range sources {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return transferImages(q.out(), q.interrupted, "loaded", "qliksense images load "+archiveFile, images, 1, func(image string, out io.Writer) (bool, error) {
+		srcRef, cleanup, err := sources[image]()
+		if err != nil {
+			return false, err
+		}
+		defer cleanup()
+		nameTag := getImageNameParts(image)
+		targetDir := nameTag.storeDir(imagesDir)
+		if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+			return false, err
+		}
+		// the image no longer is the one pulled from the source registry
+		if err := os.Remove(filepath.Join(targetDir, imageSourceDigestFileName)); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		destRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", targetDir))
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "==> Loading image %v\n", image)
+		return false, copyImage(destRef, srcRef, nil, imageStoreContext(imagesDir), nil, copy.CopyAllImages, out)
+	})
+}
+
+// ociArchiveSources returns the images of the OCI layout in dir by the name they are annotated with
+func ociArchiveSources(dir string) (map[string]archivedImageSource, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ociIndexFileName))
+	if err != nil {
+		return nil, err
+	}
+	index := &ociIndex{}
+	if err := json.Unmarshal(b, index); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ociIndexFileName, err)
+	}
+	sources := map[string]archivedImageSource{}
+	for _, m := range index.Manifests {
+		image := m.Annotations[ociImageRefNameAnnotation]
+		if image == "" {
+			return nil, fmt.Errorf("image %s has no %s annotation", m.Digest, ociImageRefNameAnnotation)
+		}
+		sources[image] = func() (imageTypes.ImageReference, func(), error) {
+			ref, err := layout.NewReference(dir, image)
+			return ref, func() {}, err
+		}
+	}
+	return sources, nil
+}
+
+// dockerArchiveSources returns the images of the docker-archive extracted into dir by their first tag.
+// containers/image reads a single image per docker-archive, every image is written into a docker-archive
+// of its own in workDir while it is loaded.
+func dockerArchiveSources(dir, workDir string, items []*qapi.DockerArchiveManifestItem) (map[string]archivedImageSource, error) {
+	sources := map[string]archivedImageSource{}
+	for i, item := range items {
+		if len(item.RepoTags) == 0 {
+			return nil, fmt.Errorf("image %s has no tag", item.Config)
+		}
+		item := item
+		imageArchive := filepath.Join(workDir, fmt.Sprintf("%d.tar", i))
+		sources[item.RepoTags[0]] = func() (imageTypes.ImageReference, func(), error) {
+			cleanup := func() { os.Remove(imageArchive) }
+			if err := qapi.WriteDockerArchiveImage(dir, item, imageArchive); err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+			ref, err := alltransports.ParseImageName(fmt.Sprintf("%v:%v", ImageArchiveDocker, imageArchive))
+			return ref, cleanup, err
+		}
+	}
+	return sources, nil
+}
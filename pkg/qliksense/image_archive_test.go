@@ -0,0 +1,213 @@
+package qliksense
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containers/image/v5/transports/alltransports"
+)
+
+// writeTestImage writes an image with a single layer holding the file name into the image store in imagesDir
+func writeTestImage(t *testing.T, imagesDir, image, name string) {
+	t.Helper()
+	manifest, blobs := testImageBlobs(t, name)
+	blobsDir := filepath.Join(imagesDir, imageSharedBlobsDirName, "sha256")
+	if err := os.MkdirAll(blobsDir, os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, blob := range append(blobs, manifest) {
+		if err := ioutil.WriteFile(filepath.Join(blobsDir, fmt.Sprintf("%x", sha256.Sum256(blob))), blob, 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	nameTag := getImageNameParts(image)
+	targetDir := nameTag.storeDir(imagesDir)
+	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index := fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:%x","size":%d}]}`, sha256.Sum256([]byte(manifest)), len(manifest))
+	if err := ioutil.WriteFile(filepath.Join(targetDir, ociLayoutFileName), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ioutil.WriteFile(filepath.Join(targetDir, ociIndexFileName), []byte(index), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// testImageBlobs returns the OCI manifest of a linux/amd64 image with a single layer holding a file name,
+// and its layer and config blobs
+func testImageBlobs(t *testing.T, name string) ([]byte, [][]byte) {
+	t.Helper()
+	return testPlatformImageBlobs(t, name, "amd64")
+}
+
+// testPlatformImageBlobs returns the OCI manifest of a linux image of the architecture with a single layer holding
+// a file name, and its layer and config blobs
+func testPlatformImageBlobs(t *testing.T, name, arch string) ([]byte, [][]byte) {
+	t.Helper()
+	var layer bytes.Buffer
+	tw := tar.NewWriter(&layer)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(name))}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := tw.Write([]byte(name)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := tw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	if _, err := gw.Write(layer.Bytes()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := gw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config := fmt.Sprintf(`{"architecture":"%s","os":"linux","config":{},"rootfs":{"type":"layers","diff_ids":["sha256:%x"]}}`, arch, sha256.Sum256(layer.Bytes()))
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:%x","size":%d},"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":"sha256:%x","size":%d}]}`,
+		sha256.Sum256([]byte(config)), len(config), sha256.Sum256(compressed.Bytes()), compressed.Len())
+	return []byte(manifest), [][]byte{compressed.Bytes(), []byte(config)}
+}
+
+func Test_saveAndLoadImages(t *testing.T) {
+	images := []string{"qlik/engine:1.0", "docker.io/qlik/edge-auth:2.0"}
+	for _, format := range []string{ImageArchiveOci, ImageArchiveDocker} {
+		t.Run(format, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			imagesDir, err := setupImagesDir(filepath.Join(tmpDir, "save"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, image := range images {
+				writeTestImage(t, imagesDir, image, image)
+			}
+			archiveFile := filepath.Join(tmpDir, "images.tar")
+			if err := saveImages(ioutil.Discard, images, imagesDir, format, archiveFile, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			q := &Qliksense{QliksenseHome: filepath.Join(tmpDir, "load")}
+			if err := q.LoadImages(archiveFile); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			loadedImagesDir := filepath.Join(q.QliksenseHome, imagesDirName)
+			for _, image := range images {
+				nameTag := getImageNameParts(image)
+				ref, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", nameTag.storeDir(loadedImagesDir)))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if size, err := getImageSize(ref, imageStoreContext(loadedImagesDir)); err != nil {
+					t.Errorf("expected %s to be loaded: %v", image, err)
+				} else if size == 0 {
+					t.Errorf("expected %s to have a size", image)
+				}
+			}
+		})
+	}
+}
+
+func Test_saveImages_platform(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(filepath.Join(tmpDir, "save"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a manifest list of both architectures, pulled for linux/arm64
+	image := "qlik/engine:1.0"
+	blobsDir := filepath.Join(imagesDir, imageSharedBlobsDirName, "sha256")
+	if err := os.MkdirAll(blobsDir, os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var instances []string
+	for _, arch := range []string{"amd64", "arm64"} {
+		manifest, blobs := testPlatformImageBlobs(t, "engine-"+arch, arch)
+		for _, blob := range append(blobs, manifest) {
+			if err := ioutil.WriteFile(filepath.Join(blobsDir, fmt.Sprintf("%x", sha256.Sum256(blob))), blob, 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		instances = append(instances, fmt.Sprintf(`{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:%x","size":%d,"platform":{"architecture":"%s","os":"linux"}}`,
+			sha256.Sum256(manifest), len(manifest), arch))
+	}
+	list := []byte(fmt.Sprintf(`{"schemaVersion":2,"manifests":[%s]}`, strings.Join(instances, ",")))
+	if err := ioutil.WriteFile(filepath.Join(blobsDir, fmt.Sprintf("%x", sha256.Sum256(list))), list, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	targetDir := getImageNameParts(image).storeDir(imagesDir)
+	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index := fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:%x","size":%d}]}`, sha256.Sum256(list), len(list))
+	if err := ioutil.WriteFile(filepath.Join(targetDir, ociLayoutFileName), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ioutil.WriteFile(filepath.Join(targetDir, ociIndexFileName), []byte(index), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := writeImagePlatform(targetDir, &imagePlatform{os: "linux", arch: "arm64"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	archiveFile := filepath.Join(tmpDir, "images.tar")
+	if err := saveImages(ioutil.Discard, []string{image}, imagesDir, ImageArchiveDocker, archiveFile, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := &Qliksense{QliksenseHome: filepath.Join(tmpDir, "load")}
+	if err := q.LoadImages(archiveFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loadedImagesDir := filepath.Join(q.QliksenseHome, imagesDirName)
+	ref, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", getImageNameParts(image).storeDir(loadedImagesDir)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := ref.NewImage(context.Background(), imageStoreContext(loadedImagesDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer img.Close()
+	if config, err := img.OCIConfig(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if config.Architecture != "arm64" {
+		t.Fatalf("expected the arm64 image the image was pulled for, got %s", config.Architecture)
+	}
+}
+
+func Test_LoadImages_notAnArchive(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	archiveFile := filepath.Join(tmpDir, "images.tar")
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	if err := tw.WriteHeader(&tar.Header{Name: "README", Mode: 0644, Size: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := tw.Write([]byte("hi")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := tw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ioutil.WriteFile(archiveFile, b.Bytes(), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := &Qliksense{QliksenseHome: filepath.Join(tmpDir, "home")}
+	if err := q.LoadImages(archiveFile); err == nil {
+		t.Fatal("expected an error for a tar that is not an image archive")
+	}
+}
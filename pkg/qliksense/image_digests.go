@@ -0,0 +1,135 @@
+package qliksense
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/transports/alltransports"
+	imageTypes "github.com/containers/image/v5/types"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+// pinManifestDigests rewrites every container and init container image of the manifests to the digest of its
+// manifest, name@sha256:..., and writes the digests into the lock file of the current context. Images of the
+// lock file keep their digest, the others are resolved from the image store or from their registry.
+func (q *Qliksense) pinManifestDigests(qConfig *qapi.QliksenseConfig, qcr *qapi.QliksenseCR, manifests []byte) ([]byte, error) {
+	images, err := getImageList(manifests)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := qConfig.GetImageDigests()
+	if err != nil {
+		return nil, err
+	}
+	imagesDir, err := setupImagesDir(q.QliksenseHome)
+	if err != nil {
+		return nil, err
+	}
+	registries, err := getImageRegistries(q.out(), qConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer registries.close()
+
+	pushedDirs, err := getPushedImageStoreDirs(imagesDir, qcr.Spec.GetImageRegistry(), registries)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(q.out(), "Pinning the images of the manifests to their digests")
+	digests := qapi.ImageDigests{}
+	newImages := map[string]string{}
+	for _, image := range images {
+		repository, suffix := qapi.SplitImageReference(image)
+		if strings.HasPrefix(suffix, "@") {
+			continue
+		}
+		digest, ok := locked[image]
+		if !ok {
+			if digest, err = resolveImageDigest(image, imagesDir, qcr.Spec.GetImageRegistry(), pushedDirs, imageRegistryAuth(qConfig), registries); err != nil {
+				return nil, fmt.Errorf("cannot pin image %v to a digest: %w", image, err)
+			}
+		}
+		digests[image] = digest
+		newImages[image] = fmt.Sprintf("%s@%s", repository, digest)
+	}
+	if err := qConfig.WriteImageDigests(digests); err != nil {
+		return nil, err
+	}
+	return replaceImages(manifests, newImages)
+}
+
+// resolveImageDigest returns the digest of the manifest of the image. For an image of the image registry the digest
+// of the image pushed from the image store is returned, for any other image the digest it was pulled from. Images
+// not in the image store are resolved from their registry, with auth for the image registry. pushedDirs holds the
+// directories of the images of the image store by the reference push puts them at.
+func resolveImageDigest(image, imagesDir, imageRegistry string, pushedDirs map[string]string, auth *imageTypes.DockerAuthConfig,
+	registries *imageRegistries) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+	pushed := imageRegistry != "" && reference.Domain(named) == qapi.RegistryHost(imageRegistry)
+	if targetDir, ok := pushedDirs[image]; pushed && ok {
+		// push copies the manifests of the image store as they are
+		localRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", targetDir))
+		if err != nil {
+			return "", err
+		}
+		if digest, err := getManifestDigest(localRef, imageStoreContext(imagesDir)); err == nil {
+			return digest, nil
+		}
+	} else if sourceDigest, err := readImageSourceDigest(getImageNameParts(image).storeDir(imagesDir)); !pushed && err == nil && sourceDigest != "" {
+		return sourceDigest, nil
+	}
+
+	// source images are pulled through the mirrors
+	sourceImage := registries.sourceImage(image)
+	sys := &imageTypes.SystemContext{}
+	if pushed {
+		sourceImage = image
+		sys.DockerAuthConfig = auth
+	}
+	srcRef, err := alltransports.ParseImageName(fmt.Sprintf("docker://%v", sourceImage))
+	if err != nil {
+		return "", err
+	}
+	return getManifestDigest(srcRef, registries.systemContext(srcRef, sys))
+}
+
+// imageRegistryAuth returns the credentials of the image registry of the current context, those of the pull secret
+// or of the push secret, nil if neither has any
+func imageRegistryAuth(qConfig *qapi.QliksenseConfig) *imageTypes.DockerAuthConfig {
+	for _, getSecret := range []func() (*qapi.DockerConfigJsonSecret, error){qConfig.GetPullDockerConfigJsonSecret, qConfig.GetPushDockerConfigJsonSecret} {
+		if secret, err := getSecret(); err == nil && secret.Username != "" {
+			return &imageTypes.DockerAuthConfig{Username: secret.Username, Password: secret.Password}
+		}
+	}
+	return nil
+}
+
+// getPushedImageStoreDirs returns the directories of the images of the image store by the reference push puts them at
+// in the image registry, none without an image registry
+func getPushedImageStoreDirs(imagesDir, imageRegistry string, registries *imageRegistries) (map[string]string, error) {
+	pushedDirs := map[string]string{}
+	if imageRegistry == "" {
+		return pushedDirs, nil
+	}
+	indexDir := filepath.Join(imagesDir, imageIndexDirName)
+	targetDirs, err := getStoredImageDirs(indexDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, targetDir := range targetDirs {
+		image, err := storedImageName(indexDir, targetDir)
+		if err != nil {
+			return nil, err
+		} else if image == "" {
+			continue
+		}
+		pushedDirs[registries.targetImage(image, imageRegistry)] = targetDir
+	}
+	return pushedDirs, nil
+}
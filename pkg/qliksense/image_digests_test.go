@@ -0,0 +1,62 @@
+package qliksense
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/image/v5/transports/alltransports"
+)
+
+func Test_resolveImageDigest(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeTestImage(t, imagesDir, "qlik/engine:1.0", "engine")
+	targetDir := getImageNameParts("qlik/engine:1.0").storeDir(imagesDir)
+	sourceDigest := "sha256:9e4a8b32f2d7e0f0b5c8f1a26a6bb2a4c0b4e0d36f9c1a1e8b0a2d0a3c3f9b1c"
+	if err := ioutil.WriteFile(filepath.Join(targetDir, imageSourceDigestFileName), []byte(sourceDigest), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	localRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", targetDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	localDigest, err := getManifestDigest(localRef, imageStoreContext(imagesDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		image         string
+		imageRegistry string
+		want          string
+	}{
+		{name: "source image", image: "qlik/engine:1.0", want: sourceDigest},
+		{name: "source image with an image registry", image: "docker.io/qlik/engine:1.0", imageRegistry: "registry.example.com", want: sourceDigest},
+		{name: "pushed image", image: "registry.example.com/engine:1.0", imageRegistry: "https://registry.example.com/", want: localDigest},
+		{name: "pushed image with a prefix", image: "registry.example.com:5000/qlik/engine:1.0", imageRegistry: "registry.example.com:5000/qlik", want: localDigest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pushedDirs, err := getPushedImageStoreDirs(imagesDir, tt.imageRegistry, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, err := resolveImageDigest(tt.image, imagesDir, tt.imageRegistry, pushedDirs, nil, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if got != tt.want {
+				t.Errorf("resolveImageDigest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
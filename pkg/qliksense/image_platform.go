@@ -0,0 +1,94 @@
+package qliksense
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/copy"
+	imageTypes "github.com/containers/image/v5/types"
+)
+
+const (
+	// imagePlatformFileName holds the platform an image in the image store was pulled for
+	imagePlatformFileName = "platform"
+	// allImagePlatforms is the platform of an image pulled with every platform of its manifest list
+	allImagePlatforms = "all"
+)
+
+// defaultImagePlatform is the platform of the manifest lists images are pulled for unless selected otherwise
+var defaultImagePlatform = &imagePlatform{os: "linux", arch: "amd64"}
+
+// imagePlatform selects the images of the manifest lists pull copies into the image store,
+// a single platform or all of them
+type imagePlatform struct {
+	os   string
+	arch string
+	all  bool
+}
+
+// parseImagePlatform parses a platform given as os/arch, allPlatforms selects every platform
+func parseImagePlatform(platform string, allPlatforms bool) (*imagePlatform, error) {
+	if allPlatforms {
+		if platform != "" {
+			return nil, fmt.Errorf("a platform cannot be selected with all platforms")
+		}
+		return &imagePlatform{all: true}, nil
+	} else if platform == "" {
+		return defaultImagePlatform, nil
+	} else if platform == allImagePlatforms {
+		return &imagePlatform{all: true}, nil
+	}
+	// containers/image selects the images of manifest lists by os and architecture only, not by variant
+	segments := strings.Split(platform, "/")
+	if len(segments) != 2 || segments[0] == "" || segments[1] == "" {
+		return nil, fmt.Errorf("invalid platform %s, use os/arch, i.e. linux/arm64", platform)
+	}
+	return &imagePlatform{os: segments[0], arch: segments[1]}, nil
+}
+
+func (p *imagePlatform) String() string {
+	if p == nil {
+		return defaultImagePlatform.String()
+	} else if p.all {
+		return allImagePlatforms
+	}
+	return fmt.Sprintf("%s/%s", p.os, p.arch)
+}
+
+// systemContext selects the platform in sys and returns it, with all platforms the default platform is read
+// wherever a single image is needed
+func (p *imagePlatform) systemContext(sys *imageTypes.SystemContext) *imageTypes.SystemContext {
+	if p == nil || p.all {
+		p = defaultImagePlatform
+	}
+	sys.OSChoice = p.os
+	sys.ArchitectureChoice = p.arch
+	return sys
+}
+
+// listSelection returns which images of a manifest list are copied
+func (p *imagePlatform) listSelection() copy.ImageListSelection {
+	if p != nil && p.all {
+		return copy.CopyAllImages
+	}
+	return copy.CopySystemImage
+}
+
+// readImagePlatform returns the platform the image in targetDir was pulled for,
+// images pulled before the platform was recorded were pulled for the default platform
+func readImagePlatform(targetDir string) (*imagePlatform, error) {
+	platform, err := ioutil.ReadFile(filepath.Join(targetDir, imagePlatformFileName))
+	if os.IsNotExist(err) {
+		return defaultImagePlatform, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseImagePlatform(strings.TrimSpace(string(platform)), false)
+}
+
+func writeImagePlatform(targetDir string, platform *imagePlatform) error {
+	return ioutil.WriteFile(filepath.Join(targetDir, imagePlatformFileName), []byte(platform.String()), 0644)
+}
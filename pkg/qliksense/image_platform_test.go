@@ -0,0 +1,81 @@
+package qliksense
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/containers/image/v5/copy"
+	imageTypes "github.com/containers/image/v5/types"
+)
+
+func Test_parseImagePlatform(t *testing.T) {
+	tests := []struct {
+		name          string
+		platform      string
+		allPlatforms  bool
+		want          string
+		wantSelection copy.ImageListSelection
+		wantErr       bool
+	}{
+		{name: "default", want: "linux/amd64", wantSelection: copy.CopySystemImage},
+		{name: "platform", platform: "linux/arm64", want: "linux/arm64", wantSelection: copy.CopySystemImage},
+		{name: "all platforms", allPlatforms: true, want: allImagePlatforms, wantSelection: copy.CopyAllImages},
+		{name: "all", platform: allImagePlatforms, want: allImagePlatforms, wantSelection: copy.CopyAllImages},
+		{name: "platform and all platforms", platform: "linux/arm64", allPlatforms: true, wantErr: true},
+		{name: "variant", platform: "linux/arm/v7", wantErr: true},
+		{name: "no arch", platform: "linux", wantErr: true},
+		{name: "empty os", platform: "/arm64", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseImagePlatform(tt.platform, tt.allPlatforms)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseImagePlatform() error = %v, wantErr %v", err, tt.wantErr)
+			} else if tt.wantErr {
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("parseImagePlatform() = %v, want %v", got, tt.want)
+			}
+			if selection := got.listSelection(); selection != tt.wantSelection {
+				t.Errorf("listSelection() = %v, want %v", selection, tt.wantSelection)
+			}
+		})
+	}
+}
+
+func Test_imagePlatform_systemContext(t *testing.T) {
+	sys := (&imagePlatform{os: "linux", arch: "arm64"}).systemContext(&imageTypes.SystemContext{})
+	if sys.OSChoice != "linux" || sys.ArchitectureChoice != "arm64" {
+		t.Errorf("expected linux/arm64, got %s/%s", sys.OSChoice, sys.ArchitectureChoice)
+	}
+	sys = (&imagePlatform{all: true}).systemContext(&imageTypes.SystemContext{})
+	if sys.OSChoice != "linux" || sys.ArchitectureChoice != "amd64" {
+		t.Errorf("expected the default platform for all platforms, got %s/%s", sys.OSChoice, sys.ArchitectureChoice)
+	}
+}
+
+func Test_readImagePlatform(t *testing.T) {
+	targetDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(targetDir)
+
+	if platform, err := readImagePlatform(targetDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if platform != defaultImagePlatform {
+		t.Errorf("expected the default platform without a platform file, got %v", platform)
+	}
+	for _, want := range []*imagePlatform{{os: "linux", arch: "arm64"}, {all: true}} {
+		if err := writeImagePlatform(targetDir, want); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if platform, err := readImagePlatform(targetDir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		} else if platform.String() != want.String() {
+			t.Errorf("expected platform %v, got %v", want, platform)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package qliksense
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	containersImage "github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports/alltransports"
+	imageTypes "github.com/containers/image/v5/types"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+	"golang.org/x/net/context"
+)
+
+// loadImagePolicy returns the signature policy of the current context, the global one otherwise, nil if none is set
+func loadImagePolicy(qConfig *qapi.QliksenseConfig) (*signature.Policy, error) {
+	policyFile, err := qConfig.GetImagePolicyFile()
+	if err != nil || policyFile == "" {
+		return nil, err
+	}
+	policy, err := signature.NewPolicyFromFile(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature policy %s: %w", policyFile, err)
+	}
+	return policy, nil
+}
+
+// SetImagePolicy sets the containers-policy.json images are pulled with for the current context, or for every context
+// without a policy of its own if global. An empty policyFile removes the policy.
+func (q *Qliksense) SetImagePolicy(policyFile string, global bool) error {
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	if policyFile == "" {
+		return qConfig.SetImagePolicy(nil, global)
+	}
+	policy, err := ioutil.ReadFile(policyFile)
+	if err != nil {
+		return err
+	} else if _, err := signature.NewPolicyFromBytes(policy); err != nil {
+		return fmt.Errorf("invalid signature policy %s: %w", policyFile, err)
+	}
+	return qConfig.SetImagePolicy(policy, global)
+}
+
+// VerifyImages checks every image of the current context in the image store against the signature policy:
+// the signatures of the manifest the image was pulled from are read again from the source registry and
+// the manifest in the image store has to be that manifest or one of its platforms.
+func (q *Qliksense) VerifyImages() error {
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		return err
+	}
+	imagesDir, err := setupImagesDir(q.QliksenseHome)
+	if err != nil {
+		return err
+	}
+	images, err := q.getImagesOfCurrentCR(qcr, imagesDir)
+	if err != nil {
+		return err
+	}
+	registries, err := getImageRegistries(q.out(), qConfig)
+	if err != nil {
+		return err
+	}
+	defer registries.close()
+	if registries.signaturePolicy() == nil {
+		fmt.Fprintf(q.out(), "WARNING: no signature policy set, only the manifests of the images are checked\n")
+	}
+	return transferImages(q.out(), q.interrupted, "verified", "qliksense images verify", images, 1, func(image string, out io.Writer) (bool, error) {
+		return false, verifyImage(image, imagesDir, registries, out)
+	})
+}
+
+// verifyImage checks the image in the image store against the signature policy and the manifest it was pulled from
+func verifyImage(image, imagesDir string, registries *imageRegistries, out io.Writer) error {
+	nameTag := getImageNameParts(image)
+	targetDir := nameTag.storeDir(imagesDir)
+	if exists, err := directoryExists(targetDir); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("image %v is not in the image store", image)
+	}
+	sourceDigest, err := readImageSourceDigest(targetDir)
+	if err != nil {
+		return err
+	} else if sourceDigest == "" {
+		return fmt.Errorf("image %v was not pulled from a registry, its signatures cannot be read", image)
+	}
+
+	localRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", targetDir))
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	localSrc, err := localRef.NewImageSource(ctx, imageStoreContext(imagesDir))
+	if err != nil {
+		return err
+	}
+	defer localSrc.Close()
+	// the image store keeps OCI manifests, which differ from the docker manifests they were converted from,
+	// so images are compared by the digests of their configs
+	localConfigs, err := getManifestConfigDigests(ctx, localSrc)
+	if err != nil {
+		return err
+	}
+
+	srcRef, err := alltransports.ParseImageName(fmt.Sprintf("docker://%v", registries.sourceImage(image)))
+	if err != nil {
+		return err
+	}
+	digestRef, err := docker.ParseReference(fmt.Sprintf("//%v@%v", reference.TrimNamed(srcRef.DockerReference()), sourceDigest))
+	if err != nil {
+		return err
+	}
+	platform, err := readImagePlatform(targetDir)
+	if err != nil {
+		return err
+	}
+	policyRef, err := registries.policyReference(image, digestRef)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "==> Verifying image %v with digest %v\n", image, sourceDigest)
+	remoteSrc, err := policyRef.NewImageSource(ctx, registries.systemContext(digestRef, platform.systemContext(&imageTypes.SystemContext{})))
+	if err != nil {
+		return err
+	}
+	defer remoteSrc.Close()
+	remoteConfigs, err := getManifestConfigDigests(ctx, remoteSrc)
+	if err != nil {
+		return err
+	}
+	for config := range localConfigs {
+		if _, ok := remoteConfigs[config]; !ok {
+			return fmt.Errorf("image %v in the image store is not the image of %v it was pulled from", image, sourceDigest)
+		}
+	}
+
+	unparsed := containersImage.UnparsedInstance(remoteSrc, nil)
+	if len(localConfigs) == 1 {
+		// the image store keeps a single platform of a manifest list, its signatures are those of the platform
+		for config := range localConfigs {
+			unparsed = remoteConfigs[config]
+		}
+	}
+	policyContext, err := newPolicyContext(registries.signaturePolicy())
+	if err != nil {
+		return err
+	}
+	defer policyContext.Destroy()
+	if allowed, err := policyContext.IsRunningImageAllowed(ctx, unparsed); !allowed || err != nil {
+		return fmt.Errorf("image %v does not satisfy the signature policy: %w", image, err)
+	}
+	return nil
+}
+
+// getManifestConfigDigests returns the images of the manifest of src by the digests of their configs,
+// the single image or every instance of the manifest list
+func getManifestConfigDigests(ctx context.Context, src imageTypes.ImageSource) (map[string]*containersImage.UnparsedImage, error) {
+	configs := map[string]*containersImage.UnparsedImage{}
+	manifestBytes, mimeType, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !manifest.MIMETypeIsMultiImage(mimeType) {
+		m, err := manifest.FromBlob(manifestBytes, mimeType)
+		if err != nil {
+			return nil, err
+		}
+		configs[m.ConfigInfo().Digest.String()] = containersImage.UnparsedInstance(src, nil)
+		return configs, nil
+	}
+	list, err := manifest.ListFromBlob(manifestBytes, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range list.Instances() {
+		instance := instance
+		instanceBytes, instanceMIMEType, err := src.GetManifest(ctx, &instance)
+		if err != nil {
+			return nil, err
+		}
+		m, err := manifest.FromBlob(instanceBytes, instanceMIMEType)
+		if err != nil {
+			return nil, err
+		}
+		configs[m.ConfigInfo().Digest.String()] = containersImage.UnparsedInstance(src, &instance)
+	}
+	return configs, nil
+}
@@ -0,0 +1,226 @@
+package qliksense
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports/alltransports"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+const testRejectPolicy = `{"default":[{"type":"reject"}]}`
+
+func Test_SetImagePolicy(t *testing.T) {
+	tmpQlikSenseHome, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpQlikSenseHome)
+	setupQliksenseTestDefaultContext(t, tmpQlikSenseHome, `
+apiVersion: qlik.com/v1
+kind: Qliksense
+metadata:
+  name: qlik-default
+spec:
+  profile: docker-desktop
+`)
+	q := &Qliksense{QliksenseHome: tmpQlikSenseHome}
+	qConfig := qapi.NewQConfig(tmpQlikSenseHome)
+
+	writePolicy := func(name, content string) string {
+		policyFile := filepath.Join(tmpQlikSenseHome, name)
+		if err := ioutil.WriteFile(policyFile, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return policyFile
+	}
+	if err := q.SetImagePolicy(writePolicy("invalid.json", `{"default":[{"type":"unknown"}]}`), false); err == nil {
+		t.Fatal("expected an error for an invalid policy")
+	}
+	if policy, err := loadImagePolicy(qConfig); err != nil || policy != nil {
+		t.Fatalf("expected no policy, got %v, %v", policy, err)
+	}
+
+	if err := q.SetImagePolicy(writePolicy("global.json", `{"default":[{"type":"insecureAcceptAnything"}]}`), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := q.SetImagePolicy(writePolicy("context.json", testRejectPolicy), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy, err := loadImagePolicy(qConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if policy == nil || len(policy.Default) != 1 || !reflect.DeepEqual(policy.Default[0], signature.NewPRReject()) {
+		t.Fatalf("expected the policy of the context, got %v", policy)
+	}
+
+	if err := q.SetImagePolicy("", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy, err := loadImagePolicy(qConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if policy == nil || len(policy.Default) != 1 || !reflect.DeepEqual(policy.Default[0], signature.NewPRInsecureAcceptAnything()) {
+		t.Fatalf("expected the global policy, got %v", policy)
+	}
+}
+
+func Test_copyImage_policy(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeTestImage(t, imagesDir, "qlik/engine:1.0", "engine")
+	srcRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", getImageNameParts("qlik/engine:1.0").storeDir(imagesDir)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	destRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", filepath.Join(tmpDir, "copy")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reject, err := signature.NewPolicyFromBytes([]byte(testRejectPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var policyErr signature.PolicyRequirementError
+	if err := copyImage(destRef, srcRef, imageStoreContext(imagesDir), nil, reject, copy.CopySystemImage, ioutil.Discard); !errors.As(err, &policyErr) {
+		t.Fatalf("expected the policy to reject the image, got %v", err)
+	}
+	if err := copyImage(destRef, srcRef, imageStoreContext(imagesDir), nil, nil, copy.CopySystemImage, ioutil.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func Test_verifyImage_notPulled(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeTestImage(t, imagesDir, "qlik/engine:1.0", "engine")
+	for _, image := range []string{"qlik/engine:1.0", "qlik/edge-auth:1.0"} {
+		if err := verifyImage(image, imagesDir, nil, ioutil.Discard); err == nil {
+			t.Errorf("expected an error for %s, it was not pulled from a registry", image)
+		}
+	}
+}
+
+func Test_pullImage_policyOfMirroredImage(t *testing.T) {
+	server := newTestImageServer(t)
+	defer server.Close()
+	server.addImage(t, "qlik/engine", "1.0", "engine")
+	server.addImage(t, "qlik/edge-auth", "1.0", "edge-auth")
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registries, err := newImageRegistries(ioutil.Discard, qapi.RegistriesTLS{server.host(): {Insecure: true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer registries.close()
+	registries.mirrors = qapi.ImageMirrors{{Source: "source.example.com", Mirror: server.host()}}
+	// the images are only signed for their names in source.example.com, a scope naming the mirror never matches
+	policy, err := signature.NewPolicyFromBytes([]byte(`{
+  "default": [{"type": "insecureAcceptAnything"}],
+  "transports": {"docker": {"source.example.com/qlik/engine": [{"type": "signedBy", "keyType": "GPGKeys", "keyPath": "/nonexistent/key.gpg"}]}}
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	registries.policy = policy
+	var policyErr signature.PolicyRequirementError
+	if _, err := pullImage("source.example.com/qlik/engine:1.0", imagesDir, defaultImagePlatform, registries, ioutil.Discard); !errors.As(err, &policyErr) {
+		t.Fatalf("expected the scope of the source name to reject the unsigned image of the mirror, got %v", err)
+	}
+	if _, err := pullImage("source.example.com/qlik/edge-auth:1.0", imagesDir, defaultImagePlatform, registries, ioutil.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// pulled before the policy was set, verify checks it against the source name as well
+	registries.policy = nil
+	if _, err := pullImage("source.example.com/qlik/engine:1.0", imagesDir, defaultImagePlatform, registries, ioutil.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registries.policy = policy
+	if err := verifyImage("source.example.com/qlik/engine:1.0", imagesDir, registries, ioutil.Discard); err == nil {
+		t.Fatal("expected the scope of the source name to reject the unsigned image of the mirror")
+	}
+	if err := verifyImage("source.example.com/qlik/edge-auth:1.0", imagesDir, registries, ioutil.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func Test_pullImage_policyOfStoredImage(t *testing.T) {
+	server := newTestImageServer(t)
+	defer server.Close()
+	image, _ := server.addImage(t, "qlik/engine", "1.0", "engine")
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registries, err := newImageRegistries(ioutil.Discard, qapi.RegistriesTLS{server.host(): {Insecure: true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer registries.close()
+	if _, err := pullImage(image, imagesDir, defaultImagePlatform, registries, ioutil.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	targetDir := getImageNameParts(image).storeDir(imagesDir)
+	storedIndex, err := ioutil.ReadFile(filepath.Join(targetDir, ociIndexFileName))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	registries.policy, err = signature.NewPolicyFromBytes([]byte(testRejectPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var policyErr signature.PolicyRequirementError
+	if _, err := pullImage(image, imagesDir, defaultImagePlatform, registries, ioutil.Discard); !errors.As(err, &policyErr) {
+		t.Fatalf("expected the policy to reject the image already present, got %v", err)
+	}
+
+	// a rejected image of a moved tag keeps the image pulled before
+	server.addImage(t, "qlik/engine", "1.0", "engine-2")
+	if _, err := pullImage(image, imagesDir, defaultImagePlatform, registries, ioutil.Discard); !errors.As(err, &policyErr) {
+		t.Fatalf("expected the policy to reject the image, got %v", err)
+	}
+	if index, err := ioutil.ReadFile(filepath.Join(targetDir, ociIndexFileName)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if string(index) != string(storedIndex) {
+		t.Fatalf("expected the index of the image pulled before, got %s", index)
+	}
+	if leftovers, err := filepath.Glob(filepath.Join(imagesDir, ".pull-*")); err != nil || len(leftovers) > 0 {
+		t.Fatalf("expected no pull directories left, got %v, %v", leftovers, err)
+	}
+}
@@ -0,0 +1,333 @@
+package qliksense
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+type ImagePruneCommandOptions struct {
+	// Keep are images kept in the image store even if no version needs them
+	Keep   []string
+	DryRun bool
+}
+
+// imageStorePruneResult is what prune removed, or would remove with a dry run, from the image store
+type imageStorePruneResult struct {
+	// Images are the removed index entries as name:tag
+	Images []string
+	// Versions are the removed image lists of versions
+	Versions []string
+	Blobs    int
+	Bytes    int64
+}
+
+// ociBlobReferences is the part of an index or a manifest of an OCI layout that references other blobs
+type ociBlobReferences struct {
+	Manifests []struct {
+		Digest string `json:"digest"`
+	} `json:"manifests"`
+	Config *struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		Digest string `json:"digest"`
+	} `json:"layers"`
+}
+
+// PruneImages removes the images no version fetched in any context needs from the image store, except the images
+// of opts.Keep, and then the blobs no image left in the image store references. With DryRun nothing is removed.
+func (q *Qliksense) PruneImages(opts *ImagePruneCommandOptions) error {
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	imagesDir, err := setupImagesDir(q.QliksenseHome)
+	if err != nil {
+		return err
+	}
+	versions, images, err := q.getImagesOfFetchedVersions(qConfig, imagesDir)
+	if err != nil {
+		return err
+	}
+	images = append(images, opts.Keep...)
+	result, err := pruneImageStore(imagesDir, versions, images, opts.DryRun)
+	if err != nil {
+		return err
+	}
+
+	action := "Removed"
+	if opts.DryRun {
+		action = "Would remove"
+	}
+	for _, image := range result.Images {
+		fmt.Fprintf(q.out(), "%s image %s\n", action, image)
+	}
+	for _, version := range result.Versions {
+		fmt.Fprintf(q.out(), "%s image list of version %s\n", action, version)
+	}
+	reclaimed := formatImageSize(result.Bytes)
+	if reclaimed == "" {
+		reclaimed = "0B"
+	}
+	fmt.Fprintf(q.out(), "%s %d images, %d image lists and %d blobs, %s (%d bytes) reclaimed\n", action, len(result.Images),
+		len(result.Versions), result.Blobs, reclaimed, result.Bytes)
+	return nil
+}
+
+// getImagesOfFetchedVersions returns the versions fetched in every context and the images they need,
+// with the operator, ops-runner and preflight images of every context
+func (q *Qliksense) getImagesOfFetchedVersions(qConfig *qapi.QliksenseConfig, imagesDir string) (map[string]bool, []string, error) {
+	versions := map[string]bool{}
+	var images []string
+	for _, ctx := range qConfig.Spec.Contexts {
+		qcr, err := qConfig.GetCR(ctx.Name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot read the CR of context %s: %w", ctx.Name, err)
+		}
+		repoDirs := map[string]string{}
+		reposDir := qConfig.BuildRepoPathForContext(ctx.Name, "")
+		if infos, err := ioutil.ReadDir(reposDir); err != nil && !os.IsNotExist(err) {
+			return nil, nil, err
+		} else {
+			for _, info := range infos {
+				if info.IsDir() {
+					repoDirs[info.Name()] = filepath.Join(reposDir, info.Name())
+				}
+			}
+		}
+		// the manifests of the CR may be outside of the context, i.e. those of a loaded bundle
+		if qcr.Spec.ManifestsRoot != "" && qcr.IsRepoExist() {
+			if _, ok := repoDirs[qcr.GetLabelFromCr("version")]; !ok {
+				repoDirs[qcr.GetLabelFromCr("version")] = qcr.Spec.ManifestsRoot
+			}
+		}
+		for version, repoDir := range repoDirs {
+			versionOut, _, err := q.readOrGenerateVersionOutput(imagesDir, version, repoDir, qcr.Spec.Profile)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cannot read the images of version %s of context %s: %w", version, ctx.Name, err)
+			}
+			versions[version] = true
+			images = append(images, versionOut.Images...)
+		}
+		if err := q.appendAdditionalImages(&images, qcr); err != nil {
+			return nil, nil, err
+		}
+	}
+	return versions, images, nil
+}
+
+// pruneImageStore removes the index entries of the image store in imagesDir that are none of images, the image lists
+// of other than versions and the blobs no index entry left references. With dryRun nothing is removed.
+func pruneImageStore(imagesDir string, versions map[string]bool, images []string, dryRun bool) (*imageStorePruneResult, error) {
+	result := &imageStorePruneResult{}
+	remove := func(path string) error {
+		size, err := directorySize(path)
+		if err != nil {
+			return err
+		}
+		result.Bytes += size
+		if dryRun {
+			return nil
+		}
+		return os.RemoveAll(path)
+	}
+
+	// the index keeps the images by repository and tag
+	keep := map[string]bool{}
+	for _, image := range images {
+		keep[getImageNameParts(image).storeDir(imagesDir)] = true
+	}
+	indexDir := filepath.Join(imagesDir, imageIndexDirName)
+	blobsDir := filepath.Join(imagesDir, imageSharedBlobsDirName)
+	referencedBlobs := map[string]bool{}
+	targetDirs, err := getStoredImageDirs(indexDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, targetDir := range targetDirs {
+		if keep[targetDir] {
+			if err := addReferencedBlobs(targetDir, blobsDir, referencedBlobs); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		image, err := storedImageName(indexDir, targetDir)
+		if err != nil {
+			return nil, err
+		} else if image == "" {
+			// a directory of an earlier layout of the index, index/<name>/<tag>
+			rel, err := filepath.Rel(indexDir, targetDir)
+			if err != nil {
+				return nil, err
+			}
+			image = fmt.Sprintf("%s:%s", filepath.ToSlash(filepath.Dir(rel)), filepath.Base(rel))
+		}
+		result.Images = append(result.Images, image)
+		if err := remove(targetDir); err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			if err := removeEmptyParentDirs(targetDir, indexDir); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the image lists of versions are the files next to the index and the blobs
+	files, err := ioutil.ReadDir(imagesDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || versions[file.Name()] {
+			continue
+		}
+		result.Versions = append(result.Versions, file.Name())
+		if err := remove(filepath.Join(imagesDir, file.Name())); err != nil {
+			return nil, err
+		}
+	}
+
+	algorithms, err := ioutil.ReadDir(blobsDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, algorithm := range algorithms {
+		blobs, err := ioutil.ReadDir(filepath.Join(blobsDir, algorithm.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range blobs {
+			if referencedBlobs[fmt.Sprintf("%s:%s", algorithm.Name(), blob.Name())] {
+				continue
+			}
+			result.Blobs++
+			if err := remove(filepath.Join(blobsDir, algorithm.Name(), blob.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Strings(result.Images)
+	sort.Strings(result.Versions)
+	return result, nil
+}
+
+// getStoredImageDirs returns the directories of the images in the index of the image store, the directories
+// without subdirectories. Repositories nest, images of the same registry and organization share directories.
+func getStoredImageDirs(indexDir string) ([]string, error) {
+	var targetDirs []string
+	err := filepath.Walk(indexDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() || path == indexDir {
+			return err
+		}
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				return nil
+			}
+		}
+		targetDirs = append(targetDirs, path)
+		return filepath.SkipDir
+	})
+	return targetDirs, err
+}
+
+// storedImageName returns the image of a directory of the index of the image store, the reverse of storeDir.
+// It returns an empty name for a directory of an earlier layout of the index, without the registry of the image.
+func storedImageName(indexDir, targetDir string) (string, error) {
+	rel, err := filepath.Rel(indexDir, targetDir)
+	if err != nil {
+		return "", err
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	// a normalized name has a registry and at least one path component
+	if len(segments) < 3 {
+		return "", nil
+	}
+	// host names have no underscores, storeDir replaced the colon of the port
+	segments[0] = strings.Replace(segments[0], "_", ":", 1)
+	name, tag := strings.Join(segments[:len(segments)-1], "/"), segments[len(segments)-1]
+	if strings.HasPrefix(tag, "sha256_") {
+		return fmt.Sprintf("%s@%s", name, strings.Replace(tag, "_", ":", 1)), nil
+	}
+	return fmt.Sprintf("%s:%s", name, tag), nil
+}
+
+// removeEmptyParentDirs removes the parent directories of dir up to root as long as they are empty
+func removeEmptyParentDirs(dir, root string) error {
+	for parent := filepath.Dir(dir); parent != root && strings.HasPrefix(parent, root); parent = filepath.Dir(parent) {
+		entries, err := ioutil.ReadDir(parent)
+		if err != nil {
+			return err
+		} else if len(entries) > 0 {
+			return nil
+		} else if err := os.Remove(parent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addReferencedBlobs adds the digests of the manifests, configs and layers the index entry in targetDir references
+// to blobs. An index entry without index.json, as left by a failed pull, references none.
+func addReferencedBlobs(targetDir, blobsDir string, blobs map[string]bool) error {
+	index, err := ioutil.ReadFile(filepath.Join(targetDir, ociIndexFileName))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return addBlobReferences(index, blobsDir, blobs)
+}
+
+// addBlobReferences adds the blobs the index or manifest content references to blobs, and those of the
+// manifests it references in turn
+func addBlobReferences(content []byte, blobsDir string, blobs map[string]bool) error {
+	references := &ociBlobReferences{}
+	if err := json.Unmarshal(content, references); err != nil {
+		return err
+	}
+	if references.Config != nil {
+		blobs[references.Config.Digest] = true
+	}
+	for _, layer := range references.Layers {
+		blobs[layer.Digest] = true
+	}
+	for _, m := range references.Manifests {
+		if blobs[m.Digest] {
+			continue
+		}
+		blobs[m.Digest] = true
+		manifestBlob, err := ioutil.ReadFile(filepath.Join(blobsDir, strings.Replace(m.Digest, ":", string(filepath.Separator), 1)))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := addBlobReferences(manifestBlob, blobsDir, blobs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// directorySize returns the size of the files in path, or of the file at path
+func directorySize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
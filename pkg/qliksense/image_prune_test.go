@@ -0,0 +1,104 @@
+package qliksense
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_pruneImageStore(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	imagesDir, err := setupImagesDir(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeTestImage(t, imagesDir, "qlik/engine:1.0", "engine")
+	writeTestImage(t, imagesDir, "qlik/engine:2.0", "engine-2")
+	writeTestImage(t, imagesDir, "qlik/edge-auth:1.0", "edge-auth")
+	for _, version := range []string{"v1.0.0", "v2.0.0"} {
+		if err := ioutil.WriteFile(filepath.Join(imagesDir, version), []byte("images: []\n"), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	orphan := filepath.Join(imagesDir, imageSharedBlobsDirName, "sha256", "0000000000000000000000000000000000000000000000000000000000000000")
+	if err := ioutil.WriteFile(orphan, []byte("orphan"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	countBlobs := func() int {
+		blobs, err := ioutil.ReadDir(filepath.Join(imagesDir, imageSharedBlobsDirName, "sha256"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return len(blobs)
+	}
+	versions := map[string]bool{"v2.0.0": true}
+	images := []string{"docker.io/qlik/engine:2.0", "qlik/edge-auth:1.0"}
+
+	dryRun, err := pruneImageStore(imagesDir, versions, images, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(dryRun.Images, []string{"docker.io/qlik/engine:1.0"}) {
+		t.Errorf("expected docker.io/qlik/engine:1.0 to be pruned, got %v", dryRun.Images)
+	} else if !reflect.DeepEqual(dryRun.Versions, []string{"v1.0.0"}) {
+		t.Errorf("expected v1.0.0 to be pruned, got %v", dryRun.Versions)
+	} else if dryRun.Blobs != 4 || dryRun.Bytes == 0 {
+		t.Errorf("expected the 3 blobs of engine:1.0 and the orphan to be pruned, got %d blobs of %d bytes", dryRun.Blobs, dryRun.Bytes)
+	}
+	if blobs := countBlobs(); blobs != 10 {
+		t.Fatalf("expected a dry run to keep the 10 blobs, got %d", blobs)
+	}
+
+	result, err := pruneImageStore(imagesDir, versions, images, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !reflect.DeepEqual(result, dryRun) {
+		t.Errorf("expected the result of the dry run %v, got %v", dryRun, result)
+	}
+	if blobs := countBlobs(); blobs != 6 {
+		t.Errorf("expected the 6 blobs of the kept images, got %d", blobs)
+	}
+	if _, err := os.Stat(filepath.Join(imagesDir, imageIndexDirName, "docker.io", "qlik", "engine", "1.0")); !os.IsNotExist(err) {
+		t.Errorf("expected engine:1.0 to be removed from the index, got %v", err)
+	} else if _, err := os.Stat(filepath.Join(imagesDir, "v1.0.0")); !os.IsNotExist(err) {
+		t.Errorf("expected the image list of v1.0.0 to be removed, got %v", err)
+	}
+	for _, image := range images {
+		if _, err := storedImageReference(image, imagesDir, defaultImagePlatform, nil, ioutil.Discard); err != nil {
+			t.Errorf("expected %s to be kept, got %v", image, err)
+		}
+	}
+
+	if result, err := pruneImageStore(imagesDir, versions, nil, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(result.Images) != 2 || countBlobs() != 0 {
+		t.Errorf("expected every image and blob to be pruned, got %v and %d blobs", result.Images, countBlobs())
+	}
+	if names, err := ioutil.ReadDir(filepath.Join(imagesDir, imageIndexDirName)); err != nil || len(names) != 0 {
+		t.Errorf("expected an empty index, got %v, %v", names, err)
+	}
+}
+
+func Test_storedImageName(t *testing.T) {
+	indexDir := filepath.Join("images", imageIndexDirName)
+	for _, image := range []string{
+		"docker.io/qlik/engine:1.0",
+		"registry.example.com:5000/qlik/edge-auth:2.0_rc",
+		"quay.io/org/team/nginx@sha256:9e4a8b32f2d7e0f0b5c8f1a26a6bb2a4c0b4e0d36f9c1a1e8b0a2d0a3c3f9b1c",
+	} {
+		if got, err := storedImageName(indexDir, getImageNameParts(image).storeDir("images")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		} else if got != image {
+			t.Errorf("expected %s, got %s", image, got)
+		}
+	}
+	if got, err := storedImageName(indexDir, filepath.Join(indexDir, "engine", "1.0")); err != nil || got != "" {
+		t.Errorf("expected no image for a directory of the earlier layout, got %q, %v", got, err)
+	}
+}
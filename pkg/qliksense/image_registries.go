@@ -0,0 +1,253 @@
+package qliksense
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	containersImage "github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/signature"
+	imageTypes "github.com/containers/image/v5/types"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+	"golang.org/x/net/context"
+)
+
+// names containers/image looks for in the certificates directory of a registry
+const (
+	registryCACertFileName     = "ca.crt"
+	registryClientCertFileName = "client.cert"
+	registryClientKeyFileName  = "client.key"
+)
+
+// imageRegistries applies the settings of the registries of the current context to the system contexts
+// images are read from and written to registries with. A nil imageRegistries applies no settings.
+type imageRegistries struct {
+	tls qapi.RegistriesTLS
+	// certsDir has a directory per registry with a CA bundle or a client certificate, laid out as containers/image reads it
+	certsDir string
+	// policy is the signature policy images are pulled with, nil accepts any image
+	policy *signature.Policy
+	// mapping maps the repositories of images to repositories of the image registry, nil flattens them
+	mapping *qapi.ImageRegistryMapping
+	// mirrors are the mirrors images are pulled from their source registries through
+	mirrors qapi.ImageMirrors
+	// dockerCredentials are the credentials of the docker config by registry host, nil for a registry docker has none of
+	dockerCredentials      map[string]*imageTypes.DockerAuthConfig
+	dockerCredentialsMutex sync.Mutex
+	// out receives the warnings of the registries
+	out io.Writer
+}
+
+// getImageRegistries returns the settings of the registries of the current context, close releases them.
+// The warnings of the registries are written to out.
+func getImageRegistries(out io.Writer, qConfig *qapi.QliksenseConfig) (*imageRegistries, error) {
+	registriesTLS, err := qConfig.GetRegistriesTLS()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := loadImagePolicy(qConfig)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := qConfig.GetImageRegistryMapping()
+	if err != nil {
+		return nil, err
+	}
+	mirrors, err := qConfig.GetImageMirrors()
+	if err != nil {
+		return nil, err
+	}
+	r, err := newImageRegistries(out, registriesTLS)
+	if err != nil {
+		return nil, err
+	}
+	r.policy = policy
+	r.mapping = mapping
+	r.mirrors = mirrors
+	return r, nil
+}
+
+func newImageRegistries(out io.Writer, registriesTLS qapi.RegistriesTLS) (*imageRegistries, error) {
+	r := &imageRegistries{tls: registriesTLS, dockerCredentials: map[string]*imageTypes.DockerAuthConfig{}, out: out}
+	for host, tls := range registriesTLS {
+		if tls.CACert == "" && tls.ClientCert == "" {
+			continue
+		}
+		if r.certsDir == "" {
+			certsDir, err := ioutil.TempDir("", "qliksense-certs-")
+			if err != nil {
+				return nil, err
+			}
+			r.certsDir = certsDir
+		}
+		if err := writeRegistryCertsDir(r.registryCertsDir(host), tls); err != nil {
+			r.close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// writeRegistryCertsDir copies the CA bundle and the client certificate of the registry into dir
+func writeRegistryCertsDir(dir string, tls *qapi.RegistryTLS) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for _, file := range []struct{ src, name string }{
+		{tls.CACert, registryCACertFileName},
+		{tls.ClientCert, registryClientCertFileName},
+		{tls.ClientKey, registryClientKeyFileName},
+	} {
+		if file.src == "" {
+			continue
+		}
+		content, err := ioutil.ReadFile(file.src)
+		if err != nil {
+			return err
+		} else if err := ioutil.WriteFile(filepath.Join(dir, file.name), content, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registryCertsDir returns the certificates directory of the registry host, host_port for a host with a port
+// as a colon is not allowed in file names on windows
+func (r *imageRegistries) registryCertsDir(host string) string {
+	return filepath.Join(r.certsDir, strings.ReplaceAll(host, ":", "_"))
+}
+
+// close removes the copies of the certificates
+func (r *imageRegistries) close() {
+	if r != nil && r.certsDir != "" {
+		os.RemoveAll(r.certsDir)
+	}
+}
+
+// signaturePolicy returns the signature policy images are pulled with, nil if none is set
+func (r *imageRegistries) signaturePolicy() *signature.Policy {
+	if r == nil {
+		return nil
+	}
+	return r.policy
+}
+
+// targetImage returns where push puts the image in the registry
+func (r *imageRegistries) targetImage(image, registry string) string {
+	if r == nil {
+		return getTargetImage(image, registry, nil)
+	}
+	return getTargetImage(image, registry, r.mapping)
+}
+
+// sourceImage returns where the image is pulled from, through the mirror rules
+func (r *imageRegistries) sourceImage(image string) string {
+	if r == nil {
+		return image
+	}
+	return r.mirrors.MirrorImage(image)
+}
+
+// policyReference returns the reference the signature policy checks the image of mirrorRef against: mirrorRef itself
+// unless the image is pulled through a mirror, the image in its source registry otherwise, with the digest of mirrorRef.
+// The scopes of the policy name the source registries and signatures the images they were signed for, not their mirrors.
+func (r *imageRegistries) policyReference(image string, mirrorRef imageTypes.ImageReference) (imageTypes.ImageReference, error) {
+	if r.sourceImage(image) == image {
+		return mirrorRef, nil
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, err
+	}
+	if digested, ok := mirrorRef.DockerReference().(reference.Digested); ok {
+		if named, err = reference.WithDigest(reference.TrimNamed(named), digested.Digest()); err != nil {
+			return nil, err
+		}
+	}
+	sourceRef, err := docker.NewReference(reference.TagNameOnly(named))
+	if err != nil {
+		return nil, err
+	}
+	return &mirroredImageReference{ImageReference: sourceRef, mirror: mirrorRef}, nil
+}
+
+// mirroredImageReference is an image in its source registry read from a mirror
+type mirroredImageReference struct {
+	imageTypes.ImageReference
+	mirror imageTypes.ImageReference
+}
+
+// NewImageSource reads the image from the mirror, the source is referenced as the image in its source registry
+func (r *mirroredImageReference) NewImageSource(ctx context.Context, sys *imageTypes.SystemContext) (imageTypes.ImageSource, error) {
+	src, err := r.mirror.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	return &mirroredImageSource{ImageSource: src, ref: r}, nil
+}
+
+// NewImage reads the image from the mirror
+func (r *mirroredImageReference) NewImage(ctx context.Context, sys *imageTypes.SystemContext) (imageTypes.ImageCloser, error) {
+	src, err := r.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	return containersImage.FromSource(ctx, sys, src)
+}
+
+type mirroredImageSource struct {
+	imageTypes.ImageSource
+	ref imageTypes.ImageReference
+}
+
+func (s *mirroredImageSource) Reference() imageTypes.ImageReference {
+	return s.ref
+}
+
+// systemContext applies the settings of the registry of ref to sys and returns it, with the credentials docker has
+// for the registry unless sys has credentials. Without settings the certificate of the registry is verified with
+// the system CAs.
+func (r *imageRegistries) systemContext(ref imageTypes.ImageReference, sys *imageTypes.SystemContext) *imageTypes.SystemContext {
+	if r == nil || ref.DockerReference() == nil {
+		return sys
+	}
+	host := reference.Domain(ref.DockerReference())
+	if sys.DockerAuthConfig == nil {
+		sys.DockerAuthConfig = r.getDockerCredentials(host)
+	}
+	tls, ok := r.tls[host]
+	if !ok {
+		return sys
+	}
+	if tls.Insecure {
+		sys.DockerInsecureSkipTLSVerify = imageTypes.OptionalBoolTrue
+	}
+	if tls.CACert != "" || tls.ClientCert != "" {
+		sys.DockerCertPath = r.registryCertsDir(host)
+	}
+	return sys
+}
+
+// getDockerCredentials returns the credentials of the docker config for the registry host, read once per host
+// as credential helpers may be slow. A registry docker has no credentials of is accessed anonymously.
+func (r *imageRegistries) getDockerCredentials(host string) *imageTypes.DockerAuthConfig {
+	r.dockerCredentialsMutex.Lock()
+	defer r.dockerCredentialsMutex.Unlock()
+	if credentials, ok := r.dockerCredentials[host]; ok {
+		return credentials
+	}
+	var credentials *imageTypes.DockerAuthConfig
+	if secret, err := qapi.GetDockerConfigJsonSecretFromDockerConfig(host); err != nil {
+		fmt.Fprintf(r.out, "WARNING: cannot read the docker credentials of %s: %v\n", host, err)
+	} else if secret != nil {
+		credentials = &imageTypes.DockerAuthConfig{Username: secret.Username, Password: secret.Password}
+	}
+	r.dockerCredentials[host] = credentials
+	return credentials
+}
@@ -0,0 +1,94 @@
+package qliksense
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/image/v5/transports/alltransports"
+	imageTypes "github.com/containers/image/v5/types"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_imageRegistries_systemContext(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	caCert := filepath.Join(tmpDir, "ca.pem")
+	if err := ioutil.WriteFile(caCert, []byte("ca"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	registries, err := newImageRegistries(ioutil.Discard, qapi.RegistriesTLS{
+		"secure.example.com:5000": {CACert: caCert},
+		"insecure.example.com":    {Insecure: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer registries.close()
+
+	tests := []struct {
+		image        string
+		wantInsecure bool
+		wantCert     bool
+	}{
+		{image: "secure.example.com:5000/qlik/engine:1.0", wantCert: true},
+		{image: "insecure.example.com/qlik/engine:1.0", wantInsecure: true},
+		{image: "qlik/engine:1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := alltransports.ParseImageName("docker://" + tt.image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sys := registries.systemContext(ref, &imageTypes.SystemContext{})
+			if insecure := sys.DockerInsecureSkipTLSVerify == imageTypes.OptionalBoolTrue; insecure != tt.wantInsecure {
+				t.Errorf("expected insecure %v, got %v", tt.wantInsecure, insecure)
+			}
+			if !tt.wantCert {
+				if sys.DockerCertPath != "" {
+					t.Errorf("unexpected certificates directory %s", sys.DockerCertPath)
+				}
+			} else if filepath.Base(sys.DockerCertPath) != "secure.example.com_5000" {
+				t.Errorf("expected the certificates directory to be named host_port, got %s", sys.DockerCertPath)
+			} else if content, err := ioutil.ReadFile(filepath.Join(sys.DockerCertPath, registryCACertFileName)); err != nil {
+				t.Errorf("expected the CA in the certificates directory: %v", err)
+			} else if string(content) != "ca" {
+				t.Errorf("unexpected CA %s", string(content))
+			}
+		})
+	}
+
+	registries.close()
+	if _, err := os.Stat(registries.certsDir); !os.IsNotExist(err) {
+		t.Error("expected the certificates directory to be removed")
+	}
+
+	var none *imageRegistries
+	ref, err := alltransports.ParseImageName("docker://insecure.example.com/qlik/engine:1.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sys := none.systemContext(ref, &imageTypes.SystemContext{}); sys.DockerInsecureSkipTLSVerify != imageTypes.OptionalBoolUndefined {
+		t.Error("expected no settings without registries")
+	}
+}
+
+func Test_imageRegistries_sourceImage(t *testing.T) {
+	registries := &imageRegistries{mirrors: qapi.ImageMirrors{{Source: "docker.io", Mirror: "mirror.example.com/dockerhub"}}}
+	if got := registries.sourceImage("nginx:1.17"); got != "mirror.example.com/dockerhub/library/nginx:1.17" {
+		t.Errorf("sourceImage() = %v, want the image of the mirror", got)
+	}
+	if got := registries.sourceImage("quay.io/qlik/engine:1.0"); got != "quay.io/qlik/engine:1.0" {
+		t.Errorf("sourceImage() = %v, want the image itself", got)
+	}
+	var noRegistries *imageRegistries
+	if got := noRegistries.sourceImage("nginx:1.17"); got != "nginx:1.17" {
+		t.Errorf("sourceImage() = %v, want the image itself", got)
+	}
+}
@@ -0,0 +1,190 @@
+package qliksense
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/pkg/tlsclientconfig"
+	imageTypes "github.com/containers/image/v5/types"
+)
+
+// getRegistryManifestDigest returns the digest of the manifest of the docker image ref from a HEAD request, the
+// manifest itself is not downloaded. The registry is reached with the TLS settings and the credentials of sys,
+// over plain http as well if it is insecure, and with a bearer token if it asks for one.
+func getRegistryManifestDigest(ref imageTypes.ImageReference, sys *imageTypes.SystemContext) (string, error) {
+	named := ref.DockerReference()
+	if named == nil {
+		return "", fmt.Errorf("%v is not an image of a registry", ref.StringWithinTransport())
+	}
+	tagOrDigest := "latest"
+	if digested, ok := named.(reference.Digested); ok {
+		tagOrDigest = digested.Digest().String()
+	} else if tagged, ok := named.(reference.Tagged); ok {
+		tagOrDigest = tagged.Tag()
+	}
+	host := reference.Domain(named)
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: sys.DockerInsecureSkipTLSVerify == imageTypes.OptionalBoolTrue}
+	if sys.DockerCertPath != "" {
+		if err := tlsclientconfig.SetupCertificates(sys.DockerCertPath, tlsConfig); err != nil {
+			return "", err
+		}
+	}
+	transport := tlsclientconfig.NewTransport()
+	transport.TLSClientConfig = tlsConfig
+	client := &http.Client{Transport: transport}
+
+	schemes := []string{"https"}
+	if sys.DockerInsecureSkipTLSVerify == imageTypes.OptionalBoolTrue {
+		schemes = append(schemes, "http")
+	}
+	var err error
+	for _, scheme := range schemes {
+		manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, host, reference.Path(named), tagOrDigest)
+		var digest string
+		if digest, err = headManifest(client, manifestURL, reference.Path(named), sys.DockerAuthConfig); err == nil {
+			return digest, nil
+		}
+	}
+	return "", err
+}
+
+// headManifest requests the manifest at manifestURL with HEAD and returns its Docker-Content-Digest header,
+// answering a challenge of the registry with the credentials
+func headManifest(client *http.Client, manifestURL, repository string, credentials *imageTypes.DockerAuthConfig) (string, error) {
+	res, err := doManifestHead(client, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode == http.StatusUnauthorized {
+		authorization, err := registryAuthorization(client, res.Header.Get("WWW-Authenticate"), repository, credentials)
+		if err != nil {
+			return "", err
+		}
+		if res, err = doManifestHead(client, manifestURL, authorization); err != nil {
+			return "", err
+		}
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reading the manifest %s: %s", manifestURL, res.Status)
+	}
+	digest := res.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("the registry returned no digest of the manifest %s", manifestURL)
+	}
+	return digest, nil
+}
+
+func doManifestHead(client *http.Client, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifest.DefaultRequestedManifestMIMETypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+	return res, nil
+}
+
+// registryAuthorization returns the Authorization header answering the challenge of a registry: the credentials
+// for basic authentication, a token pulling the repository from the realm of the challenge for bearer authentication
+func registryAuthorization(client *http.Client, challenge, repository string, credentials *imageTypes.DockerAuthConfig) (string, error) {
+	scheme, params := parseAuthChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if credentials == nil {
+			return "", fmt.Errorf("the registry requires credentials")
+		}
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		tokenURL, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("invalid realm of the registry challenge %q", challenge)
+		}
+		query := tokenURL.Query()
+		if params["service"] != "" {
+			query.Set("service", params["service"])
+		}
+		query.Set("scope", fmt.Sprintf("repository:%s:pull", repository))
+		tokenURL.RawQuery = query.Encode()
+		req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if credentials != nil {
+			req.SetBasicAuth(credentials.Username, credentials.Password)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("requesting a token from %s: %s", tokenURL.Host, res.Status)
+		}
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+			return "", err
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	}
+	return "", fmt.Errorf("unsupported registry challenge %q", challenge)
+}
+
+// parseAuthChallenge splits a WWW-Authenticate header into its lower case scheme and its parameters
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	scheme := strings.ToLower(parts[0])
+	if len(parts) < 2 {
+		return scheme, params
+	}
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return scheme, params
+}
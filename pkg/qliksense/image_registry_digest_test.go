@@ -0,0 +1,117 @@
+package qliksense
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/containers/image/v5/transports/alltransports"
+	imageTypes "github.com/containers/image/v5/types"
+)
+
+func Test_getRegistryManifestDigest(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			} else if r.URL.Query().Get("scope") != "repository:qlik/engine:pull" || r.URL.Query().Get("service") != "test" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token": "secret"}`)
+		case r.Method != http.MethodHead:
+			t.Errorf("expected only HEAD requests of manifests, got %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/qlik/engine/manifests/1.0":
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name        string
+		image       string
+		credentials *imageTypes.DockerAuthConfig
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "present",
+			image:       host + "/qlik/engine:1.0",
+			credentials: &imageTypes.DockerAuthConfig{Username: "user", Password: "password"},
+			want:        digest,
+		},
+		{
+			name:        "missing",
+			image:       host + "/qlik/engine:2.0",
+			credentials: &imageTypes.DockerAuthConfig{Username: "user", Password: "password"},
+			wantErr:     true,
+		},
+		{
+			name:        "wrong credentials",
+			image:       host + "/qlik/engine:1.0",
+			credentials: &imageTypes.DockerAuthConfig{Username: "user", Password: "wrong"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := alltransports.ParseImageName("docker://" + tt.image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sys := &imageTypes.SystemContext{DockerAuthConfig: tt.credentials, DockerInsecureSkipTLSVerify: imageTypes.OptionalBoolTrue}
+			got, err := getRegistryManifestDigest(ref, sys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getRegistryManifestDigest() error = %v, wantErr %v", err, tt.wantErr)
+			} else if got != tt.want {
+				t.Fatalf("getRegistryManifestDigest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseAuthChallenge(t *testing.T) {
+	tests := []struct {
+		challenge  string
+		wantScheme string
+		wantParams map[string]string
+	}{
+		{
+			challenge:  `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:qlik/engine:pull,push"`,
+			wantScheme: "bearer",
+			wantParams: map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:qlik/engine:pull,push"},
+		},
+		{
+			challenge:  `Basic realm=registry`,
+			wantScheme: "basic",
+			wantParams: map[string]string{"realm": "registry"},
+		},
+		{
+			challenge:  `Basic`,
+			wantScheme: "basic",
+			wantParams: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.challenge, func(t *testing.T) {
+			scheme, params := parseAuthChallenge(tt.challenge)
+			if scheme != tt.wantScheme || !reflect.DeepEqual(params, tt.wantParams) {
+				t.Fatalf("parseAuthChallenge() = %v, %v, want %v, %v", scheme, params, tt.wantScheme, tt.wantParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package qliksense
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+type ImageCommandOptions struct {
+	// Parallel is the number of images transferred at the same time
+	Parallel int
+	// Platform selects the image of manifest lists pull copies, as os/arch[/variant]
+	Platform string
+	// AllPlatforms copies every image of manifest lists
+	AllPlatforms bool
+}
+
+func (o *ImageCommandOptions) parallel() int {
+	if o == nil || o.Parallel < 1 {
+		return 1
+	}
+	return o.Parallel
+}
+
+func (o *ImageCommandOptions) imagePlatform() (*imagePlatform, error) {
+	if o == nil {
+		return defaultImagePlatform, nil
+	}
+	return parseImagePlatform(o.Platform, o.AllPlatforms)
+}
+
+// imageTransferFailure is an image that could not be transferred
+type imageTransferFailure struct {
+	image string
+	err   error
+}
+
+// imageLocks serializes the transfers of images sharing an index directory in the image store,
+// i.e. the same image with and without its default registry
+type imageLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func (l *imageLocks) lock(image string) func() {
+	key := getImageNameParts(image).storeDir("")
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*sync.Mutex{}
+	}
+	m, ok := l.locks[key]
+	if !ok {
+		m = &sync.Mutex{}
+		l.locks[key] = m
+	}
+	l.mu.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+// transferImages runs transfer for every image, with at most parallel images at the same time. With a single
+// worker the progress of every image is printed as it goes, with more only a line per finished image is printed.
+// A failed image does not stop the others, the failures are summarized at the end with retryCommand.
+// transfer returns true if the image was already present at the destination. The progress is written to progress.
+// Once stop is closed no other image is started and qapi.ErrInterrupted is returned, nil never stops.
+func transferImages(progress io.Writer, stop <-chan struct{}, action, retryCommand string, images []string, parallel int, transfer func(image string, out io.Writer) (bool, error)) error {
+	images = uniqueImages(images)
+	if parallel < 1 {
+		parallel = 1
+	}
+	if parallel > len(images) {
+		parallel = len(images)
+	}
+	start := time.Now()
+
+	var (
+		printMu  sync.Mutex
+		done     int
+		present  int
+		failures []*imageTransferFailure
+		locks    imageLocks
+		wg       sync.WaitGroup
+	)
+	jobs := make(chan string)
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range jobs {
+				select {
+				case <-stop:
+					// an image sent as the interrupt came is not started
+					continue
+				default:
+				}
+				out := progress
+				if parallel > 1 {
+					// the output of concurrent transfers would interleave
+					out = ioutil.Discard
+				}
+				unlock := locks.lock(image)
+				imageStart := time.Now()
+				imagePresent, err := transfer(image, out)
+				unlock()
+
+				printMu.Lock()
+				done++
+				if err != nil {
+					failures = append(failures, &imageTransferFailure{image: image, err: err})
+				} else if imagePresent {
+					present++
+				}
+				if parallel > 1 {
+					status := fmt.Sprintf("%s in %v", action, time.Since(imageStart).Round(time.Second))
+					if err != nil {
+						status = fmt.Sprintf("failed: %v", err)
+					} else if imagePresent {
+						status = "already present"
+					}
+					fmt.Fprintf(progress, "[%d/%d] %s %s\n", done, len(images), image, status)
+				} else {
+					if err != nil {
+						fmt.Fprintf(progress, "%v\n", err)
+					}
+					fmt.Fprint(progress, "---\n")
+				}
+				printMu.Unlock()
+			}
+		}()
+	}
+	stopped := false
+	for _, image := range images {
+		select {
+		case jobs <- image:
+			continue
+		case <-stop:
+			stopped = true
+		}
+		break
+	}
+	close(jobs)
+	wg.Wait()
+	if stopped {
+		fmt.Fprintf(progress, "%d of %d images %s before the interrupt\n", done-len(failures), len(images), action)
+		return qapi.ErrInterrupted
+	}
+
+	fmt.Fprintf(progress, "%d of %d images %s in %v, %d of them already present\n", len(images)-len(failures), len(images), action, time.Since(start).Round(time.Second), present)
+	if len(failures) == 0 {
+		return nil
+	}
+	fmt.Fprintf(progress, "%d images failed, retry them with: %s\n", len(failures), retryCommand)
+	for _, failure := range failures {
+		fmt.Fprintf(progress, "  %s: %v\n", failure.image, failure.err)
+	}
+	return fmt.Errorf("%d of %d images failed", len(failures), len(images))
+}
+
+// uniqueImages returns the images without duplicates, in their order
+func uniqueImages(images []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, image := range images {
+		if !seen[image] {
+			seen[image] = true
+			unique = append(unique, image)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,124 @@
+package qliksense
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_transferImages(t *testing.T) {
+	tests := []struct {
+		name     string
+		images   []string
+		parallel int
+		failing  map[string]bool
+		present  map[string]bool
+		wantErr  string
+	}{
+		{
+			name:     "sequential",
+			images:   []string{"qlik/a:1", "qlik/b:1", "qlik/c:1"},
+			parallel: 1,
+		},
+		{
+			name:     "parallel with duplicates",
+			images:   []string{"qlik/a:1", "qlik/b:1", "qlik/a:1", "qlik/c:1", "qlik/d:1"},
+			parallel: 3,
+			present:  map[string]bool{"qlik/b:1": true},
+		},
+		{
+			name:     "failures do not stop the others",
+			images:   []string{"qlik/a:1", "qlik/b:1", "qlik/c:1", "qlik/d:1"},
+			parallel: 2,
+			failing:  map[string]bool{"qlik/b:1": true, "qlik/d:1": true},
+			wantErr:  "2 of 4 images failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu          sync.Mutex
+				transferred []string
+				running     int
+				maxRunning  int
+			)
+			err := transferImages(ioutil.Discard, nil, "pulled", "qliksense pull", tt.images, tt.parallel, func(image string, out io.Writer) (bool, error) {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				running--
+				transferred = append(transferred, image)
+				mu.Unlock()
+				if tt.failing[image] {
+					return false, errors.New("unauthorized")
+				}
+				return tt.present[image], nil
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+			want := uniqueImages(tt.images)
+			sort.Strings(want)
+			sort.Strings(transferred)
+			if strings.Join(transferred, ",") != strings.Join(want, ",") {
+				t.Errorf("expected %v to be transferred once, got %v", want, transferred)
+			}
+			if maxRunning > tt.parallel {
+				t.Errorf("expected at most %d transfers at the same time, got %d", tt.parallel, maxRunning)
+			}
+		})
+	}
+}
+
+func Test_imageLocks(t *testing.T) {
+	locks := imageLocks{}
+	unlock := locks.lock("qlik/engine:1.0")
+	locked := make(chan struct{})
+	go func() {
+		// the same image with its default registry shares the index directory
+		defer locks.lock("docker.io/qlik/engine:1.0")()
+		close(locked)
+	}()
+	// other images, even of the same name from another registry, are not blocked
+	locks.lock("qlik/edge-auth:1.0")()
+	locks.lock("registry.example.com/qlik/engine:1.0")()
+	select {
+	case <-locked:
+		t.Fatal("expected the image with the same name and tag to wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+}
+
+func Test_transferImages_interrupted(t *testing.T) {
+	stop := make(chan struct{})
+	var interrupt sync.Once
+	var transferred []string
+	err := transferImages(ioutil.Discard, stop, "pulled", "qliksense pull", []string{"a:1", "b:1", "c:1"}, 1, func(image string, out io.Writer) (bool, error) {
+		transferred = append(transferred, image)
+		// interrupted while the first image is transferred
+		interrupt.Do(func() { close(stop) })
+		return false, nil
+	})
+	if err != qapi.ErrInterrupted {
+		t.Fatalf("expected the transfer to be interrupted, got %v", err)
+	}
+	if len(transferred) != 1 {
+		t.Errorf("expected no image started after the interrupt, got %v", transferred)
+	}
+}
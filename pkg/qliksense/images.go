@@ -0,0 +1,221 @@
+package qliksense
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/containers/image/v5/transports/alltransports"
+	imageTypes "github.com/containers/image/v5/types"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+	"golang.org/x/net/context"
+)
+
+const (
+	imagesOutputText = "text"
+	imagesOutputJson = "json"
+	imagesOutputCsv  = "csv"
+)
+
+type ImageListCommandOptions struct {
+	Profile string
+	Output  string
+}
+
+// ImageInfo describes an image of an install: where it is pulled from, where push puts it,
+// the digest of its manifest and the compressed size of its config and layers
+type ImageInfo struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// ListImages prints every image an install of the current context, or of the version if given, needs:
+// the images of the profile and the operator, ops-runner and preflight images. The digest and size are read from
+// the image store for pulled images and from the source registry for the others.
+func (q *Qliksense) ListImages(version string, opts *ImageListCommandOptions) error {
+	if opts.Output != imagesOutputText && opts.Output != imagesOutputJson && opts.Output != imagesOutputCsv {
+		return fmt.Errorf("unsupported output %s, use %s, %s or %s", opts.Output, imagesOutputText, imagesOutputJson, imagesOutputCsv)
+	}
+	document := q.out()
+	if opts.Output != imagesOutputText {
+		// keep the output for the document
+		defer q.withOut(os.Stderr)()
+	}
+	infos, err := q.getImageInfos(version, opts.Profile)
+	if err != nil {
+		return err
+	}
+	switch opts.Output {
+	case imagesOutputJson:
+		enc := json.NewEncoder(document)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	case imagesOutputCsv:
+		return writeImageInfosCsv(document, infos)
+	}
+	return printImageInfos(document, infos)
+}
+
+func (q *Qliksense) getImageInfos(version, profile string) ([]*ImageInfo, error) {
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		fmt.Fprintln(q.out(), "cannot get the current-context cr", err)
+		return nil, err
+	}
+	images, err := q.getImagesOfVersion(qConfig, qcr, version, profile)
+	if err != nil {
+		return nil, err
+	}
+	imagesDir, err := setupImagesDir(q.QliksenseHome)
+	if err != nil {
+		return nil, err
+	}
+
+	registries, err := getImageRegistries(q.out(), qConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer registries.close()
+	registry := qcr.Spec.GetImageRegistry()
+	var infos []*ImageInfo
+	for _, image := range uniqueImages(images) {
+		info := &ImageInfo{
+			Source: image,
+			Target: registries.targetImage(image, registry),
+		}
+		if err := resolveImageInfo(info, imagesDir, registries); err != nil {
+			fmt.Fprintf(q.out(), "WARNING: unable to read the manifest of %s: %v\n", image, err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// getImagesOfVersion returns the images of the profile and the additional images, for the version if given,
+// from the manifests of the current context otherwise
+func (q *Qliksense) getImagesOfVersion(qConfig *qapi.QliksenseConfig, qcr *qapi.QliksenseCR, version, profile string) ([]string, error) {
+	if profile == "" {
+		profile = qcr.Spec.Profile
+	}
+	repoDir := qcr.Spec.ManifestsRoot
+	if version != "" {
+		repoDir = qConfig.BuildRepoPath(version)
+		if exists, err := directoryExists(repoDir); err != nil {
+			return nil, err
+		} else if !exists {
+			encKey, err := qConfig.GetEncryptionKeyFor(qcr.GetName())
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(q.out(), "fetching version [%s] from %s\n", version, qcr.GetFetchUrl())
+			if repoDir, err = fetchToTempDir(qcr.GetFetchUrl(), version, qcr.GetFetchAccessToken(encKey)); err != nil {
+				return nil, err
+			}
+			defer os.RemoveAll(filepath.Dir(repoDir))
+		}
+	} else if !qcr.IsRepoExist() {
+		return nil, errors.New("no manifests found for the current context, please provide a version")
+	}
+	versionOut, err := q.AboutDir(repoDir, profile)
+	if err != nil {
+		return nil, err
+	}
+	images := versionOut.Images
+	if err := q.appendAdditionalImages(&images, qcr); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// resolveImageInfo sets the digest and size of the image, from the image store if the image was pulled
+func resolveImageInfo(info *ImageInfo, imagesDir string, registries *imageRegistries) error {
+	nameTag := getImageNameParts(info.Source)
+	targetDir := nameTag.storeDir(imagesDir)
+	localRef, err := alltransports.ParseImageName(fmt.Sprintf("oci:%v", targetDir))
+	if err != nil {
+		return err
+	}
+	localCtx := imageStoreContext(imagesDir)
+	if pulledDigest, err := readImageSourceDigest(targetDir); err == nil && pulledDigest != "" {
+		if size, err := getImageSize(localRef, localCtx); err == nil {
+			info.Digest = pulledDigest
+			info.Size = size
+			return nil
+		}
+	}
+
+	srcRef, err := alltransports.ParseImageName(fmt.Sprintf("docker://%v", registries.sourceImage(info.Source)))
+	if err != nil {
+		return err
+	}
+	sourceCtx := registries.systemContext(srcRef, defaultImagePlatform.systemContext(&imageTypes.SystemContext{}))
+	if info.Digest, err = getManifestDigest(srcRef, sourceCtx); err != nil {
+		return err
+	}
+	info.Size, err = getImageSize(srcRef, sourceCtx)
+	return err
+}
+
+// getImageSize returns the compressed size of the config and the layers of the image
+func getImageSize(ref imageTypes.ImageReference, sys *imageTypes.SystemContext) (int64, error) {
+	img, err := ref.NewImage(context.Background(), sys)
+	if err != nil {
+		return 0, err
+	}
+	defer img.Close()
+	size := img.ConfigInfo().Size
+	for _, layer := range img.LayerInfos() {
+		size += layer.Size
+	}
+	return size, nil
+}
+
+func printImageInfos(out io.Writer, infos []*ImageInfo) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tTARGET\tDIGEST\tSIZE")
+	for _, info := range infos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Source, info.Target, info.Digest, formatImageSize(info.Size))
+	}
+	return w.Flush()
+}
+
+func writeImageInfosCsv(out io.Writer, infos []*ImageInfo) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"source", "target", "digest", "size"}); err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if err := w.Write([]string{info.Source, info.Target, info.Digest, strconv.FormatInt(info.Size, 10)}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// formatImageSize formats a size in bytes with decimal units, as docker images does
+func formatImageSize(size int64) string {
+	if size <= 0 {
+		return ""
+	}
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	value := float64(size)
+	i := 0
+	for value >= 1000 && i < len(units)-1 {
+		value /= 1000
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", size, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
@@ -0,0 +1,92 @@
+package qliksense
+
+import (
+	"bytes"
+	"testing"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_getTargetImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		registry string
+		mapping  *qapi.ImageRegistryMapping
+		want     string
+	}{
+		{image: "qlik/engine:12.585.0", registry: "", want: "qlik/engine:12.585.0"},
+		{image: "qlik/engine:12.585.0", registry: "registry.example.com", want: "registry.example.com/engine:12.585.0"},
+		{image: "docker.io/bitnami/mongodb:4.0", registry: "registry.example.com/qlik", want: "registry.example.com/qlik/mongodb:4.0"},
+		{image: "qlik/edge-auth", registry: "registry.example.com", want: "registry.example.com/edge-auth:latest"},
+		{image: "qlik/engine:12.585.0", registry: "registry.example.com", mapping: &qapi.ImageRegistryMapping{Strategy: qapi.ImageMappingPreserve},
+			want: "registry.example.com/qlik/engine:12.585.0"},
+		{image: "nginx:1.19.0-alpine", registry: "https://registry.example.com", mapping: &qapi.ImageRegistryMapping{Strategy: qapi.ImageMappingPreserve},
+			want: "registry.example.com/library/nginx:1.19.0-alpine"},
+		{image: "qlik-docker-oss.bintray.io/preflight-netcat:v1.0.0", registry: "registry.example.com", mapping: &qapi.ImageRegistryMapping{Strategy: qapi.ImageMappingPrefix, Prefix: "qlik"},
+			want: "registry.example.com/qlik/preflight-netcat:v1.0.0"},
+		{image: "docker.io/bitnami/mongodb:4.0", registry: "registry.example.com", mapping: &qapi.ImageRegistryMapping{
+			Strategy: qapi.ImageMappingPrefix, Prefix: "qlik", Rules: []qapi.ImageMappingRule{{Source: "bitnami/mongodb", Target: "third-party/mongodb"}}},
+			want: "registry.example.com/third-party/mongodb:4.0"},
+	}
+	for _, tt := range tests {
+		if got := getTargetImage(tt.image, tt.registry, tt.mapping); got != tt.want {
+			t.Errorf("getTargetImage(%q, %q) = %q, want %q", tt.image, tt.registry, got, tt.want)
+		}
+	}
+}
+
+func Test_formatImageSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{size: 0, want: ""},
+		{size: 512, want: "512B"},
+		{size: 1500, want: "1.5kB"},
+		{size: 123456789, want: "123.5MB"},
+		{size: 2000000000, want: "2.0GB"},
+	}
+	for _, tt := range tests {
+		if got := formatImageSize(tt.size); got != tt.want {
+			t.Errorf("formatImageSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func Test_writeImageInfos(t *testing.T) {
+	infos := []*ImageInfo{
+		{
+			Source: "qlik/engine:12.585.0",
+			Target: "registry.example.com/engine:12.585.0",
+			Digest: "sha256:0123",
+			Size:   123456789,
+		},
+		{
+			Source: "qlik/edge-auth:1.0",
+			Target: "registry.example.com/edge-auth:1.0",
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := writeImageInfosCsv(buf, infos); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantCsv := `source,target,digest,size
+qlik/engine:12.585.0,registry.example.com/engine:12.585.0,sha256:0123,123456789
+qlik/edge-auth:1.0,registry.example.com/edge-auth:1.0,,0
+`
+	if buf.String() != wantCsv {
+		t.Errorf("expected csv:\n%s\ngot:\n%s", wantCsv, buf.String())
+	}
+
+	buf.Reset()
+	if err := printImageInfos(buf, infos[:1]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantText := `SOURCE                TARGET                                DIGEST       SIZE
+qlik/engine:12.585.0  registry.example.com/engine:12.585.0  sha256:0123  123.5MB
+`
+	if buf.String() != wantText {
+		t.Errorf("expected text:\n%s\ngot:\n%s", wantText, buf.String())
+	}
+}
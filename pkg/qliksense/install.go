@@ -7,15 +7,14 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mattn/go-tty"
 
-	"github.com/mitchellh/go-homedir"
 	"github.com/qlik-oss/k-apis/pkg/config"
-	"github.com/qlik-oss/k-apis/pkg/cr"
 	"sigs.k8s.io/kustomize/api/filesys"
 
 	qapi "github.com/qlik-oss/sense-installer/pkg/api"
@@ -30,6 +29,17 @@ type InstallCommandOptions struct {
 	Push            bool
 	CleanPatchFiles bool
 	RotateKeys      bool
+	Wait            bool
+	WaitTimeout     time.Duration
+	ForceUnlock     bool
+	// PinDigests rewrites the images of the manifests to the digests of their manifests
+	PinDigests bool
+	// Bundle is an archive of qliksense bundle create to fetch and pull from instead of git and the registries
+	Bundle string
+	// Images are the options of the image pull and push
+	Images ImageCommandOptions
+	// Reporter receives the progress events of the install phases, may be nil
+	Reporter Reporter
 }
 
 const (
@@ -39,26 +49,65 @@ const (
 )
 
 func (q *Qliksense) InstallQK8s(version string, opts *InstallCommandOptions) error {
+	action := "install"
+	if opts.RotateKeys {
+		action = "keys rotate"
+	}
+	return q.installQK8s(version, opts, action)
+}
+
+// installQK8s installs the current CR and records the given action in the revision history of the context
+func (q *Qliksense) installQK8s(version string, opts *InstallCommandOptions, action string) error {
 
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	qcr, err := qConfig.GetCurrentCR()
 	if err != nil {
-		fmt.Println("cannot get the current-context cr", err)
+		fmt.Fprintln(q.out(), "cannot get the current-context cr", err)
 		return err
 	}
 
-	if !qcr.IsRepoExist() {
-		if err := fetchAndUpdateCR(qConfig, version); err != nil {
+	r := opts.Reporter
+	pullImages := func() error {
+		return q.PullImages(version, "", &opts.Images)
+	}
+	if opts.Bundle != "" {
+		b, err := openBundle(q.out(), opts.Bundle, q.QliksenseHome)
+		if err != nil {
+			return err
+		}
+		defer b.close()
+		if version != "" && version != b.metadata.Version {
+			return fmt.Errorf("bundle %s is of version %s, not %s", opts.Bundle, b.metadata.Version, version)
+		}
+		if err := RunPhase(r, PhaseFetch, func() error {
+			return b.importRepo(qConfig)
+		}); err != nil {
+			return err
+		} else if qcr, err = qConfig.GetCurrentCR(); err != nil {
+			return err
+		}
+		pullImages = func() error {
+			return b.importImages(q.QliksenseHome)
+		}
+	} else if !qcr.IsRepoExist() {
+		if err := RunPhase(r, PhaseFetch, func() error {
+			return fetchAndUpdateCR(q.out(), qConfig, version)
+		}); err != nil {
 			return err
 		} else if qcr, err = qConfig.GetCurrentCR(); err != nil {
 			return err
 		}
+	} else {
+		SkipPhase(r, PhaseFetch, "version already fetched")
 	}
 
-	if opts.AcceptEULA != "" && opts.AcceptEULA != "yes" {
-		enforceEula()
-	} else if opts.AcceptEULA == "" && !qcr.IsEULA() {
-		enforceEula()
+	if err := RunPhase(r, PhaseEula, func() error {
+		if (opts.AcceptEULA != "" && opts.AcceptEULA != "yes") || (opts.AcceptEULA == "" && !qcr.IsEULA()) {
+			return q.enforceEula()
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 	qcr.SetEULA("yes")
 
@@ -75,65 +124,54 @@ func (q *Qliksense) InstallQK8s(version string, opts *InstallCommandOptions) err
 
 	if opts.CleanPatchFiles {
 		if err := q.DiscardAllUnstagedChangesFromGitRepo(qConfig); err != nil {
-			fmt.Printf("error removing temporary changes to the config: %v\n", err)
+			fmt.Fprintf(q.out(), "error removing temporary changes to the config: %v\n", err)
 		}
 	}
 
 	// for debugging purpose
 	if opts.DryRun {
 		// generate patches
-		userHomeDir, _ := homedir.Dir()
-		fmt.Println("Generating patches only")
-		cr.GeneratePatches(&qcr.KApiCr, config.KeysActionDoNothing, path.Join(userHomeDir, ".kube", "config"))
-		return nil
+		fmt.Fprintln(q.out(), "Generating patches only")
+		return RunPhase(r, PhaseRender, func() error {
+			return q.generatePatches(qcr, config.KeysActionDoNothing)
+		})
 	}
 
-	if installed, err := q.CheckAllCrdsInstalled(); err != nil {
-		fmt.Println("error verifying whether CRDs are installed", err)
+	unlock, err := q.lockInstallNamespace(action, getTargetNamespace(), opts.ForceUnlock)
+	if err != nil {
 		return err
-	} else if !installed {
-		return errors.New(`please install CRDs by executing: $ qliksense crds install`)
 	}
+	defer unlock()
 
-	if err := validatePullPushFlagsOnInstall(qcr, opts.Pull, opts.Push); err != nil {
-		return err
-	}
-	if opts.Pull {
-		fmt.Println("Pulling images...")
-		if err := q.PullImages(version, ""); err != nil {
+	if err := RunPhase(r, PhaseCrdCheck, func() error {
+		if installed, err := q.CheckAllCrdsInstalled(); err != nil {
+			fmt.Fprintln(q.out(), "error verifying whether CRDs are installed", err)
 			return err
+		} else if !installed {
+			return errors.New(`please install CRDs by executing: $ qliksense crds install`)
 		}
-	}
-	if opts.Push {
-		fmt.Println("Pushing images...")
-		if err := q.PushImagesForCurrentCR(); err != nil {
-			return err
-		}
-	}
-
-	if err := applyImagePullSecret(qConfig); err != nil {
+		return nil
+	}); err != nil {
 		return err
 	}
 
-	//CRD will be installed outside of operator
-	//install operator controller into the namespace
-	fmt.Println("Installing operator controller")
-	if operatorControllerString, err := q.getProcessedOperatorControllerString(qcr); err != nil {
-		fmt.Println("error extracting/transforming operator controller", err)
+	if err := validatePullPushFlagsOnInstall(q.out(), qcr, opts.Pull, opts.Push); err != nil {
 		return err
-	} else if err := qapi.KubectlApply(operatorControllerString, ""); err != nil {
-		fmt.Println("cannot do kubectl apply on operator controller", err)
+	}
+	// the images of a bundle are always loaded
+	if err := q.pullAndPushImages(r, opts.Pull || opts.Bundle != "", opts.Push, &opts.Images, pullImages); err != nil {
 		return err
 	}
 
-	// create patch dependent resources
-	fmt.Println("Installing resources used by the kuztomize patch")
-	if err := q.createK8sResourceBeforePatch(qcr); err != nil {
+	if err := q.checkInterrupted(); err != nil {
+		return err
+	}
+	if err := q.installOperatorAndPatchResources(qConfig, qcr, r); err != nil {
 		return err
 	}
 
 	if opts.RotateKeys {
-		fmt.Println("Deleting stored application keys")
+		fmt.Fprintln(q.out(), "Deleting stored application keys")
 		if err := q.DeleteKeysClusterBackup(); err != nil {
 			return err
 		} else {
@@ -144,56 +182,196 @@ func (q *Qliksense) InstallQK8s(version string, opts *InstallCommandOptions) err
 		}
 	}
 
-	if qcr.Spec.OpsRunner != nil {
-		// fetching and applying manifest will be in the operator controller
-		// get decrypted cr
-		if dcr, err := qConfig.GetDecryptedCr(qcr); err != nil {
+	if err := q.checkInterrupted(); err != nil {
+		return err
+	}
+	if err := q.applyManifestsAndCR(qConfig, qcr, action, false, opts.PinDigests, r); err != nil {
+		return err
+	}
+	if opts.Wait {
+		return RunPhase(r, PhaseWait, func() error {
+			return q.waitForRollout(qConfig, qcr.GetName(), opts.WaitTimeout)
+		})
+	}
+	return nil
+}
+
+// pullAndPushImages pulls the images with pullImages and pushes them to the image registry of the current CR,
+// as requested. A phase not requested is reported as skipped.
+func (q *Qliksense) pullAndPushImages(r Reporter, pull, push bool, imageOpts *ImageCommandOptions, pullImages func() error) error {
+	if pull {
+		if err := RunPhase(r, PhasePull, func() error {
+			fmt.Fprintln(q.out(), "Pulling images...")
+			return pullImages()
+		}); err != nil {
 			return err
-		} else {
-			return q.applyCR(dcr)
 		}
+	} else {
+		SkipPhase(r, PhasePull, "--pull not set")
 	}
+	if push {
+		return RunPhase(r, PhasePush, func() error {
+			fmt.Fprintln(q.out(), "Pushing images...")
+			return q.PushImagesForCurrentCR(imageOpts)
+		})
+	}
+	SkipPhase(r, PhasePush, "--push not set")
+	return nil
+}
+
+// installOperatorAndPatchResources applies the image pull secret, the operator controller
+// and the resources the kustomize patches depend on
+func (q *Qliksense) installOperatorAndPatchResources(qConfig *qapi.QliksenseConfig, qcr *qapi.QliksenseCR, r Reporter) error {
+	if err := RunPhase(r, PhaseOperator, func() error {
+		if err := q.applyImagePullSecret(qConfig, qcr); err != nil {
+			return err
+		}
 
-	// install generated manifests into cluster
-	fmt.Println("Installing generated manifests into the cluster")
-	if dcr, err := qConfig.GetDecryptedCr(qcr); err != nil {
+		//CRD will be installed outside of operator
+		//install operator controller into the namespace
+		fmt.Fprintln(q.out(), "Installing operator controller")
+		if operatorControllerString, err := q.getProcessedOperatorControllerString(qcr); err != nil {
+			fmt.Fprintln(q.out(), "error extracting/transforming operator controller", err)
+			return err
+		} else if err := q.applyAndRecord(qConfig, qcr.GetName(), qapi.InventoryOperator, operatorControllerString, ""); err != nil {
+			fmt.Fprintln(q.out(), "cannot apply operator controller", err)
+			return err
+		}
+		return nil
+	}); err != nil {
 		return err
-	} else if err := q.applyConfigToK8s(dcr); err != nil {
-		fmt.Println("cannot do kubectl apply on manifests")
+	}
+
+	// create patch dependent resources
+	return RunPhase(r, PhaseSecrets, func() error {
+		fmt.Fprintln(q.out(), "Installing resources used by the kuztomize patch")
+		return q.createK8sResourceBeforePatch(qcr)
+	})
+}
+
+// applyManifestsAndCR generates and applies the manifests for the CR, then applies the CR itself.
+// What was applied is saved as a new revision of the context. With pinDigests the images of the manifests are
+// pinned to their digests.
+func (q *Qliksense) applyManifestsAndCR(qConfig *qapi.QliksenseConfig, qcr *qapi.QliksenseCR, action string, prune, pinDigests bool, r Reporter) error {
+	// get decrypted cr
+	dcr, err := qConfig.GetDecryptedCr(qcr)
+	if err != nil {
 		return err
+	}
+	var mByte []byte
+	if dcr.Spec.OpsRunner == nil {
+		if err := RunPhase(r, PhaseRender, func() error {
+			if mByte, err = q.generateManifests(dcr, config.KeysActionRestoreOrRotate); err != nil || !pinDigests {
+				return err
+			}
+			mByte, err = q.pinManifestDigests(qConfig, dcr, mByte)
+			return err
+		}); err != nil {
+			return err
+		}
 	} else {
-		return q.applyCR(dcr)
+		SkipPhase(r, PhaseRender, "manifests are rendered by the operator with opsRunner")
 	}
+	return RunPhase(r, PhaseApply, func() error {
+		if mByte != nil {
+			// install generated manifests into cluster
+			fmt.Fprintln(q.out(), "Installing generated manifests into the cluster")
+			if err := q.applyContextManifests(qConfig, dcr.GetName(), mByte, dcr.GetNamespace(), prune); err != nil {
+				return err
+			}
+		}
+		// with opsRunner fetching and applying manifest will be in the operator controller
+		if err := q.applyCR(dcr); err != nil {
+			return err
+		}
+		return q.saveRevision(qConfig, dcr, mByte, action)
+	})
 }
 
 func (q *Qliksense) getProcessedOperatorControllerString(qcr *qapi.QliksenseCR) (string, error) {
 	operatorControllerString := q.GetOperatorControllerString()
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	operatorImageRepo := path.Join(qliksenseOperatorImageRepo, qliksenseOperatorImageName)
 	if imageRegistry := qcr.Spec.GetImageRegistry(); imageRegistry != "" {
-		return kustomizeForImageRegistry(operatorControllerString, pullSecretName,
-			path.Join(qliksenseOperatorImageRepo, qliksenseOperatorImageName),
-			path.Join(imageRegistry, qliksenseOperatorImageName))
+		mapping, err := qConfig.GetImageRegistryMapping()
+		if err != nil {
+			return "", err
+		}
+		return kustomizeForImageRegistry(operatorControllerString, pullSecretName, map[string]string{
+			operatorImageRepo: mapping.TargetRepository(operatorImageRepo, imageRegistry),
+		})
+	}
+	// without an image registry the operator image is pulled from its source registry, through the mirrors
+	mirrors, err := qConfig.GetImageMirrors()
+	if err != nil {
+		return "", err
+	} else if mirrored := mirrors.MirrorImage(operatorImageRepo); mirrored != operatorImageRepo {
+		return kustomizeForImageRegistry(operatorControllerString, "", map[string]string{operatorImageRepo: mirrored})
 	}
 	return operatorControllerString, nil
 }
 
-func applyImagePullSecret(qConfig *qapi.QliksenseConfig) error {
-	if pullDockerConfigJsonSecret, err := qConfig.GetPullDockerConfigJsonSecret(); err == nil {
+func (q *Qliksense) applyImagePullSecret(qConfig *qapi.QliksenseConfig, qcr *qapi.QliksenseCR) error {
+	if pullDockerConfigJsonSecret, err := getPullDockerConfigJsonSecret(qConfig, qcr); err != nil {
+		return err
+	} else if pullDockerConfigJsonSecret != nil {
 		if dockerConfigJsonSecretYaml, err := pullDockerConfigJsonSecret.ToYaml(""); err != nil {
 			return err
-		} else if err := qapi.KubectlApply(string(dockerConfigJsonSecretYaml), ""); err != nil {
+		} else if err := q.applyAndRecord(qConfig, qConfig.Spec.CurrentContext, qapi.InventoryPullSecret, string(dockerConfigJsonSecretYaml), ""); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func kustomizeForImageRegistry(resources, dockerConfigJsonSecretName, name, newName string) (string, error) {
+// getPullDockerConfigJsonSecret returns the image pull secret of the current context, nil if it has none. The pull secret
+// of a context set with --docker-config is generated from the credentials docker currently has for the image registry.
+func getPullDockerConfigJsonSecret(qConfig *qapi.QliksenseConfig, qcr *qapi.QliksenseCR) (*qapi.DockerConfigJsonSecret, error) {
+	if fromDockerConfig, err := qConfig.IsPullSecretFromDockerConfig(); err != nil {
+		return nil, err
+	} else if !fromDockerConfig {
+		if pullDockerConfigJsonSecret, err := qConfig.GetPullDockerConfigJsonSecret(); err == nil {
+			return pullDockerConfigJsonSecret, nil
+		}
+		return nil, nil
+	}
+	registry := qcr.Spec.GetImageRegistry()
+	pullDockerConfigJsonSecret, err := qapi.GetDockerConfigJsonSecretFromDockerConfig(registry)
+	if err != nil {
+		return nil, err
+	} else if pullDockerConfigJsonSecret == nil {
+		return nil, fmt.Errorf("docker has no credentials for %s, log in with docker login first", registry)
+	}
+	pullDockerConfigJsonSecret.Name = pullSecretName
+	return pullDockerConfigJsonSecret, nil
+}
+
+// kustomizeForImageRegistry renames the images of resources, newNames holds the new name by image name,
+// and adds the image pull secret to the operator deployment unless dockerConfigJsonSecretName is empty
+func kustomizeForImageRegistry(resources, dockerConfigJsonSecretName string, newNames map[string]string) (string, error) {
 	dir, err := ioutil.TempDir("", "")
 	if err != nil {
 		return "", err
 	}
 	defer os.RemoveAll(dir)
 
+	var kustomization strings.Builder
+	kustomization.WriteString("resources:\n- resources.yaml\n")
+	if dockerConfigJsonSecretName != "" {
+		kustomization.WriteString("transformers:\n- addImagePullSecrets.yaml\n")
+	}
+	if len(newNames) > 0 {
+		var names []string
+		for name := range newNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		kustomization.WriteString("images:\n")
+		for _, name := range names {
+			fmt.Fprintf(&kustomization, "- name: %s\n  newName: %s\n", name, newNames[name])
+		}
+	}
+
 	if err := ioutil.WriteFile(filepath.Join(dir, "resources.yaml"), []byte(resources), os.ModePerm); err != nil {
 		return "", err
 	} else if err := ioutil.WriteFile(filepath.Join(dir, "addImagePullSecrets.yaml"), []byte(fmt.Sprintf(`
@@ -207,15 +385,7 @@ target:
   kind: Deployment
 `, dockerConfigJsonSecretName)), os.ModePerm); err != nil {
 		return "", err
-	} else if err := ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(fmt.Sprintf(`
-resources:
-- resources.yaml
-transformers:
-- addImagePullSecrets.yaml
-images:
-- name: %s
-  newName: %s
-`, name, newName)), os.ModePerm); err != nil {
+	} else if err := ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(kustomization.String()), os.ModePerm); err != nil {
 		return "", err
 	} else if out, err := executeKustomizeBuildForFileSystem(dir, filesys.MakeFsOnDisk()); err != nil {
 		return "", err
@@ -227,13 +397,13 @@ images:
 func (q *Qliksense) applyCR(cr *qapi.QliksenseCR) error {
 	// install operator cr into cluster
 	//get the current context cr
-	fmt.Println("Installing operator CR into the cluster")
+	fmt.Fprintln(q.out(), "Installing operator CR into the cluster")
 	r, err := cr.GetString()
 	if err != nil {
 		return err
 	}
-	if err := qapi.KubectlApply(r, ""); err != nil {
-		fmt.Println("cannot do kubectl apply on operator CR")
+	if err := q.applyAndRecord(qapi.NewQConfig(q.QliksenseHome), cr.GetName(), qapi.InventoryCR, r, ""); err != nil {
+		fmt.Fprintln(q.out(), "cannot apply operator CR")
 		return err
 	}
 	return nil
@@ -243,11 +413,11 @@ func (q *Qliksense) createK8sResourceBeforePatch(qcr *qapi.QliksenseCR) error {
 	for svc, nvs := range qcr.Spec.Secrets {
 		for _, nv := range nvs {
 			if isK8sSecretNeedToCreate(nv) {
-				fmt.Println(filepath.Join(qcr.GetK8sSecretsFolder(q.QliksenseHome), svc+".yaml"))
+				fmt.Fprintln(q.out(), filepath.Join(qcr.GetK8sSecretsFolder(q.QliksenseHome), svc+".yaml"))
 				if secS, err := q.PrepareK8sSecret(filepath.Join(qcr.GetK8sSecretsFolder(q.QliksenseHome), svc+".yaml")); err != nil {
 					return err
 				} else {
-					return qapi.KubectlApply(secS, "")
+					return q.applyAndRecord(qapi.NewQConfig(q.QliksenseHome), qcr.GetName(), qapi.InventoryPatchResources, secS, "")
 				}
 			}
 		}
@@ -259,14 +429,15 @@ func isK8sSecretNeedToCreate(nv config.NameValue) bool {
 	return nv.ValueFrom != nil
 }
 
-func enforceEula() {
-	fmt.Println(eulaText)
-	fmt.Print(eulaPrompt)
+// enforceEula asks to accept the EULA, an error if it is not accepted
+func (q *Qliksense) enforceEula() error {
+	fmt.Fprintln(q.out(), eulaText)
+	fmt.Fprint(q.out(), eulaPrompt)
 	answer := readAnswerFromTty()
 	if strings.ToLower(answer) != "y" {
-		fmt.Println(eulaErrorInstruction)
-		os.Exit(1)
+		return errors.New(eulaErrorInstruction)
 	}
+	return nil
 }
 
 func readAnswerFromTty() string {
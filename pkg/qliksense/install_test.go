@@ -164,3 +164,90 @@ spec:
 		controllerImageCheck(t, controllerImage)
 	}
 }
+
+func Test_mapManifestImages(t *testing.T) {
+	tmpQlikSenseHome, err := ioutil.TempDir("", "tmp-qlik-sense-home-")
+	if err != nil {
+		t.Fatalf("unexpected error creating tmp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpQlikSenseHome)
+
+	registry := "registryFoo"
+	setupQliksenseTestDefaultContext(t, tmpQlikSenseHome, fmt.Sprintf(`
+apiVersion: qlik.com/v1
+kind: Qliksense
+metadata:
+  name: qlik-default
+  labels:
+    version: "1.0"
+spec:
+  configs:
+    qliksense:
+    - name: imageRegistry
+      value: %v
+`, registry))
+	q := &Qliksense{
+		QliksenseHome: tmpQlikSenseHome,
+		CrdBox:        packr.New("crds", "./crds"),
+	}
+	imagesDir, err := setupImagesDir(tmpQlikSenseHome)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := q.writeVersionOutput(&VersionOutput{Images: []string{"qlik/engine:1.0", "nginx:1.19"}}, imagesDir, "1.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	if err := qConfig.SetImageRegistryMapping(&qapi.ImageRegistryMapping{Strategy: qapi.ImageMappingPreserve}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		t.Fatalf("unexpected error getting current CR: %v", err)
+	}
+
+	manifests, err := q.mapManifestImages(qcr, []byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: engine
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: nginx:1.19
+      containers:
+      - name: engine
+        image: registryFoo/engine:1.0
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	images, err := getImageList(manifests)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"registryFoo/library/nginx:1.19", "registryFoo/qlik/engine:1.0"}
+	if strings.Join(images, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected images %v, got %v", want, images)
+	}
+
+	// both engines are flattened to registryFoo/engine:1.0, the mapping cannot tell which one the manifests use
+	if err := q.writeVersionOutput(&VersionOutput{Images: []string{"qlik/engine:1.0", "other/engine:1.0"}}, imagesDir, "1.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := q.mapManifestImages(qcr, []byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: engine
+spec:
+  template:
+    spec:
+      containers:
+      - name: engine
+        image: registryFoo/engine:1.0
+`)); err == nil || !strings.Contains(err.Error(), "flattened") {
+		t.Fatalf("expected an error for images flattened alike, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package qliksense
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+// handleInterrupts stops the running command on an interrupt or a termination, or once lost is closed, i.e. the
+// install lock was taken over: the command returns qapi.ErrInterrupted from the next step that checks for it, once its
+// deferred cleanups ran, i.e. the install lock is released. A second interrupt terminates the process at once.
+// The returned function stops handling them.
+func (q *Qliksense) handleInterrupts(lost <-chan struct{}) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	interrupted := make(chan struct{})
+	q.interrupted = interrupted
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			// the next signal gets its default behavior
+			signal.Stop(signals)
+			fmt.Fprintf(q.out(), "Stopping on %v, interrupt again to terminate at once\n", sig)
+			close(interrupted)
+		case <-lost:
+			fmt.Fprintln(q.out(), "Stopping, the install lock was taken over by another command")
+			close(interrupted)
+		case <-stopped:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(stopped)
+	}
+}
+
+// checkInterrupted returns qapi.ErrInterrupted once the command is interrupted
+func (q *Qliksense) checkInterrupted() error {
+	select {
+	case <-q.interrupted:
+		return qapi.ErrInterrupted
+	default:
+		return nil
+	}
+}
@@ -1,9 +1,6 @@
 package qliksense
 
 import (
-	"path"
-
-	"github.com/mitchellh/go-homedir"
 	"github.com/qlik-oss/k-apis/pkg/cr"
 	qapi "github.com/qlik-oss/sense-installer/pkg/api"
 )
@@ -12,10 +9,10 @@ func (q *Qliksense) DeleteKeysClusterBackup() error {
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
 	if qcr, err := qConfig.GetCurrentCR(); err != nil {
 		return err
-	} else if userHomeDir, err := homedir.Dir(); err != nil {
-		return err
-	} else if err := cr.DeleteKeysClusterBackup(&qcr.KApiCr, path.Join(userHomeDir, ".kube", "config")); err != nil {
+	} else if kubeConfigPath, cleanup, err := qapi.WriteKubeConfigToTempFile(); err != nil {
 		return err
+	} else {
+		defer cleanup()
+		return cr.DeleteKeysClusterBackup(&qcr.KApiCr, kubeConfigPath)
 	}
-	return nil
 }
@@ -0,0 +1,111 @@
+package qliksense
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+type ContextBindingOptions struct {
+	KubeConfig  string
+	KubeContext string
+	Namespace   string
+	Unbind      bool
+}
+
+func (o *ContextBindingOptions) isSet() bool {
+	return o.KubeConfig != "" || o.KubeContext != "" || o.Namespace != "" || o.Unbind
+}
+
+// bindContext binds the context to the kube config, kube context and namespace of the options. The kube context
+// is recorded by name, if none is given the current context of the kube config at the time of binding is used.
+func (q *Qliksense) bindContext(contextName string, opts *ContextBindingOptions) error {
+	qConfig := api.NewQConfig(q.QliksenseHome)
+	if opts.Unbind {
+		fmt.Fprintf(q.out(), "Context %s is not bound to a kube context anymore\n", contextName)
+		return qConfig.SetContextBinding(contextName, "", "", "")
+	}
+	kubeConfig := opts.KubeConfig
+	if kubeConfig != "" {
+		var err error
+		if kubeConfig, err = filepath.Abs(kubeConfig); err != nil {
+			return err
+		} else if !api.FileExists(kubeConfig) {
+			return fmt.Errorf("kube config %s does not exist", kubeConfig)
+		}
+	}
+	api.SetKubeConfig(kubeConfig, opts.KubeContext, opts.Namespace)
+	kubeContext, err := api.GetKubeContextName()
+	if err != nil {
+		return err
+	}
+	if err := qConfig.SetContextBinding(contextName, kubeConfig, kubeContext, opts.Namespace); err != nil {
+		return err
+	}
+	fmt.Fprintf(q.out(), "Context %s is bound to kube context %s\n", contextName, kubeContext)
+	return nil
+}
+
+// SelectKubeContext selects the kube config, kube context and namespace the context contextName is bound to, those
+// of the current context if contextName is empty. kubeConfig and kubeContext of --kubeconfig and --kube-context take
+// precedence, but unless force is set they are refused when the kube context they select differs from the bound one.
+func (q *Qliksense) SelectKubeContext(contextName, kubeConfig, kubeContext string, force bool) error {
+	qConfig := api.NewQConfig(q.QliksenseHome)
+	if contextName == "" {
+		contextName = qConfig.Spec.CurrentContext
+	} else if !qConfig.IsContextExist(contextName) {
+		return fmt.Errorf("context name [ %s ] not found", contextName)
+	}
+	ctx := qConfig.GetContext(contextName)
+	if ctx == nil || !ctx.IsBound() {
+		api.SetKubeConfig(kubeConfig, kubeContext, "")
+		return nil
+	}
+	err := checkContextBinding(ctx, kubeConfig, kubeContext)
+	if kubeConfig == "" {
+		kubeConfig = ctx.KubeConfig
+	}
+	if kubeContext == "" {
+		kubeContext = ctx.KubeContext
+	}
+	api.SetKubeConfig(kubeConfig, kubeContext, ctx.Namespace)
+	if err == nil {
+		err = checkActiveKubeContext(ctx)
+	}
+	if err != nil && !force {
+		return fmt.Errorf("%v, use --force-kube-context to run anyway", err)
+	} else if err != nil {
+		fmt.Fprintf(q.out(), "WARNING: %v\n", err)
+	}
+	return nil
+}
+
+// checkActiveKubeContext returns an error if the selected kube config has no kube context the context is bound to
+func checkActiveKubeContext(ctx *api.Context) error {
+	if ctx.KubeContext == "" {
+		return nil
+	}
+	activeKubeContext, err := api.GetKubeContextName()
+	if err != nil {
+		return fmt.Errorf("context %s is bound to kube context %s: %v", ctx.Name, ctx.KubeContext, err)
+	} else if activeKubeContext != ctx.KubeContext {
+		return fmt.Errorf("context %s is bound to kube context %s, but %s is selected", ctx.Name, ctx.KubeContext, activeKubeContext)
+	}
+	return nil
+}
+
+// checkContextBinding returns an error if kubeConfig or kubeContext differ from the ones the context is bound to
+func checkContextBinding(ctx *api.Context, kubeConfig, kubeContext string) error {
+	if kubeConfig != "" && ctx.KubeConfig != "" {
+		if abs, err := filepath.Abs(kubeConfig); err != nil {
+			return err
+		} else if abs != ctx.KubeConfig {
+			return fmt.Errorf("context %s is bound to kube config %s, but %s is selected", ctx.Name, ctx.KubeConfig, abs)
+		}
+	}
+	if kubeContext != "" && ctx.KubeContext != "" && kubeContext != ctx.KubeContext {
+		return fmt.Errorf("context %s is bound to kube context %s, but %s is selected", ctx.Name, ctx.KubeContext, kubeContext)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package qliksense
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_checkContextBinding(t *testing.T) {
+	bound := &api.Context{Name: "prod", KubeConfig: "/etc/kube/prod", KubeContext: "prod-admin", Namespace: "qliksense"}
+	tests := []struct {
+		name        string
+		ctx         *api.Context
+		kubeConfig  string
+		kubeContext string
+		wantErr     bool
+	}{
+		{name: "bound kube context", ctx: bound},
+		{name: "same kube context", ctx: bound, kubeConfig: "/etc/kube/prod", kubeContext: "prod-admin"},
+		{name: "other kube context", ctx: bound, kubeContext: "dev-admin", wantErr: true},
+		{name: "other kube config", ctx: bound, kubeConfig: "/etc/kube/dev", wantErr: true},
+		{name: "namespace only", ctx: &api.Context{Name: "dev", Namespace: "dev"}, kubeConfig: "/etc/kube/dev", kubeContext: "dev-admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkContextBinding(tt.ctx, tt.kubeConfig, tt.kubeContext); (err != nil) != tt.wantErr {
+				t.Errorf("checkContextBinding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQliksense_SelectKubeContext(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	kubeConfig := filepath.Join(tmpDir, "kubeconfig")
+	if err := ioutil.WriteFile(kubeConfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: prod-admin
+  context:
+    cluster: prod
+- name: dev-admin
+  context:
+    cluster: dev
+current-context: dev-admin
+`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer api.SetKubeConfig("", "", "")
+
+	q := &Qliksense{QliksenseHome: filepath.Join(tmpDir, "home")}
+	if err := q.SetUpQliksenseDefaultContext(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.SetUpQliksenseContext("prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	qConfig := api.NewQConfig(q.QliksenseHome)
+	if err := qConfig.SetContextBinding("prod", kubeConfig, "prod-admin", "qliksense"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.SetUpQliksenseContext(DefaultQliksenseContext); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the binding of the context given as argument is selected, not the one of the current context
+	if err := q.SelectKubeContext("prod", "", "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name, err := api.GetKubeContextName(); err != nil || name != "prod-admin" {
+		t.Errorf("expected kube context prod-admin, got %s, %v", name, err)
+	} else if namespace, err := api.GetKubeNamespace(); err != nil || namespace != "qliksense" {
+		t.Errorf("expected namespace qliksense, got %s, %v", namespace, err)
+	}
+	if err := q.SelectKubeContext("prod", "", "dev-admin", false); err == nil {
+		t.Error("expected an error selecting another kube context than the bound one")
+	}
+	if err := q.SelectKubeContext("missing", "", "", false); err == nil {
+		t.Error("expected an error for a missing context")
+	}
+
+	if err := qConfig.SetContextBinding("prod", kubeConfig, "gone-admin", "qliksense"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.SelectKubeContext("prod", "", "", false); err == nil {
+		t.Error("expected an error for a bound kube context missing from the kube config")
+	} else if err := q.SelectKubeContext("prod", "", "", true); err != nil {
+		t.Errorf("unexpected error with force: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package qliksense
 
 import (
 	"bufio"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -40,8 +41,8 @@ func executeKustomizeBuildForFileSystem(directory string, fSys filesys.FileSyste
 	return resMap.AsYaml()
 }
 
-func executeKustomizeBuildWithStdoutProgress(path string) (kuzManifest []byte, err error) {
-	result, err := api.ExecuteTaskWithBlinkingStdoutFeedback(func() (interface{}, error) {
+func executeKustomizeBuildWithProgress(out io.Writer, path string) (kuzManifest []byte, err error) {
+	result, err := api.ExecuteTaskWithBlinkingFeedback(out, func() (interface{}, error) {
 		return ExecuteKustomizeBuild(path)
 	}, "...")
 	if err != nil {
@@ -12,7 +12,7 @@ func (q *Qliksense) LoadCr(crBytes []byte, overwriteExistingContext bool) error
 	if crName, err := q.loadCrStringIntoFileSystem(string(crBytes), overwriteExistingContext); err != nil {
 		return err
 	} else {
-		fmt.Println("cr name: [ " + crName + " ] has been loaded")
+		fmt.Fprintln(q.out(), "cr name: [ "+crName+" ] has been loaded")
 	}
 	return nil
 }
@@ -0,0 +1,64 @@
+package qliksense
+
+import (
+	"fmt"
+	"time"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+// lockInstallNamespace takes the install lock of the namespace for the command, so that no other install,
+// upgrade, uninstall or keys rotate runs against it at the same time. The returned function releases the lock,
+// while the lock is held an interrupt, or another command taking over the lock, stops the command, see handleInterrupts.
+func (q *Qliksense) lockInstallNamespace(command, namespace string, force bool) (func(), error) {
+	lock, err := qapi.NewInstallLockFromKubeConfig(namespace, command)
+	if err != nil {
+		return nil, err
+	}
+	lock.Out = q.out()
+	if err := lock.Acquire(force); err != nil {
+		return nil, err
+	}
+	stopHandlingInterrupts := q.handleInterrupts(lock.Lost())
+	return func() {
+		stopHandlingInterrupts()
+		if err := lock.Release(); err != nil {
+			fmt.Fprintf(q.out(), "cannot release the install lock of namespace %s: %v\n", lock.Namespace, err)
+		}
+	}, nil
+}
+
+// PrintLockStatus prints who holds the install lock of the target namespace
+func (q *Qliksense) PrintLockStatus() error {
+	lock, err := qapi.NewInstallLockFromKubeConfig(getTargetNamespace(), "")
+	if err != nil {
+		return err
+	}
+	status, err := lock.Status()
+	if err != nil {
+		return err
+	} else if status == nil {
+		fmt.Fprintf(q.out(), "Namespace %s is not locked\n", lock.Namespace)
+		return nil
+	}
+	fmt.Fprintf(q.out(), "Namespace: %s\n", status.Namespace)
+	fmt.Fprintf(q.out(), "Holder:    %s\n", status.Holder)
+	fmt.Fprintf(q.out(), "Command:   %s\n", status.Command)
+	fmt.Fprintf(q.out(), "Acquired:  %s\n", status.AcquireTime.Format(time.RFC3339))
+	fmt.Fprintf(q.out(), "Renewed:   %s\n", status.RenewTime.Format(time.RFC3339))
+	if status.Expired(time.Now()) {
+		fmt.Fprintln(q.out(), "Expired:   yes, the next install takes over the lock")
+	} else {
+		fmt.Fprintf(q.out(), "Expires:   %s\n", status.RenewTime.Add(status.Duration).Format(time.RFC3339))
+	}
+	return nil
+}
+
+// getTargetNamespace returns the namespace of the selected kube context, default if it has none
+func getTargetNamespace() string {
+	// a kube config that cannot be loaded fails the commands using the namespace
+	if namespace, _ := qapi.GetKubeNamespace(); namespace != "" {
+		return namespace
+	}
+	return "default"
+}
@@ -0,0 +1,122 @@
+package qliksense
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/qlik-oss/k-apis/pkg/config"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+// contextOwnerLabels are the labels of every object applied for the context
+func contextOwnerLabels(contextName string) map[string]string {
+	return map[string]string{qapi.OwnerLabel: contextName}
+}
+
+// newContextApplyEngine returns an apply engine that stamps every object with the owner label of the context
+// and the revision annotation
+func (q *Qliksense) newContextApplyEngine(contextName string, revision int) (*qapi.ApplyEngine, error) {
+	engine, err := qapi.NewApplyEngineFromKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	engine.Out = q.out()
+	engine.Labels = contextOwnerLabels(contextName)
+	engine.Annotations = map[string]string{qapi.RevisionAnnotation: strconv.Itoa(revision)}
+	return engine, nil
+}
+
+// applyContextManifests applies the manifests for the context as its next revision. With prune the objects
+// of the context that are no longer in the manifests are deleted afterwards.
+func (q *Qliksense) applyContextManifests(qConfig *qapi.QliksenseConfig, contextName string, manifests []byte, namespace string, prune bool) error {
+	var previousManifests []byte
+	if prune {
+		var err error
+		if previousManifests, err = getLatestRevisionManifests(qConfig, contextName); err != nil {
+			return err
+		}
+	}
+	revision, err := qConfig.NextRevisionNumber(contextName)
+	if err != nil {
+		return err
+	}
+	engine, err := q.newContextApplyEngine(contextName, revision)
+	if err != nil {
+		return err
+	}
+	if err := engine.Apply(string(manifests), namespace); err != nil {
+		fmt.Fprintln(q.out(), "cannot apply manifests")
+		return err
+	} else if err := recordInventory(qConfig, engine, contextName, qapi.InventoryManifests, string(manifests), namespace); err != nil {
+		return err
+	}
+	if !prune {
+		return nil
+	}
+	fmt.Fprintln(q.out(), "Pruning resources that are no longer in the manifests")
+	pruned, err := engine.Prune(string(manifests), string(previousManifests), namespace, contextOwnerLabels(contextName), false)
+	printPrunedObjects(q.out(), pruned, false)
+	if err != nil {
+		fmt.Fprintln(q.out(), "cannot prune resources")
+	}
+	return err
+}
+
+// reportPrunableObjects prints the objects of the context an upgrade to the current CR would delete
+func (q *Qliksense) reportPrunableObjects(qConfig *qapi.QliksenseConfig, qcr *qapi.QliksenseCR) error {
+	dcr, err := qConfig.GetDecryptedCr(qcr)
+	if err != nil {
+		return err
+	}
+	if dcr.Spec.OpsRunner != nil {
+		// the operator controller applies the manifests
+		return nil
+	}
+	manifests, err := q.generateManifests(dcr, config.KeysActionDoNothing)
+	if err != nil {
+		return err
+	}
+	previousManifests, err := getLatestRevisionManifests(qConfig, dcr.GetName())
+	if err != nil {
+		return err
+	}
+	engine, err := qapi.NewApplyEngineFromKubeConfig()
+	if err != nil {
+		return err
+	}
+	engine.Out = q.out()
+	pruned, err := engine.Prune(string(manifests), string(previousManifests), dcr.GetNamespace(), contextOwnerLabels(dcr.GetName()), true)
+	if err != nil {
+		return err
+	}
+	if len(pruned) == 0 {
+		fmt.Fprintln(q.out(), "No resources to prune")
+	}
+	printPrunedObjects(q.out(), pruned, true)
+	return nil
+}
+
+func getLatestRevisionManifests(qConfig *qapi.QliksenseConfig, contextName string) ([]byte, error) {
+	rev, err := qConfig.GetLatestRevision(contextName)
+	if err != nil || rev == nil {
+		return nil, err
+	}
+	return qConfig.GetRevisionManifests(contextName, rev.Number)
+}
+
+func printPrunedObjects(out io.Writer, pruned []*qapi.PrunedObject, dryRun bool) {
+	if len(pruned) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "Resources no longer in the manifests:")
+	for _, p := range pruned {
+		result := "deleted"
+		if p.Kept {
+			result = "kept"
+		} else if dryRun {
+			result = "would be deleted"
+		}
+		fmt.Fprintf(out, "  %s %s\n", p, result)
+	}
+}
@@ -2,6 +2,9 @@
 package qliksense
 
 import (
+	"io"
+	"os"
+
 	"github.com/gobuffalo/packr/v2"
 )
 
@@ -9,6 +12,10 @@ import (
 type Qliksense struct {
 	QliksenseHome string
 	CrdBox        *packr.Box ``
+	// Out receives the progress messages of the commands, os.Stdout if nil
+	Out io.Writer
+	// interrupted is closed when the command is interrupted, see handleInterrupts
+	interrupted chan struct{}
 }
 
 // New qliksense client, initialized with useful defaults.
@@ -20,3 +27,20 @@ func New(qliksenseHome string) *Qliksense {
 
 	return qliksenseClient
 }
+
+// out returns the writer the progress messages of the commands are printed to
+func (q *Qliksense) out() io.Writer {
+	if q.Out == nil {
+		return os.Stdout
+	}
+	return q.Out
+}
+
+// withOut prints the progress messages to out until the returned function is called
+func (q *Qliksense) withOut(out io.Writer) func() {
+	previous := q.Out
+	q.Out = out
+	return func() {
+		q.Out = previous
+	}
+}
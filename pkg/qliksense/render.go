@@ -0,0 +1,214 @@
+package qliksense
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/qlik-oss/k-apis/pkg/config"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+const renderSingleFileName = "qliksense.yaml"
+
+type RenderCommandOptions struct {
+	Out            string
+	SingleFile     bool
+	Secrets        string
+	EncryptCommand string
+	// PinDigests rewrites the images of the manifests to the digests of their manifests
+	PinDigests bool
+}
+
+// renderComponent holds the manifests of one part of an install, written into a directory of its name
+type renderComponent struct {
+	name      string
+	manifests string
+}
+
+// RenderQK8s renders everything an install applies for the current context into a directory, without using
+// the cluster: the CRDs, the operator controller, the image pull secret, the secrets of the kustomize patches,
+// the generated manifests and the CR. Every object is written into a file of its own, in a directory per
+// component, or all of them into one multi-document file.
+func (q *Qliksense) RenderQK8s(opts *RenderCommandOptions) error {
+	if opts.Out == "" {
+		return errors.New("an output directory is required")
+	}
+	renderOpts := &qapi.RenderOptions{Secrets: opts.Secrets, EncryptCommand: opts.EncryptCommand}
+	if err := renderOpts.Validate(); err != nil {
+		return err
+	}
+	if opts.Secrets == qapi.SecretsRedacted {
+		fmt.Fprintln(q.out(), "WARNING: secrets are redacted, the rendered manifests cannot be applied")
+	}
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		fmt.Fprintln(q.out(), "cannot get the current-context cr", err)
+		return err
+	}
+	if !qcr.IsRepoExist() {
+		return errors.New("no manifests found for the current context, please fetch a version first")
+	}
+	components, err := q.getRenderComponents(qConfig, qcr, opts.Secrets, opts.PinDigests)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(opts.Out, os.ModePerm); err != nil {
+		return err
+	}
+	var single bytes.Buffer
+	singlePerm := os.FileMode(0644)
+	count := 0
+	for _, component := range components {
+		objs, err := qapi.RenderObjects(component.manifests, renderOpts)
+		if err != nil {
+			return err
+		}
+		componentDir := filepath.Join(opts.Out, component.name)
+		// objects rendered before may no longer exist
+		if err := os.RemoveAll(componentDir); err != nil {
+			return err
+		}
+		if !opts.SingleFile && len(objs) > 0 {
+			if err := os.MkdirAll(componentDir, os.ModePerm); err != nil {
+				return err
+			}
+		}
+		for _, obj := range objs {
+			perm := os.FileMode(0644)
+			// plain secrets, and the CR with its secret values, are only readable by the user
+			if opts.Secrets == qapi.SecretsPlain && (obj.Secret || component.name == qapi.InventoryCR) {
+				perm = 0600
+				singlePerm = 0600
+			}
+			if opts.SingleFile {
+				single.WriteString("---\n")
+				single.Write(obj.Content)
+			} else if err := ioutil.WriteFile(filepath.Join(componentDir, obj.FileName), obj.Content, perm); err != nil {
+				return err
+			}
+		}
+		count += len(objs)
+	}
+	// WriteFile keeps the permissions of an existing file
+	singleFile := filepath.Join(opts.Out, renderSingleFileName)
+	if err := os.RemoveAll(singleFile); err != nil {
+		return err
+	}
+	if opts.SingleFile {
+		if err := ioutil.WriteFile(singleFile, single.Bytes(), singlePerm); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(q.out(), "Rendered %d objects into %s\n", count, opts.Out)
+	return nil
+}
+
+// getRenderComponents returns the manifests of every component in the order an install applies them
+func (q *Qliksense) getRenderComponents(qConfig *qapi.QliksenseConfig, qcr *qapi.QliksenseCR, secrets string, pinDigests bool) ([]*renderComponent, error) {
+	crds, err := getQliksenseInitCrds(qcr)
+	if err != nil {
+		return nil, err
+	}
+	if customCrds, err := getCustomCrds(qcr); err != nil {
+		return nil, err
+	} else if customCrds != "" {
+		crds = crds + "\n---\n" + customCrds
+	}
+	crds = crds + "\n---\n" + q.GetOperatorCRDString()
+
+	operatorController, err := q.getProcessedOperatorControllerString(qcr)
+	if err != nil {
+		return nil, err
+	}
+
+	pullSecret := ""
+	if pullDockerConfigJsonSecret, err := getPullDockerConfigJsonSecret(qConfig, qcr); err != nil {
+		return nil, err
+	} else if pullDockerConfigJsonSecret != nil {
+		if b, err := pullDockerConfigJsonSecret.ToYaml(""); err != nil {
+			return nil, err
+		} else {
+			pullSecret = string(b)
+		}
+	}
+
+	patchResources, err := q.getPatchResources(qcr)
+	if err != nil {
+		return nil, err
+	}
+
+	dcr, err := qConfig.GetDecryptedCr(qcr)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := q.generateManifests(dcr, config.KeysActionDoNothing)
+	if err != nil {
+		return nil, err
+	}
+	if pinDigests {
+		if manifests, err = q.pinManifestDigests(qConfig, dcr, manifests); err != nil {
+			return nil, err
+		}
+	}
+
+	renderedCr, err := renderCRSecrets(qConfig, dcr, secrets)
+	if err != nil {
+		return nil, err
+	}
+	crString, err := renderedCr.GetString()
+	if err != nil {
+		return nil, err
+	}
+
+	return []*renderComponent{
+		{name: qapi.InventoryCRDs, manifests: crds},
+		{name: qapi.InventoryOperator, manifests: operatorController},
+		{name: qapi.InventoryPullSecret, manifests: pullSecret},
+		{name: qapi.InventoryPatchResources, manifests: patchResources},
+		{name: qapi.InventoryManifests, manifests: string(manifests)},
+		{name: qapi.InventoryCR, manifests: crString},
+	}, nil
+}
+
+// getPatchResources returns the secrets the kustomize patches refer to, as createK8sResourceBeforePatch applies them
+func (q *Qliksense) getPatchResources(qcr *qapi.QliksenseCR) (string, error) {
+	var services []string
+	for svc := range qcr.Spec.Secrets {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+	var resources bytes.Buffer
+	for _, svc := range services {
+		for _, nv := range qcr.Spec.Secrets[svc] {
+			if isK8sSecretNeedToCreate(nv) {
+				secS, err := q.PrepareK8sSecret(filepath.Join(qcr.GetK8sSecretsFolder(q.QliksenseHome), svc+".yaml"))
+				if err != nil {
+					return "", err
+				}
+				resources.WriteString("\n---\n")
+				resources.WriteString(secS)
+				break
+			}
+		}
+	}
+	return resources.String(), nil
+}
+
+// renderCRSecrets returns the decrypted CR with its secret values as the secrets are rendered. The CR is not a
+// secret the encrypt command encrypts, encrypted values are encrypted with the key of the context as the CR is stored.
+func renderCRSecrets(qConfig *qapi.QliksenseConfig, dcr *qapi.QliksenseCR, secrets string) (*qapi.QliksenseCR, error) {
+	switch secrets {
+	case qapi.SecretsRedacted:
+		qapi.RedactCRSecrets(dcr)
+	case qapi.SecretsEncrypted:
+		return qConfig.GetEncryptedCr(dcr)
+	}
+	return dcr, nil
+}
@@ -0,0 +1,70 @@
+package qliksense
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/qlik-oss/k-apis/pkg/config"
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_renderCRSecrets(t *testing.T) {
+	tmpQlikSenseHome, err := ioutil.TempDir("", "tmp-qlik-sense-home-")
+	if err != nil {
+		t.Fatalf("unexpected error creating tmp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpQlikSenseHome)
+	setupQliksenseTestDefaultContext(t, tmpQlikSenseHome, `
+apiVersion: qlik.com/v1
+kind: Qliksense
+metadata:
+  name: qlik-default
+spec:
+  profile: docker-desktop
+`)
+	qConfig := qapi.NewQConfig(tmpQlikSenseHome)
+	decryptedCr := func() *qapi.QliksenseCR {
+		qcr, err := qConfig.GetCurrentCR()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		qcr.Spec.Secrets = map[string]config.NameValues{"qliksense": {{Name: "mongodbUri", Value: "mongodb://mongo"}}}
+		return qcr
+	}
+
+	tests := []struct {
+		name    string
+		secrets string
+		want    string
+	}{
+		{name: "plain", secrets: qapi.SecretsPlain, want: "mongodb://mongo"},
+		{name: "redacted", secrets: qapi.SecretsRedacted, want: "***"},
+		{name: "encrypted", secrets: qapi.SecretsEncrypted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := renderCRSecrets(qConfig, decryptedCr(), tt.secrets)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			value := rendered.Spec.Secrets["qliksense"][0].Value
+			if tt.secrets != qapi.SecretsEncrypted {
+				if value != tt.want {
+					t.Errorf("expected %q, got %q", tt.want, value)
+				}
+				return
+			}
+			if value == "mongodb://mongo" || value == "***" {
+				t.Fatalf("expected an encrypted value, got %q", value)
+			}
+			decrypted, err := qConfig.GetDecryptedCr(rendered)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if value := decrypted.Spec.Secrets["qliksense"][0].Value; value != "mongodb://mongo" {
+				t.Errorf("expected the value to decrypt with the key of the context, got %q", value)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package qliksense
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// phases of an install reported as progress events
+const (
+	PhaseFetch      = "fetch"
+	PhaseEula       = "eula"
+	PhaseCrdCheck   = "crd-check"
+	PhasePull       = "pull"
+	PhasePush       = "push"
+	PhaseOperator   = "operator"
+	PhaseSecrets    = "secrets"
+	PhaseRender     = "render"
+	PhaseApply      = "apply"
+	PhaseWait       = "wait"
+	PhasePostflight = "postflight"
+	// PhaseSummary is reported once at the end of a command, with the outcome of the whole command
+	PhaseSummary = "summary"
+)
+
+// statuses of a phase
+const (
+	StatusStarted   = "started"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// ProgressEvent is reported when a phase starts and when it ends
+type ProgressEvent struct {
+	Phase   string     `json:"phase"`
+	Status  string     `json:"status"`
+	Start   time.Time  `json:"start"`
+	End     *time.Time `json:"end,omitempty"`
+	Error   string     `json:"error,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+// Reporter receives the progress events of a command
+type Reporter interface {
+	Report(event *ProgressEvent)
+}
+
+// RunPhase runs f as the phase, reporting its start and its end. The reporter may be nil.
+func RunPhase(r Reporter, phase string, f func() error) error {
+	if r == nil {
+		return f()
+	}
+	start := time.Now().UTC()
+	r.Report(&ProgressEvent{Phase: phase, Status: StatusStarted, Start: start})
+	err := f()
+	r.Report(endEvent(phase, start, err))
+	return err
+}
+
+// RunCommand runs f as a whole command and reports a summary once it ends, with the phase that failed if any.
+// The reporter may be nil.
+func RunCommand(r Reporter, f func(r Reporter) error) error {
+	if r == nil {
+		return f(nil)
+	}
+	start := time.Now().UTC()
+	failures := &failedPhaseReporter{Reporter: r}
+	err := f(failures)
+	event := endEvent(PhaseSummary, start, err)
+	if err != nil && failures.phase != "" {
+		event.Message = "failed phase: " + failures.phase
+	}
+	r.Report(event)
+	return err
+}
+
+func endEvent(phase string, start time.Time, err error) *ProgressEvent {
+	end := time.Now().UTC()
+	event := &ProgressEvent{Phase: phase, Status: StatusSucceeded, Start: start, End: &end}
+	if err != nil {
+		event.Status = StatusFailed
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// failedPhaseReporter remembers the first phase that failed
+type failedPhaseReporter struct {
+	Reporter
+	mu    sync.Mutex
+	phase string
+}
+
+func (r *failedPhaseReporter) Report(event *ProgressEvent) {
+	r.mu.Lock()
+	if event.Status == StatusFailed && r.phase == "" {
+		r.phase = event.Phase
+	}
+	r.mu.Unlock()
+	r.Reporter.Report(event)
+}
+
+// SkipPhase reports that the phase is skipped. The reporter may be nil.
+func SkipPhase(r Reporter, phase, reason string) {
+	if r == nil {
+		return
+	}
+	now := time.Now().UTC()
+	r.Report(&ProgressEvent{Phase: phase, Status: StatusSkipped, Start: now, End: &now, Message: reason})
+}
+
+// JSONReporter writes every event as a line of JSON
+type JSONReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONReporter(out io.Writer) *JSONReporter {
+	return &JSONReporter{enc: json.NewEncoder(out)}
+}
+
+func (r *JSONReporter) Report(event *ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(event)
+}
+
+// MultiReporter reports every event to all of its reporters
+type MultiReporter []Reporter
+
+func (m MultiReporter) Report(event *ProgressEvent) {
+	for _, r := range m {
+		r.Report(event)
+	}
+}
@@ -0,0 +1,121 @@
+package qliksense
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestRunPhase(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewJSONReporter(buf)
+	if err := RunPhase(r, PhaseFetch, func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RunPhase(r, PhaseApply, func() error { return errors.New("apply failed") }); err == nil {
+		t.Fatal("expected the error of the phase")
+	}
+	SkipPhase(r, PhasePull, "--pull not set")
+
+	tests := []struct {
+		phase  string
+		status string
+		err    string
+		end    bool
+	}{
+		{phase: PhaseFetch, status: StatusStarted},
+		{phase: PhaseFetch, status: StatusSucceeded, end: true},
+		{phase: PhaseApply, status: StatusStarted},
+		{phase: PhaseApply, status: StatusFailed, err: "apply failed", end: true},
+		{phase: PhasePull, status: StatusSkipped, end: true},
+	}
+	scanner := bufio.NewScanner(buf)
+	for _, tt := range tests {
+		if !scanner.Scan() {
+			t.Fatalf("missing event %s %s", tt.phase, tt.status)
+		}
+		event := &ProgressEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			t.Fatalf("event is not a line of json: %v", err)
+		}
+		if event.Phase != tt.phase || event.Status != tt.status || event.Error != tt.err {
+			t.Errorf("expected %s %s %q, got %s %s %q", tt.phase, tt.status, tt.err, event.Phase, event.Status, event.Error)
+		}
+		if event.Start.IsZero() {
+			t.Errorf("expected a start time for %s %s", tt.phase, tt.status)
+		}
+		if (event.End != nil) != tt.end {
+			t.Errorf("expected end time %v for %s %s", tt.end, tt.phase, tt.status)
+		} else if event.End != nil && event.End.Before(event.Start) {
+			t.Errorf("end before start for %s %s", tt.phase, tt.status)
+		}
+	}
+	if scanner.Scan() {
+		t.Errorf("unexpected event %s", scanner.Text())
+	}
+}
+
+func TestRunPhase_withoutReporter(t *testing.T) {
+	called := false
+	if err := RunPhase(nil, PhaseFetch, func() error { called = true; return nil }); err != nil || !called {
+		t.Errorf("expected the phase to run without a reporter")
+	}
+	SkipPhase(nil, PhasePull, "")
+}
+
+func TestRunCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(r Reporter) error
+		status  string
+		message string
+	}{
+		{
+			name: "succeeded",
+			run: func(r Reporter) error {
+				return RunPhase(r, PhaseFetch, func() error { return nil })
+			},
+			status: StatusSucceeded,
+		},
+		{
+			name: "failed phase",
+			run: func(r Reporter) error {
+				return RunPhase(r, PhaseEula, func() error { return errors.New("eula not accepted") })
+			},
+			status:  StatusFailed,
+			message: "failed phase: eula",
+		},
+		{
+			name: "failed outside of a phase",
+			run: func(r Reporter) error {
+				return errors.New("no current context")
+			},
+			status: StatusFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := RunCommand(NewJSONReporter(buf), tt.run)
+			var last *ProgressEvent
+			scanner := bufio.NewScanner(buf)
+			for scanner.Scan() {
+				last = &ProgressEvent{}
+				if err := json.Unmarshal(scanner.Bytes(), last); err != nil {
+					t.Fatalf("event is not a line of json: %v", err)
+				}
+			}
+			if last == nil || last.Phase != PhaseSummary {
+				t.Fatalf("expected the summary as the last event, got %+v", last)
+			}
+			if last.Status != tt.status || last.Message != tt.message || last.End == nil {
+				t.Errorf("expected %s %q, got %+v", tt.status, tt.message, last)
+			}
+			if (err != nil) != (tt.status == StatusFailed) || (err != nil && last.Error != err.Error()) {
+				t.Errorf("unexpected error %v for summary %+v", err, last)
+			}
+		})
+	}
+}
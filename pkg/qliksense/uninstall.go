@@ -2,28 +2,287 @@ package qliksense
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
 
 	qapi "github.com/qlik-oss/sense-installer/pkg/api"
 )
 
-func (q *Qliksense) UninstallQK8s(contextName string, skipConfirmation bool) error {
-	ans := skipConfirmation
+const (
+	namespaceKind     = "Namespace"
+	crdKind           = "CustomResourceDefinition"
+	crDeletionTimeout = 2 * time.Minute
+	purgePVCsPrompt   = "Deleting the persistent volume claims deletes all data of the deployment, continue?"
+	purgeCRDsPrompt   = "Deleting the CRDs deletes all resources of those kinds in the cluster, also of other contexts, continue?"
+)
+
+type UninstallCommandOptions struct {
+	SkipConfirmation bool
+	DryRun           bool
+	PurgePVCs        bool
+	PurgeCRDs        bool
+	ForceUnlock      bool
+}
+
+// uninstallStep is a group of objects that is deleted together
+type uninstallStep struct {
+	title   string
+	objects []*qapi.InventoryObject
+	// wait until the objects are gone before the next step
+	waitForDeletion bool
+}
 
-	if ans == false {
-		ans = AskForConfirmation("Are You Sure? ")
+// UninstallQK8s deletes everything recorded in the inventory of the context: the CR, the generated manifests,
+// the resources the patches depend on, the image pull secret and the operator controller.
+// Namespaces, persistent volume claims and CRDs are only deleted when purged, deleting a namespace deletes its claims.
+func (q *Qliksense) UninstallQK8s(contextName string, opts *UninstallCommandOptions) error {
+	qConfig := qapi.NewQConfig(q.QliksenseHome)
+	if contextName == "" {
+		contextName = qConfig.Spec.CurrentContext
+	} else if !qConfig.IsContextExist(contextName) {
+		return errors.New("context name [ " + contextName + " ] not found")
 	}
-	if ans == true {
-		qConfig := qapi.NewQConfig(q.QliksenseHome)
-		if contextName == "" {
-			contextName = qConfig.Spec.CurrentContext
-		} else if !qConfig.IsContextExist(contextName) {
-			return errors.New("context name [ " + contextName + " ] not found")
+
+	inv, err := qConfig.GetInventory(contextName)
+	if err != nil {
+		return err
+	}
+	if len(inv.Objects) == 0 {
+		fmt.Fprintf(q.out(), "No inventory recorded for context %s, using the resources of its CR and latest revision\n", contextName)
+		if inv, err = q.getInventoryFromContext(qConfig, contextName); err != nil {
+			return err
 		}
-		str, err := q.getCRString(contextName)
+	}
+	engine, err := qapi.NewApplyEngineFromKubeConfig()
+	if err != nil {
+		return err
+	}
+	engine.Out = q.out()
+
+	manifests := inv.ObjectsOf(qapi.InventoryManifests)
+	steps := []*uninstallStep{
+		// the operator controller cleans up for the CR, so it has to be gone before the operator is deleted
+		{title: "Operator CR", objects: inv.ObjectsOf(qapi.InventoryCR), waitForDeletion: true},
+		{title: "Generated manifests", objects: withoutProtectedKinds(manifests)},
+		{title: "Resources used by the kustomize patches", objects: inv.ObjectsOf(qapi.InventoryPatchResources)},
+		{title: "Image pull secret", objects: inv.ObjectsOf(qapi.InventoryPullSecret)},
+		{title: "Operator controller", objects: inv.ObjectsOf(qapi.InventoryOperator)},
+	}
+	if opts.PurgePVCs {
+		claims, err := engine.PersistentVolumeClaims(manifests)
 		if err != nil {
 			return err
 		}
-		return qapi.KubectlDelete(str, "")
+		steps = append(steps,
+			&uninstallStep{title: "Persistent volume claims", objects: claims},
+			&uninstallStep{title: "Namespaces", objects: ofKind(manifests, namespaceKind)})
+	}
+	if opts.PurgeCRDs {
+		crds := append(inv.ObjectsOf(qapi.InventoryCRDs), ofKind(manifests, crdKind)...)
+		steps = append(steps, &uninstallStep{title: "CRDs", objects: crds})
+	}
+	printUninstallSteps(q.out(), contextName, steps, opts)
+	if opts.DryRun {
+		return nil
+	}
+
+	if !opts.SkipConfirmation && !AskForConfirmation("Are You Sure? ") {
+		return nil
+	}
+	// --yes does not confirm the purges, each is confirmed on its own
+	if opts.PurgePVCs && !AskForConfirmation(purgePVCsPrompt) {
+		return nil
+	} else if opts.PurgeCRDs && !AskForConfirmation(purgeCRDsPrompt) {
+		return nil
+	}
+
+	unlock, err := q.lockInstallNamespace("uninstall", getInventoryNamespace(inv), opts.ForceUnlock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, step := range steps {
+		if len(step.objects) == 0 {
+			continue
+		}
+		if err := q.checkInterrupted(); err != nil {
+			return err
+		}
+		fmt.Fprintf(q.out(), "Deleting %s\n", step.title)
+		if err := engine.DeleteObjects(step.objects); err != nil {
+			fmt.Fprintf(q.out(), "cannot delete %s\n", step.title)
+			return err
+		}
+		if step.waitForDeletion {
+			if err := engine.WaitForDeletion(step.objects, crDeletionTimeout); err != nil {
+				fmt.Fprintf(q.out(), "%s not deleted yet, continuing: %v\n", step.title, err)
+			}
+		}
+	}
+
+	if opts.PurgeCRDs {
+		return qConfig.DeleteInventory(contextName)
+	}
+	// the CRDs are still installed
+	return qConfig.WriteInventory(contextName, &qapi.Inventory{Objects: inv.ObjectsOf(qapi.InventoryCRDs)})
+}
+
+// getInventoryFromContext builds the inventory for a context installed before inventories were recorded
+func (q *Qliksense) getInventoryFromContext(qConfig *qapi.QliksenseConfig, contextName string) (*qapi.Inventory, error) {
+	engine, err := qapi.NewApplyEngineFromKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	inv := &qapi.Inventory{}
+	add := func(component, manifests, namespace string) error {
+		objects, err := engine.InventoryObjects(component, manifests, namespace)
+		if err != nil {
+			return err
+		}
+		inv.Add(objects...)
+		return nil
+	}
+
+	qcr, err := qConfig.GetCR(contextName)
+	if err != nil {
+		return nil, err
+	}
+	if crString, err := q.getCRString(contextName); err != nil {
+		return nil, err
+	} else if err := add(qapi.InventoryCR, crString, ""); err != nil {
+		return nil, err
+	}
+	if rev, err := qConfig.GetLatestRevision(contextName); err != nil {
+		return nil, err
+	} else if rev != nil {
+		if manifests, err := qConfig.GetRevisionManifests(contextName, rev.Number); err != nil {
+			return nil, err
+		} else if err := add(qapi.InventoryManifests, string(manifests), rev.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	for svc, nvs := range qcr.Spec.Secrets {
+		for _, nv := range nvs {
+			if !isK8sSecretNeedToCreate(nv) {
+				continue
+			}
+			if secS, err := q.PrepareK8sSecret(filepath.Join(qcr.GetK8sSecretsFolder(q.QliksenseHome), svc+".yaml")); err == nil {
+				if err := add(qapi.InventoryPatchResources, secS, ""); err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+	}
+	if fromDockerConfig, err := qConfig.IsPullSecretFromDockerConfig(); err != nil {
+		return nil, err
+	} else if fromDockerConfig {
+		// the inventory only needs the name of the pull secret, not the credentials docker has now
+		pullSecret := &qapi.DockerConfigJsonSecret{Name: pullSecretName, Uri: qcr.Spec.GetImageRegistry()}
+		if pullSecretYaml, err := pullSecret.ToYaml(""); err != nil {
+			return nil, err
+		} else if err := add(qapi.InventoryPullSecret, string(pullSecretYaml), ""); err != nil {
+			return nil, err
+		}
+	} else if pullSecret, err := qConfig.GetPullDockerConfigJsonSecret(); err == nil {
+		if pullSecretYaml, err := pullSecret.ToYaml(""); err != nil {
+			return nil, err
+		} else if err := add(qapi.InventoryPullSecret, string(pullSecretYaml), ""); err != nil {
+			return nil, err
+		}
+	}
+	if operatorControllerString, err := q.getProcessedOperatorControllerString(qcr); err != nil {
+		return nil, err
+	} else if err := add(qapi.InventoryOperator, operatorControllerString, ""); err != nil {
+		return nil, err
+	}
+	if err := add(qapi.InventoryCRDs, q.GetOperatorCRDString(), ""); err != nil {
+		return nil, err
+	}
+	if qcr.IsRepoExist() {
+		if engineCRD, err := getQliksenseInitCrds(qcr); err != nil {
+			return nil, err
+		} else if err := add(qapi.InventoryCRDs, engineCRD, ""); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+// applyAndRecord applies the manifests and adds their objects to the inventory of the context
+func (q *Qliksense) applyAndRecord(qConfig *qapi.QliksenseConfig, contextName, component, manifests, namespace string) error {
+	engine, err := qapi.NewApplyEngineFromKubeConfig()
+	if err != nil {
+		return err
+	}
+	engine.Out = q.out()
+	if err := engine.Apply(manifests, namespace); err != nil {
+		return err
+	}
+	return recordInventory(qConfig, engine, contextName, component, manifests, namespace)
+}
+
+func recordInventory(qConfig *qapi.QliksenseConfig, engine *qapi.ApplyEngine, contextName, component, manifests, namespace string) error {
+	objects, err := engine.InventoryObjects(component, manifests, namespace)
+	if err != nil {
+		return err
+	}
+	return qConfig.AddToInventory(contextName, objects)
+}
+
+// getInventoryNamespace returns the namespace the CR of the inventory was applied to, the target namespace if none
+func getInventoryNamespace(inv *qapi.Inventory) string {
+	for _, o := range inv.ObjectsOf(qapi.InventoryCR) {
+		if o.Namespace != "" {
+			return o.Namespace
+		}
+	}
+	return getTargetNamespace()
+}
+
+// withoutProtectedKinds returns the objects that are not of a kind only deleted when purged
+func withoutProtectedKinds(objects []*qapi.InventoryObject) []*qapi.InventoryObject {
+	var result []*qapi.InventoryObject
+	for _, o := range objects {
+		if !qapi.IsProtectedKind(o.APIVersion, o.Kind) {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+func ofKind(objects []*qapi.InventoryObject, kind string) []*qapi.InventoryObject {
+	var result []*qapi.InventoryObject
+	for _, o := range objects {
+		if o.Kind == kind {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+func printUninstallSteps(out io.Writer, contextName string, steps []*uninstallStep, opts *UninstallCommandOptions) {
+	if opts.DryRun {
+		fmt.Fprintf(out, "Resources that would be deleted for context %s:\n", contextName)
+	} else {
+		fmt.Fprintf(out, "Resources to delete for context %s:\n", contextName)
+	}
+	for _, step := range steps {
+		if len(step.objects) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "  %s:\n", step.title)
+		for _, o := range step.objects {
+			fmt.Fprintf(out, "    %s\n", o)
+		}
+	}
+	if !opts.PurgePVCs {
+		fmt.Fprintln(out, "Namespaces and persistent volume claims are kept, use --purge-pvcs to delete them")
+	}
+	if !opts.PurgeCRDs {
+		fmt.Fprintln(out, "CRDs are kept, use --purge-crds to delete them")
 	}
-	return nil
 }
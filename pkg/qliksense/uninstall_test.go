@@ -0,0 +1,45 @@
+package qliksense
+
+import (
+	"bytes"
+	"testing"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_printUninstallSteps(t *testing.T) {
+	manifests := []*qapi.InventoryObject{
+		{APIVersion: "apps/v1", Kind: qapi.StatefulSetKind, Namespace: "qliksense", Name: "mongodb"},
+		{APIVersion: "v1", Kind: qapi.PersistentVolumeClaimKind, Namespace: "qliksense", Name: "logs"},
+		{APIVersion: "v1", Kind: namespaceKind, Name: "qliksense"},
+		{APIVersion: "apiextensions.k8s.io/v1beta1", Kind: crdKind, Name: "engines.qixengine.qlik.com"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "qliksense", Name: "namespace"},
+	}
+	steps := []*uninstallStep{
+		{title: "Operator CR", objects: []*qapi.InventoryObject{{APIVersion: "qlik.com/v1", Kind: qapi.QliksenseKind, Namespace: "qliksense", Name: "qlik-default"}}},
+		{title: "Generated manifests", objects: withoutProtectedKinds(manifests)},
+		{title: "Image pull secret"},
+	}
+	var out bytes.Buffer
+	printUninstallSteps(&out, "qlik-default", steps, &UninstallCommandOptions{DryRun: true, PurgeCRDs: true})
+	expected := `Resources that would be deleted for context qlik-default:
+  Operator CR:
+    Qliksense/qliksense/qlik-default
+  Generated manifests:
+    StatefulSet/qliksense/mongodb
+    ConfigMap/qliksense/namespace
+Namespaces and persistent volume claims are kept, use --purge-pvcs to delete them
+`
+	if out.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, out.String())
+	}
+}
+
+func Test_getInventoryNamespace(t *testing.T) {
+	inv := &qapi.Inventory{}
+	inv.Add(&qapi.InventoryObject{Component: qapi.InventoryCRDs, APIVersion: "apiextensions.k8s.io/v1beta1", Kind: crdKind, Name: "qliksenses.qlik.com"},
+		&qapi.InventoryObject{Component: qapi.InventoryCR, APIVersion: "qlik.com/v1", Kind: qapi.QliksenseKind, Namespace: "other", Name: "qlik-other"})
+	if namespace := getInventoryNamespace(inv); namespace != "other" {
+		t.Fatalf("expected the namespace of the CR, got %s", namespace)
+	}
+}
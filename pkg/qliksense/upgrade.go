@@ -1,44 +1,245 @@
 package qliksense
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
 
+	"github.com/jinzhu/copier"
 	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+	"sigs.k8s.io/kustomize/api/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
 )
 
-func (q *Qliksense) UpgradeQK8s(cleanPatchFiles bool) error {
+type UpgradeCommandOptions struct {
+	DryRun          bool
+	Pull            bool
+	Push            bool
+	CleanPatchFiles bool
+	ForceUnlock     bool
+	// Images are the options of the image pull and push
+	Images ImageCommandOptions
+	// Reporter receives the progress events of the upgrade phases, may be nil
+	Reporter Reporter
+}
 
-	// step1: get CR
-	// step2: run kustomize
-	// step3: run kubectl apply
+// VersionChanges holds the images and resources added or removed between two versions
+type VersionChanges struct {
+	FromVersion      string
+	ToVersion        string
+	AddedImages      []string
+	RemovedImages    []string
+	AddedResources   []string
+	RemovedResources []string
+}
 
-	// fetch the version
+type manifestSummary struct {
+	images    []string
+	resources []string
+}
+
+// UpgradeQK8s fetches the target version, shows what changes and applies it to the cluster.
+// The applied manifests are saved as a new revision, so that RollbackQK8s can go back to the previous one.
+// Resources of the context that are no longer in the manifests are pruned, with DryRun they are only reported.
+func (q *Qliksense) UpgradeQK8s(version string, opts *UpgradeCommandOptions) (err error) {
+	if version == "" {
+		return errors.New("version to upgrade to is required")
+	}
 	qConfig := qapi.NewQConfig(q.QliksenseHome)
-	if cleanPatchFiles {
-		if err := q.DiscardAllUnstagedChangesFromGitRepo(qConfig); err != nil {
-			fmt.Printf("error removing temporary changes to the config: %v\n", err)
+	qcr, err := qConfig.GetCurrentCR()
+	if err != nil {
+		fmt.Fprintln(q.out(), "cannot get the current-context cr", err)
+		return err
+	}
+	fromVersion := qcr.GetLabelFromCr("version")
+	if fromVersion == "" || !qcr.IsRepoExist() {
+		return errors.New("no installed version found for the current context, please use: qliksense install")
+	}
+	if fromVersion == version {
+		fmt.Fprintf(q.out(), "version [%s] is already installed\n", version)
+		return nil
+	}
+	if !qcr.IsEULA() {
+		return errors.New(`EULA has not been accepted for the current context, please use: qliksense install`)
+	}
+
+	originalCr := &qapi.QliksenseCR{}
+	copier.Copy(originalCr, qcr)
+	fromManifestsRoot := qcr.Spec.GetManifestsRoot()
+
+	// leave the context on the version it was before unless the new version is applied
+	applied := false
+	defer func() {
+		if applied {
+			return
 		}
+		if restoreErr := qConfig.WriteCurrentContextCR(originalCr); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}()
+
+	if err := RunPhase(opts.Reporter, PhaseFetch, func() error {
+		return q.switchCurrentCRToVersion(qConfig, version)
+	}); err != nil {
+		return err
+	}
+	if qcr, err = qConfig.GetCurrentCR(); err != nil {
+		return err
 	}
 
-	qcr, err := qConfig.GetCurrentCR()
+	changes, err := getVersionChanges(q.out(), fromManifestsRoot, qcr.Spec.GetManifestsRoot(), qcr.Spec.Profile)
 	if err != nil {
-		fmt.Println("cannot get the current-context cr", err)
 		return err
 	}
+	changes.FromVersion = fromVersion
+	changes.ToVersion = version
+	printVersionChanges(q.out(), changes)
+
+	if opts.DryRun {
+		if err := q.reportPrunableObjects(qConfig, qcr); err != nil {
+			fmt.Fprintln(q.out(), "cannot check the cluster for resources to prune", err)
+		}
+		return nil
+	}
 
-	dcr, err := qConfig.GetDecryptedCr(qcr)
+	unlock, err := q.lockInstallNamespace("upgrade", getTargetNamespace(), opts.ForceUnlock)
 	if err != nil {
 		return err
 	}
-	if dcr.Spec.Git != nil && dcr.Spec.Git.Repository != "" {
-		// fetching and applying manifest will be in the operator controller
-		// get decrypted cr
-		return q.applyCR(dcr)
+	defer unlock()
+	if err := q.applyVersion(qConfig, version, opts); err != nil {
+		return err
 	}
-	err = q.applyConfigToK8s(dcr)
+	applied = true
+	return nil
+}
+
+func (q *Qliksense) applyVersion(qConfig *qapi.QliksenseConfig, version string, opts *UpgradeCommandOptions) error {
+	qcr, err := qConfig.GetCurrentCR()
 	if err != nil {
-		fmt.Println("cannot do kubectl apply on manifests")
 		return err
 	}
-	return q.applyCR(dcr)
+	if opts.CleanPatchFiles {
+		if err := q.DiscardAllUnstagedChangesFromGitRepo(qConfig); err != nil {
+			fmt.Fprintf(q.out(), "error removing temporary changes to the config: %v\n", err)
+		}
+	}
+	if err := validatePullPushFlagsOnInstall(q.out(), qcr, opts.Pull, opts.Push); err != nil {
+		return err
+	}
+	if err := q.pullAndPushImages(opts.Reporter, opts.Pull, opts.Push, &opts.Images, func() error {
+		return q.PullImagesForCurrentCR(&opts.Images)
+	}); err != nil {
+		return err
+	}
+	if err := q.checkInterrupted(); err != nil {
+		return err
+	}
+	if err := q.installOperatorAndPatchResources(qConfig, qcr, opts.Reporter); err != nil {
+		return err
+	} else if err := q.checkInterrupted(); err != nil {
+		return err
+	}
+	return q.applyManifestsAndCR(qConfig, qcr, "upgrade", true, false, opts.Reporter)
+}
+
+// switchCurrentCRToVersion points the current CR to the version, fetching the version first if needed
+func (q *Qliksense) switchCurrentCRToVersion(qConfig *qapi.QliksenseConfig, version string) error {
+	if qConfig.IsRepoExistForCurrent(version) {
+		return qConfig.SwitchCurrentCRToVersionAndProfile(version, "")
+	}
+	return fetchAndUpdateCR(q.out(), qConfig, version)
+}
+
+func getVersionChanges(out io.Writer, fromManifestsRoot, toManifestsRoot, profile string) (*VersionChanges, error) {
+	fromSummary, err := getManifestSummary(out, fromManifestsRoot, profile)
+	if err != nil {
+		return nil, err
+	}
+	toSummary, err := getManifestSummary(out, toManifestsRoot, profile)
+	if err != nil {
+		return nil, err
+	}
+	changes := &VersionChanges{}
+	changes.AddedImages, changes.RemovedImages = diffStringLists(fromSummary.images, toSummary.images)
+	changes.AddedResources, changes.RemovedResources = diffStringLists(fromSummary.resources, toSummary.resources)
+	return changes, nil
+}
+
+func getManifestSummary(out io.Writer, manifestsRoot, profile string) (*manifestSummary, error) {
+	kuzManifest, err := executeKustomizeBuildWithProgress(out, filepath.Join(manifestsRoot, "manifests", profile))
+	if err != nil {
+		return nil, err
+	}
+	images, err := getImageList(kuzManifest)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := getResourceList(kuzManifest)
+	if err != nil {
+		return nil, err
+	}
+	return &manifestSummary{
+		images:    images,
+		resources: resources,
+	}, nil
+}
+
+// getResourceList returns sorted Kind/name identifiers of all resources in a multi-doc yaml
+func getResourceList(yamlContent []byte) ([]string, error) {
+	kuzResourceFactory := resmap.NewFactory(resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl()), nil)
+	kuzResMap, err := kuzResourceFactory.NewResMapFromBytes(yamlContent)
+	if err != nil {
+		return nil, err
+	}
+	var resources []string
+	for _, kuzRes := range kuzResMap.Resources() {
+		resources = append(resources, fmt.Sprintf("%s/%s", kuzRes.GetKind(), kuzRes.GetName()))
+	}
+	sort.Strings(resources)
+	return resources, nil
+}
+
+// diffStringLists returns the items only present in newList and the items only present in oldList
+func diffStringLists(oldList, newList []string) (added, removed []string) {
+	oldMap := make(map[string]bool)
+	for _, item := range oldList {
+		oldMap[item] = true
+	}
+	newMap := make(map[string]bool)
+	for _, item := range newList {
+		newMap[item] = true
+		if !oldMap[item] {
+			added = append(added, item)
+		}
+	}
+	for _, item := range oldList {
+		if !newMap[item] {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
+
+func printVersionChanges(out io.Writer, changes *VersionChanges) {
+	fmt.Fprintf(out, "Upgrading from [%s] to [%s]\n", changes.FromVersion, changes.ToVersion)
+	printChangeList(out, "Images", changes.AddedImages, changes.RemovedImages)
+	printChangeList(out, "Resources", changes.AddedResources, changes.RemovedResources)
+}
+
+func printChangeList(out io.Writer, title string, added, removed []string) {
+	if len(added) == 0 && len(removed) == 0 {
+		fmt.Fprintf(out, "%s: no changes\n", title)
+		return
+	}
+	fmt.Fprintf(out, "%s:\n", title)
+	for _, item := range added {
+		fmt.Fprintf(out, "  + %s\n", item)
+	}
+	for _, item := range removed {
+		fmt.Fprintf(out, "  - %s\n", item)
+	}
 }
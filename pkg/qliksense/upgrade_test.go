@@ -0,0 +1,136 @@
+package qliksense
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_diffStringLists(t *testing.T) {
+	tests := []struct {
+		name        string
+		oldList     []string
+		newList     []string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{
+			name:        "no changes",
+			oldList:     []string{"a", "b"},
+			newList:     []string{"a", "b"},
+			wantAdded:   nil,
+			wantRemoved: nil,
+		},
+		{
+			name:        "added and removed",
+			oldList:     []string{"a", "b", "c"},
+			newList:     []string{"b", "c", "d"},
+			wantAdded:   []string{"d"},
+			wantRemoved: []string{"a"},
+		},
+		{
+			name:        "from nothing",
+			oldList:     nil,
+			newList:     []string{"a"},
+			wantAdded:   []string{"a"},
+			wantRemoved: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := diffStringLists(tt.oldList, tt.newList)
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("diffStringLists() added = %v, want %v", added, tt.wantAdded)
+			}
+			if !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("diffStringLists() removed = %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func Test_getResourceList(t *testing.T) {
+	manifests := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: engine
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: engine-configs
+`
+	resources, err := getResourceList([]byte(manifests))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"ConfigMap/engine-configs", "Deployment/engine"}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Fatalf("expected: %v, but got: %v", expected, resources)
+	}
+}
+
+func Test_getVersionChanges(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeVersion := func(version string, resources map[string]string) string {
+		manifestsRoot := filepath.Join(tmpDir, version)
+		profileDir := filepath.Join(manifestsRoot, "manifests", "docker-desktop")
+		if err := os.MkdirAll(profileDir, os.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		kustomization := "resources:\n"
+		for name, content := range resources {
+			kustomization += "- " + name + "\n"
+			if err := ioutil.WriteFile(filepath.Join(profileDir, name), []byte(content), os.ModePerm); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if err := ioutil.WriteFile(filepath.Join(profileDir, "kustomization.yaml"), []byte(kustomization), os.ModePerm); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return manifestsRoot
+	}
+	deployment := func(name, image string) string {
+		return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ` + name + `
+spec:
+  template:
+    spec:
+      containers:
+      - name: main
+        image: ` + image + `
+`
+	}
+	fromRoot := writeVersion("v1.0.0", map[string]string{
+		"engine.yaml": deployment("engine", "qlikcore/engine:1.0"),
+		"edge.yaml":   deployment("edge-auth", "qlik/edge-auth:1.0"),
+	})
+	toRoot := writeVersion("v1.1.0", map[string]string{
+		"engine.yaml": deployment("engine", "qlikcore/engine:1.1"),
+		"hub.yaml":    deployment("hub", "qlik/hub:1.1"),
+	})
+
+	changes, err := getVersionChanges(ioutil.Discard, fromRoot, toRoot, "docker-desktop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &VersionChanges{
+		AddedImages:      []string{"qlik/hub:1.1", "qlikcore/engine:1.1"},
+		RemovedImages:    []string{"qlik/edge-auth:1.0", "qlikcore/engine:1.0"},
+		AddedResources:   []string{"Deployment/hub"},
+		RemovedResources: []string{"Deployment/edge-auth"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected: %+v, but got: %+v", expected, changes)
+	}
+}
@@ -0,0 +1,77 @@
+package qliksense
+
+import (
+	"fmt"
+	"time"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+	"sigs.k8s.io/kustomize/api/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
+)
+
+// waitForRollout waits until the Deployments, StatefulSets and Jobs of the latest revision of the context
+// and the Qliksense CR are ready, then prints a summary
+func (q *Qliksense) waitForRollout(qConfig *qapi.QliksenseConfig, contextName string, timeout time.Duration) error {
+	rev, err := qConfig.GetLatestRevision(contextName)
+	if err != nil {
+		return err
+	} else if rev == nil {
+		return fmt.Errorf("no revision recorded for context %s", contextName)
+	}
+	manifests, err := qConfig.GetRevisionManifests(contextName, rev.Number)
+	if err != nil {
+		return err
+	}
+	namespace := rev.Namespace
+	if namespace == "" {
+		namespace = getTargetNamespace()
+	}
+	workloads, err := getWorkloadRefs(manifests, namespace)
+	if err != nil {
+		return err
+	}
+	workloads = append(workloads, qapi.WorkloadRef{Kind: qapi.QliksenseKind, Namespace: namespace, Name: contextName})
+
+	watcher, err := qapi.NewRolloutWatcherFromKubeConfig()
+	if err != nil {
+		return err
+	}
+	watcher.Out = q.out()
+	watcher.Stop = q.interrupted
+	fmt.Fprintf(q.out(), "Waiting up to %v for %d workloads to be ready\n", timeout, len(workloads))
+	statuses, err := watcher.Wait(workloads, timeout)
+	fmt.Fprintln(q.out())
+	qapi.PrintRolloutSummary(q.out(), statuses)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(q.out(), "All workloads are ready")
+	return nil
+}
+
+// getWorkloadRefs returns the Deployments, StatefulSets and Jobs of the manifests,
+// workloads without a namespace are expected in namespace
+func getWorkloadRefs(manifests []byte, namespace string) ([]qapi.WorkloadRef, error) {
+	kuzResourceFactory := resmap.NewFactory(resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl()), nil)
+	kuzResMap, err := kuzResourceFactory.NewResMapFromBytes(manifests)
+	if err != nil {
+		return nil, err
+	}
+	var workloads []qapi.WorkloadRef
+	for _, kuzRes := range kuzResMap.Resources() {
+		switch kuzRes.GetKind() {
+		case qapi.DeploymentKind, qapi.StatefulSetKind, qapi.JobKind:
+			ref := qapi.WorkloadRef{
+				Kind:      kuzRes.GetKind(),
+				Namespace: kuzRes.GetNamespace(),
+				Name:      kuzRes.GetName(),
+			}
+			if ref.Namespace == "" {
+				ref.Namespace = namespace
+			}
+			workloads = append(workloads, ref)
+		}
+	}
+	return workloads, nil
+}
@@ -0,0 +1,45 @@
+package qliksense
+
+import (
+	"reflect"
+	"testing"
+
+	qapi "github.com/qlik-oss/sense-installer/pkg/api"
+)
+
+func Test_getWorkloadRefs(t *testing.T) {
+	manifests := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: engine
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: engine-configs
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: mongodb
+  namespace: db
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migration
+`
+	workloads, err := getWorkloadRefs([]byte(manifests), "qliksense")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []qapi.WorkloadRef{
+		{Kind: qapi.DeploymentKind, Namespace: "qliksense", Name: "engine"},
+		{Kind: qapi.StatefulSetKind, Namespace: "db", Name: "mongodb"},
+		{Kind: qapi.JobKind, Namespace: "qliksense", Name: "migration"},
+	}
+	if !reflect.DeepEqual(workloads, expected) {
+		t.Fatalf("expected: %v, but got: %v", expected, workloads)
+	}
+}